@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/accounts"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"log"
+	"net/http"
+	"slices"
+)
+
+// maxFollowed limits how many podcasts a user subscribes to or watches.
+const maxFollowed = 500
+
+var errTooManyFollowed = fmt.Errorf("a user follows up to %d podcasts", maxFollowed)
+
+func (a *App) handleAccount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		a.renderAccount(w, r, a.user(r), http.StatusOK, "")
+	}
+}
+
+func (a *App) renderAccount(w http.ResponseWriter, r *http.Request, user *accounts.User, status int, message string) {
+	type response struct {
+		Subscriptions []*itunes.PodcastDetail
+		Watched       []*itunes.PodcastDetail
+		Webhooks      bool
+		Error         string
+	}
+
+	podcasts, err := a.lookupList(dedupe(append(slices.Clone(user.Subscriptions), user.Watched...)))
+	if err != nil {
+		log.Println(err)
+		message = "Your podcasts couldn't be fetched."
+	}
+
+	var resp response
+	for _, pod := range podcasts {
+		if user.Subscribed(pod.Id) {
+			resp.Subscriptions = append(resp.Subscriptions, pod)
+		}
+		if user.Watches(pod.Id) {
+			resp.Watched = append(resp.Watched, pod)
+		}
+	}
+	resp.Webhooks, resp.Error = a.webhooks != nil, message
+	a.renderStatus(w, r, status, resp, "account.html")
+}
+
+// handleAccountPodcasts subscribes to podcasts and watches their reviews, or stops doing so.
+func (a *App) handleAccountPodcasts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := a.user(r)
+		if err := r.ParseForm(); err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		id := r.Form.Get("podcast")
+		if !idPattern.MatchString(id) {
+			a.renderAccount(w, r, user, http.StatusBadRequest, "Pick a podcast to follow.")
+			return
+		}
+
+		_, err := a.accounts.Update(user.Id, func(u *accounts.User) error {
+			switch r.Form.Get("action") {
+			case "subscribe":
+				u.Subscriptions = dedupe(append(u.Subscriptions, id))
+			case "unsubscribe":
+				u.Subscriptions = slices.DeleteFunc(u.Subscriptions, func(v string) bool { return v == id })
+			case "watch":
+				u.Watched = dedupe(append(u.Watched, id))
+			case "unwatch":
+				u.Watched = slices.DeleteFunc(u.Watched, func(v string) bool { return v == id })
+			}
+			if len(u.Subscriptions) > maxFollowed || len(u.Watched) > maxFollowed {
+				return errTooManyFollowed
+			}
+			return nil
+		})
+		if errors.Is(err, errTooManyFollowed) {
+			a.renderAccount(w, r, user, http.StatusBadRequest, fmt.Sprintf("You can follow up to %d podcasts, remove some to add more.", maxFollowed))
+			return
+		}
+		if err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		http.Redirect(w, r, backTo(r, "/account"), http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,348 @@
+// Package accounts keeps visitors who signed in with a link sent to their email, their sessions and what they follow.
+package accounts
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/timiskhakov/podfinder/app/storage"
+	"net/mail"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLinkTTL    = 15 * time.Minute
+	defaultSessionTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidEmail = errors.New("invalid email address")
+	// ErrInvalidToken is returned for sign in links and sessions that are unknown, used or expired
+	ErrInvalidToken = errors.New("invalid or expired token")
+	ErrUserNotFound = errors.New("user not found")
+)
+
+// User is an account, podcasts are kept as ids in the order they were added.
+type User struct {
+	Id      string    `json:"id"`
+	Email   string    `json:"email"`
+	Created time.Time `json:"created"`
+	// Region is the preferred region, empty if it was never picked
+	Region        string   `json:"region,omitempty"`
+	Subscriptions []string `json:"subscriptions,omitempty"`
+	// Watched podcasts have their reviews archived
+	Watched []string `json:"watched,omitempty"`
+}
+
+// Subscribed tells whether the user subscribed to a podcast.
+func (u *User) Subscribed(id string) bool {
+	return slices.Contains(u.Subscriptions, id)
+}
+
+// Watches tells whether the user watches reviews of a podcast.
+func (u *User) Watches(id string) bool {
+	return slices.Contains(u.Watched, id)
+}
+
+// link is a sign in link that wasn't used yet.
+type link struct {
+	Email   string    `json:"email"`
+	Expires time.Time `json:"expires"`
+}
+
+type session struct {
+	User    string    `json:"user"`
+	Expires time.Time `json:"expires"`
+}
+
+// data is what is stored, links and sessions are keyed by hashes of their tokens,
+// so that the file doesn't hold anything to sign in with.
+type data struct {
+	Users    map[string]*User   `json:"users"`
+	Links    map[string]link    `json:"links"`
+	Sessions map[string]session `json:"sessions"`
+}
+
+// Clock lets tests control when links and sessions expire.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+type Config struct {
+	// File keeps accounts as JSON, they are kept in memory only if it is empty.
+	File string
+	// LinkTTL is how long a sign in link can be used, 15 minutes by default
+	LinkTTL time.Duration
+	// SessionTTL is how long a session lasts, 30 days by default
+	SessionTTL time.Duration
+	Clock      Clock
+}
+
+// Accounts signs users in with one time links and keeps their sessions.
+type Accounts struct {
+	file       *storage.File[data]
+	linkTTL    time.Duration
+	sessionTTL time.Duration
+	clock      Clock
+}
+
+func Open(config *Config) (*Accounts, error) {
+	f, err := storage.Open[data](config.File)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Accounts{
+		file:       f,
+		linkTTL:    config.LinkTTL,
+		sessionTTL: config.SessionTTL,
+		clock:      config.Clock,
+	}
+	if a.linkTTL <= 0 {
+		a.linkTTL = defaultLinkTTL
+	}
+	if a.sessionTTL <= 0 {
+		a.sessionTTL = defaultSessionTTL
+	}
+	if a.clock == nil {
+		a.clock = realClock{}
+	}
+
+	return a, nil
+}
+
+// RequestLink returns a token for a sign in link to send to an email address, an account is created once it is redeemed.
+func (a *Accounts) RequestLink(email string) (string, error) {
+	email, err := normalizeEmail(email)
+	if err != nil {
+		return "", err
+	}
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := a.clock.Now()
+	err = a.file.Update(func(d *data) error {
+		d.prune(now)
+		if d.Links == nil {
+			d.Links = make(map[string]link)
+		}
+		d.Links[hash(token)] = link{Email: email, Expires: now.Add(a.linkTTL)}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Redeem uses up a sign in link and starts a session, returning its token.
+func (a *Accounts) Redeem(token string) (string, *User, error) {
+	sessionToken, err := newToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var user *User
+	now := a.clock.Now()
+	err = a.file.Update(func(d *data) error {
+		d.prune(now)
+		l, ok := d.Links[hash(token)]
+		if !ok {
+			return ErrInvalidToken
+		}
+		delete(d.Links, hash(token))
+
+		u := d.byEmail(l.Email)
+		if u == nil {
+			id, err := newId()
+			if err != nil {
+				return err
+			}
+			u = &User{Id: id, Email: l.Email, Created: now}
+			if d.Users == nil {
+				d.Users = make(map[string]*User)
+			}
+			d.Users[id] = u
+		}
+		if d.Sessions == nil {
+			d.Sessions = make(map[string]session)
+		}
+		d.Sessions[hash(sessionToken)] = session{User: u.Id, Expires: now.Add(a.sessionTTL)}
+		user = u.copy()
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return sessionToken, user, nil
+}
+
+// User returns the user of a session.
+func (a *Accounts) User(session string) (*User, error) {
+	var (
+		user *User
+		err  error
+	)
+	now := a.clock.Now()
+	a.file.View(func(d *data) {
+		s, ok := d.Sessions[hash(session)]
+		if !ok || !s.Expires.After(now) {
+			err = ErrInvalidToken
+			return
+		}
+		u, ok := d.Users[s.User]
+		if !ok {
+			err = ErrInvalidToken
+			return
+		}
+		user = u.copy()
+	})
+	return user, err
+}
+
+// Find returns a user by id.
+func (a *Accounts) Find(id string) (*User, error) {
+	var user *User
+	a.file.View(func(d *data) {
+		if u, ok := d.Users[id]; ok {
+			user = u.copy()
+		}
+	})
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// SignOut ends a session, ending an unknown one is not an error.
+func (a *Accounts) SignOut(session string) error {
+	return a.file.Update(func(d *data) error {
+		delete(d.Sessions, hash(session))
+		return nil
+	})
+}
+
+// Update changes a user with fn and returns the result, the id, email and creation time can't be changed.
+func (a *Accounts) Update(id string, fn func(u *User) error) (*User, error) {
+	var user *User
+	err := a.file.Update(func(d *data) error {
+		u, ok := d.Users[id]
+		if !ok {
+			return ErrUserNotFound
+		}
+		id, email, created := u.Id, u.Email, u.Created
+		if err := fn(u); err != nil {
+			return err
+		}
+		u.Id, u.Email, u.Created = id, email, created
+		user = u.copy()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Watched returns ids of podcasts any user watches.
+func (a *Accounts) Watched() []string {
+	return a.union(func(u *User) []string { return u.Watched })
+}
+
+// Subscribed returns ids of podcasts any user subscribed to.
+func (a *Accounts) Subscribed() []string {
+	return a.union(func(u *User) []string { return u.Subscriptions })
+}
+
+// union collects podcast ids of every user, sorted.
+func (a *Accounts) union(ids func(u *User) []string) []string {
+	seen := make(map[string]bool)
+	a.file.View(func(d *data) {
+		for _, u := range d.Users {
+			for _, id := range ids(u) {
+				seen[id] = true
+			}
+		}
+	})
+
+	result := make([]string, 0, len(seen))
+	for id := range seen {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// prune drops expired links and sessions.
+func (d *data) prune(now time.Time) {
+	for k, l := range d.Links {
+		if !l.Expires.After(now) {
+			delete(d.Links, k)
+		}
+	}
+	for k, s := range d.Sessions {
+		if !s.Expires.After(now) {
+			delete(d.Sessions, k)
+		}
+	}
+}
+
+func (d *data) byEmail(email string) *User {
+	for _, u := range d.Users {
+		if u.Email == email {
+			return u
+		}
+	}
+	return nil
+}
+
+func (u *User) copy() *User {
+	c := *u
+	c.Subscriptions = slices.Clone(u.Subscriptions)
+	c.Watched = slices.Clone(u.Watched)
+	return &c
+}
+
+// normalizeEmail accepts a bare address, such as grey@example.com, and lowercases it.
+func normalizeEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || addr.Name != "" {
+		return "", ErrInvalidEmail
+	}
+	return strings.ToLower(addr.Address), nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func newId() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
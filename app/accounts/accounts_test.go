@@ -0,0 +1,191 @@
+package accounts
+
+import (
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type AccountsSuite struct {
+	suite.Suite
+	path     string
+	clock    *fixedClock
+	accounts *Accounts
+}
+
+func TestAccountsSuite(t *testing.T) {
+	suite.Run(t, new(AccountsSuite))
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time { return c.now }
+
+func (s *AccountsSuite) SetupTest() {
+	s.path = filepath.Join(s.T().TempDir(), "accounts.json")
+	s.clock = &fixedClock{time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	s.accounts = s.open()
+}
+
+func (s *AccountsSuite) open() *Accounts {
+	a, err := Open(&Config{File: s.path, LinkTTL: 15 * time.Minute, SessionTTL: 24 * time.Hour, Clock: s.clock})
+	s.NoError(err)
+	return a
+}
+
+func (s *AccountsSuite) signIn(email string) (string, *User) {
+	token, err := s.accounts.RequestLink(email)
+	s.NoError(err)
+	session, user, err := s.accounts.Redeem(token)
+	s.NoError(err)
+	return session, user
+}
+
+func (s *AccountsSuite) TestSignIn() {
+	token, err := s.accounts.RequestLink(" Grey@Example.com ")
+	s.NoError(err)
+
+	session, user, err := s.accounts.Redeem(token)
+
+	s.NoError(err)
+	s.Equal("grey@example.com", user.Email)
+	s.Equal(s.clock.now, user.Created)
+	found, err := s.accounts.User(session)
+	s.NoError(err)
+	s.Equal(user, found)
+}
+
+func (s *AccountsSuite) TestSignInExistingUser() {
+	_, first := s.signIn("grey@example.com")
+
+	_, second := s.signIn("GREY@example.com")
+
+	s.Equal(first.Id, second.Id)
+}
+
+func (s *AccountsSuite) TestRequestLinkInvalidEmail() {
+	for _, email := range []string{"", "grey", "Grey <grey@example.com>", "grey@example.com, brady@example.com"} {
+		_, err := s.accounts.RequestLink(email)
+
+		s.ErrorIs(err, ErrInvalidEmail, email)
+	}
+}
+
+func (s *AccountsSuite) TestRedeemOnce() {
+	token, err := s.accounts.RequestLink("grey@example.com")
+	s.NoError(err)
+	_, _, err = s.accounts.Redeem(token)
+	s.NoError(err)
+
+	_, _, err = s.accounts.Redeem(token)
+
+	s.ErrorIs(err, ErrInvalidToken)
+}
+
+func (s *AccountsSuite) TestRedeemExpired() {
+	token, err := s.accounts.RequestLink("grey@example.com")
+	s.NoError(err)
+	s.clock.now = s.clock.now.Add(15 * time.Minute)
+
+	_, _, err = s.accounts.Redeem(token)
+
+	s.ErrorIs(err, ErrInvalidToken)
+}
+
+func (s *AccountsSuite) TestSessionExpires() {
+	session, _ := s.signIn("grey@example.com")
+	s.clock.now = s.clock.now.Add(24 * time.Hour)
+
+	_, err := s.accounts.User(session)
+
+	s.ErrorIs(err, ErrInvalidToken)
+}
+
+func (s *AccountsSuite) TestSignOut() {
+	session, _ := s.signIn("grey@example.com")
+
+	s.NoError(s.accounts.SignOut(session))
+
+	_, err := s.accounts.User(session)
+	s.ErrorIs(err, ErrInvalidToken)
+}
+
+func (s *AccountsSuite) TestUpdate() {
+	session, user := s.signIn("grey@example.com")
+
+	updated, err := s.accounts.Update(user.Id, func(u *User) error {
+		u.Email = "brady@example.com"
+		u.Region = "gb"
+		u.Subscriptions = append(u.Subscriptions, "811377230")
+		return nil
+	})
+
+	s.NoError(err)
+	s.Equal("grey@example.com", updated.Email)
+	s.Equal("gb", updated.Region)
+	s.True(updated.Subscribed("811377230"))
+	found, err := s.accounts.User(session)
+	s.NoError(err)
+	s.Equal(updated, found)
+	found, err = s.accounts.Find(user.Id)
+	s.NoError(err)
+	s.Equal(updated, found)
+}
+
+func (s *AccountsSuite) TestUpdateFails() {
+	_, user := s.signIn("grey@example.com")
+
+	_, err := s.accounts.Update(user.Id, func(u *User) error {
+		u.Region = "gb"
+		return errors.New("failed")
+	})
+	_, missing := s.accounts.Update("unknown", func(u *User) error { return nil })
+
+	s.Error(err)
+	s.ErrorIs(missing, ErrUserNotFound)
+	_, err = s.accounts.Find("unknown")
+	s.ErrorIs(err, ErrUserNotFound)
+	var region string
+	s.accounts.file.View(func(d *data) { region = d.Users[user.Id].Region })
+	s.Empty(region)
+}
+
+func (s *AccountsSuite) TestWatchedAndSubscribed() {
+	_, grey := s.signIn("grey@example.com")
+	_, brady := s.signIn("brady@example.com")
+	_, err := s.accounts.Update(grey.Id, func(u *User) error {
+		u.Watched = []string{"811377230", "1200361736"}
+		return nil
+	})
+	s.NoError(err)
+	_, err = s.accounts.Update(brady.Id, func(u *User) error {
+		u.Watched = []string{"811377230"}
+		u.Subscriptions = []string{"151622312"}
+		return nil
+	})
+	s.NoError(err)
+
+	s.Equal([]string{"1200361736", "811377230"}, s.accounts.Watched())
+	s.Equal([]string{"151622312"}, s.accounts.Subscribed())
+}
+
+func (s *AccountsSuite) TestPersistsHashes() {
+	session, _ := s.signIn("grey@example.com")
+	token, err := s.accounts.RequestLink("grey@example.com")
+	s.NoError(err)
+
+	b, err := os.ReadFile(s.path)
+	s.NoError(err)
+	s.NotContains(string(b), session)
+	s.NotContains(string(b), token)
+	reopened := s.open()
+	_, err = reopened.User(session)
+	s.NoError(err)
+	_, _, err = reopened.Redeem(token)
+	s.NoError(err)
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reviewSorts maps sort names of the podcast page and the API to storefront orders.
+var reviewSorts = map[string]itunes.ReviewSort{
+	"recent":  itunes.ReviewsMostRecent,
+	"helpful": itunes.ReviewsMostHelpful,
+}
+
+// maxLookupIds limits how many podcasts can be fetched by a single API request.
+const maxLookupIds = 200
+
+type apiPodcast struct {
+	Id      string `json:"id"`
+	Artist  string `json:"artist"`
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	Genre   string `json:"genre,omitempty"`
+	Episode string `json:"episode,omitempty"`
+}
+
+type apiSearchPodcast struct {
+	apiPodcast
+	EpisodeCount int       `json:"episodeCount"`
+	Released     time.Time `json:"released"`
+	Explicit     bool      `json:"explicit"`
+}
+
+type apiEpisode struct {
+	Id       string    `json:"id"`
+	Title    string    `json:"title"`
+	ShowId   string    `json:"showId"`
+	Show     string    `json:"show"`
+	Image    string    `json:"image"`
+	Released time.Time `json:"released"`
+	Duration int       `json:"duration"`
+	Url      string    `json:"url"`
+	AudioUrl string    `json:"audioUrl"`
+}
+
+type apiPodcastDetail struct {
+	Id           string   `json:"id"`
+	Artist       string   `json:"artist"`
+	Name         string   `json:"name"`
+	Image        string   `json:"image"`
+	EpisodeCount int      `json:"episodeCount"`
+	Url          string   `json:"url"`
+	FeedUrl      string   `json:"feedUrl"`
+	Genres       []string `json:"genres"`
+}
+
+type apiReview struct {
+	Id        string       `json:"id"`
+	Author    string       `json:"author"`
+	Title     string       `json:"title"`
+	Content   string       `json:"content"`
+	Rating    int          `json:"rating"`
+	Date      time.Time    `json:"date"`
+	VoteCount int          `json:"voteCount"`
+	VoteSum   int          `json:"voteSum"`
+	Version   string       `json:"version"`
+	Sentiment apiSentiment `json:"sentiment"`
+}
+
+type apiError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func (a *App) handleApiTop() http.HandlerFunc {
+	type response struct {
+		Region   string       `json:"region"`
+		Type     string       `json:"type"`
+		Genre    int          `json:"genre"`
+		Limit    int          `json:"limit"`
+		Podcasts []apiPodcast `json:"podcasts"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		chart, err := apiChart(r)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		reg := a.region(r)
+		podcasts, err := a.store.Top(reg, chart)
+		if err != nil {
+			log.Println(err)
+			writeApiError(w, http.StatusBadGateway, "can't fetch top podcasts")
+			return
+		}
+
+		typ := strings.TrimPrefix(string(chart.Type), "top")
+		writeJson(w, http.StatusOK, response{reg, typ, chart.Genre, chart.Limit, toApiPodcasts(podcasts)})
+	}
+}
+
+func (a *App) handleApiSearch() http.HandlerFunc {
+	type response struct {
+		Region   string             `json:"region"`
+		Query    string             `json:"query"`
+		Total    int                `json:"total"`
+		Limit    int                `json:"limit"`
+		Offset   int                `json:"offset"`
+		Podcasts []apiSearchPodcast `json:"podcasts"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		values := r.URL.Query()
+		query := values.Get("q")
+		if query == "" {
+			writeApiError(w, http.StatusBadRequest, "query parameter q is required")
+			return
+		}
+		q, err := searchQuery(values)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		q.Term = query
+
+		if v := values.Get("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > itunes.MaxSearchLimit {
+				writeApiError(w, http.StatusBadRequest, fmt.Sprintf("query parameter limit must be between 1 and %d", itunes.MaxSearchLimit))
+				return
+			}
+			q.Limit = limit
+		}
+		if v := values.Get("offset"); v != "" {
+			offset, err := strconv.Atoi(v)
+			if err != nil || offset < 0 {
+				writeApiError(w, http.StatusBadRequest, "query parameter offset must be a non-negative number")
+				return
+			}
+			q.Offset = offset
+		}
+		q = q.Normalize()
+
+		reg := a.region(r)
+		results, err := a.store.Search(reg, q)
+		if err != nil {
+			log.Println(err)
+			writeApiError(w, http.StatusBadGateway, "can't search podcasts")
+			return
+		}
+
+		writeJson(w, http.StatusOK, response{reg, query, results.Total, q.Limit, q.Offset, toApiSearchPodcasts(results.Podcasts)})
+	}
+}
+
+func (a *App) handleApiSearchEpisodes() http.HandlerFunc {
+	type response struct {
+		Region   string       `json:"region"`
+		Query    string       `json:"query"`
+		Episodes []apiEpisode `json:"episodes"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("q")
+		if query == "" {
+			writeApiError(w, http.StatusBadRequest, "query parameter q is required")
+			return
+		}
+
+		reg := a.region(r)
+		episodes, err := a.store.SearchEpisodes(reg, query)
+		if err != nil {
+			log.Println(err)
+			writeApiError(w, http.StatusBadGateway, "can't search episodes")
+			return
+		}
+
+		result := make([]apiEpisode, len(episodes))
+		for i, e := range episodes {
+			result[i] = apiEpisode{
+				Id:       e.Id,
+				Title:    e.Title,
+				ShowId:   e.ShowId,
+				Show:     e.Show,
+				Image:    e.Image,
+				Released: e.Released,
+				Duration: int(e.Duration.Seconds()),
+				Url:      e.Url,
+				AudioUrl: e.AudioUrl,
+			}
+		}
+		writeJson(w, http.StatusOK, response{reg, query, result})
+	}
+}
+
+func (a *App) handleApiPodcast() http.HandlerFunc {
+	type response struct {
+		Podcast apiPodcastDetail `json:"podcast"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		pod, err := a.store.Lookup(r.PathValue("id"))
+		if errors.Is(err, itunes.ErrNotFound) {
+			writeApiError(w, http.StatusNotFound, "podcast not found")
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			writeApiError(w, http.StatusBadGateway, "can't fetch podcast")
+			return
+		}
+
+		writeJson(w, http.StatusOK, response{toApiPodcastDetail(pod)})
+	}
+}
+
+func (a *App) handleApiPodcasts() http.HandlerFunc {
+	type response struct {
+		Podcasts []apiPodcastDetail `json:"podcasts"`
+		Missing  []string           `json:"missing"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var ids []string
+		for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		// Repeated ids are looked up once, so they don't count against the limit
+		ids = dedupe(ids)
+		if len(ids) == 0 || len(ids) > maxLookupIds {
+			writeApiError(w, http.StatusBadRequest, fmt.Sprintf("query parameter ids must list between 1 and %d ids", maxLookupIds))
+			return
+		}
+
+		details, err := a.store.LookupMany(ids)
+		var missing *itunes.MissingError
+		if err != nil && !errors.As(err, &missing) {
+			log.Println(err)
+			writeApiError(w, http.StatusBadGateway, "can't fetch podcasts")
+			return
+		}
+
+		podcasts := make([]apiPodcastDetail, 0, len(details))
+		for _, id := range ids {
+			if pod, ok := details[id]; ok {
+				podcasts = append(podcasts, toApiPodcastDetail(pod))
+			}
+		}
+		resp := response{Podcasts: podcasts, Missing: []string{}}
+		if missing != nil {
+			resp.Missing = missing.Ids
+		}
+		writeJson(w, http.StatusOK, resp)
+	}
+}
+
+func (a *App) handleApiReviews() http.HandlerFunc {
+	type response struct {
+		Region  string      `json:"region"`
+		Sort    string      `json:"sort"`
+		Page    int         `json:"page"`
+		Last    int         `json:"last"`
+		Reviews []apiReview `json:"reviews"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		values := r.URL.Query()
+		sort := values.Get("sort")
+		if sort == "" {
+			sort = "recent"
+		}
+		if _, ok := reviewSorts[sort]; !ok {
+			writeApiError(w, http.StatusBadRequest, "query parameter sort must be recent or helpful")
+			return
+		}
+		number := 1
+		if v := values.Get("page"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > itunes.MaxReviewPages {
+				writeApiError(w, http.StatusBadRequest, fmt.Sprintf("query parameter page must be between 1 and %d", itunes.MaxReviewPages))
+				return
+			}
+			number = n
+		}
+
+		reg := a.region(r)
+		page, err := a.store.Reviews(r.PathValue("id"), reg, itunes.ReviewsQuery{Page: number, Sort: reviewSorts[sort]})
+		if err != nil {
+			log.Println(err)
+			writeApiError(w, http.StatusBadGateway, "can't fetch reviews")
+			return
+		}
+
+		reviews := make([]apiReview, len(page.Reviews))
+		for i, rew := range a.scoreReviews(page.Reviews) {
+			reviews[i] = apiReview{
+				Id:        rew.Id,
+				Author:    rew.Author,
+				Title:     rew.Title,
+				Content:   rew.Content,
+				Rating:    len(rew.Rating),
+				Date:      rew.Date,
+				VoteCount: rew.VoteCount,
+				VoteSum:   rew.VoteSum,
+				Version:   rew.Version,
+				Sentiment: apiSentiment{rew.Sentiment.Value, rew.Sentiment.Label()},
+			}
+		}
+
+		writeJson(w, http.StatusOK, response{reg, sort, page.Page, page.Last, reviews})
+	}
+}
+
+func (a *App) handleApiNotFound() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeApiError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (a *App) limitApi(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.isLimiterEnabled && !a.allow(w, r, route) {
+			writeApiError(w, http.StatusTooManyRequests, "request limit reached")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// apiChart reads the chart type, genre and limit from the query.
+func apiChart(r *http.Request) (itunes.Chart, error) {
+	q := r.URL.Query()
+	chart := itunes.Chart{}
+
+	switch q.Get("type") {
+	case "", "podcasts":
+		chart.Type = itunes.ChartPodcasts
+	case "episodes":
+		chart.Type = itunes.ChartEpisodes
+	default:
+		return chart, errors.New("query parameter type must be podcasts or episodes")
+	}
+
+	if v := q.Get("genre"); v != "" {
+		id, err := strconv.Atoi(v)
+		if _, ok := itunes.LookupGenre(id); err != nil || !ok {
+			return chart, errors.New("unknown genre " + v)
+		}
+		chart.Genre = id
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > itunes.MaxChartLimit {
+			return chart, fmt.Errorf("query parameter limit must be between 1 and %d", itunes.MaxChartLimit)
+		}
+		chart.Limit = limit
+	}
+
+	return chart.Normalize(), nil
+}
+
+// searchQuery parses search options shared by the search page and the API, the term and paging are up to the caller.
+func searchQuery(values url.Values) (itunes.Query, error) {
+	q := itunes.Query{}
+
+	switch values.Get("attribute") {
+	case "":
+	case "title":
+		q.Attribute = itunes.AttributeTitle
+	case "author":
+		q.Attribute = itunes.AttributeAuthor
+	case "keywords":
+		q.Attribute = itunes.AttributeKeywords
+	default:
+		return q, errors.New("query parameter attribute must be title, author or keywords")
+	}
+
+	if v := values.Get("genre"); v != "" {
+		id, err := strconv.Atoi(v)
+		if _, ok := itunes.LookupGenre(id); err != nil || !ok {
+			return q, errors.New("unknown genre " + v)
+		}
+		q.Genre = id
+	}
+
+	switch values.Get("explicit") {
+	case "", "true":
+	case "false":
+		q.Clean = true
+	default:
+		return q, errors.New("query parameter explicit must be true or false")
+	}
+
+	switch sort := itunes.SearchSort(values.Get("sort")); sort {
+	case "":
+	case itunes.SortRelevance, itunes.SortName, itunes.SortArtist, itunes.SortEpisodes, itunes.SortReleased:
+		q.Sort = sort
+	default:
+		return q, errors.New("query parameter sort must be relevance, name, artist, episodes or released")
+	}
+
+	return q, nil
+}
+
+func toApiPodcasts(podcasts []*itunes.Podcast) []apiPodcast {
+	result := make([]apiPodcast, len(podcasts))
+	for i, p := range podcasts {
+		result[i] = apiPodcast{
+			Id:      p.Id,
+			Artist:  p.Artist,
+			Name:    p.Name,
+			Image:   p.Image,
+			Genre:   p.Genre,
+			Episode: p.Episode,
+		}
+	}
+	return result
+}
+
+func toApiPodcastDetail(pod *itunes.PodcastDetail) apiPodcastDetail {
+	return apiPodcastDetail{
+		Id:           pod.Id,
+		Artist:       pod.Artist,
+		Name:         pod.Name,
+		Image:        pod.Image,
+		EpisodeCount: pod.EpisodeCount,
+		Url:          pod.Url,
+		FeedUrl:      pod.FeedUrl,
+		Genres:       nonNil(pod.Genres),
+	}
+}
+
+func toApiSearchPodcasts(podcasts []*itunes.Podcast) []apiSearchPodcast {
+	result := make([]apiSearchPodcast, len(podcasts))
+	for i, p := range toApiPodcasts(podcasts) {
+		result[i] = apiSearchPodcast{
+			apiPodcast:   p,
+			EpisodeCount: podcasts[i].EpisodeCount,
+			Released:     podcasts[i].Released,
+			Explicit:     podcasts[i].Explicit,
+		}
+	}
+	return result
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func writeApiError(w http.ResponseWriter, status int, message string) {
+	type response struct {
+		Error apiError `json:"error"`
+	}
+
+	writeJson(w, status, response{apiError{status, message}})
+}
+
+func writeJson(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
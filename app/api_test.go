@@ -0,0 +1,368 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/limiter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (s *AppSuite) getJson(path string, v any) *http.Response {
+	resp, err := s.httpClient.Get(s.appServer.URL + path)
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+
+	s.Equal("application/json; charset=utf-8", resp.Header.Get("Content-Type"))
+	s.NoError(json.NewDecoder(resp.Body).Decode(v))
+
+	return resp
+}
+
+func (s *AppSuite) TestApiTop() {
+	s.serveCounted("/gb/rss/toppodcasts/limit=10/json", "./testdata/top.json")
+	var body struct {
+		Region   string       `json:"region"`
+		Podcasts []apiPodcast `json:"podcasts"`
+	}
+
+	resp := s.getJson("/api/v1/top?region=gb", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("gb", body.Region)
+	s.Equal(10, len(body.Podcasts))
+	s.Equal("1612875889", body.Podcasts[0].Id)
+}
+
+func (s *AppSuite) TestApiTopChart() {
+	s.serveCounted("/gb/rss/topepisodes/limit=50/genre=1318/json", "./testdata/top.json")
+	var body struct {
+		Type     string       `json:"type"`
+		Genre    int          `json:"genre"`
+		Limit    int          `json:"limit"`
+		Podcasts []apiPodcast `json:"podcasts"`
+	}
+
+	resp := s.getJson("/api/v1/top?region=gb&type=episodes&genre=1318&limit=50", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("episodes", body.Type)
+	s.Equal(1318, body.Genre)
+	s.Equal(50, body.Limit)
+	s.Equal("True Crime", body.Podcasts[0].Genre)
+}
+
+func (s *AppSuite) TestApiTopInvalidChart() {
+	for _, query := range []string{"type=songs", "genre=26", "limit=0", "limit=201"} {
+		var body struct {
+			Error apiError `json:"error"`
+		}
+
+		resp := s.getJson("/api/v1/top?"+query, &body)
+
+		s.Equal(http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func (s *AppSuite) TestApiSearch() {
+	s.serveCounted("/search", "./testdata/search.json")
+	var body struct {
+		Query    string       `json:"query"`
+		Podcasts []apiPodcast `json:"podcasts"`
+	}
+
+	resp := s.getJson("/api/v1/search?q=hello+internet", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("hello internet", body.Query)
+	s.Equal(5, len(body.Podcasts))
+}
+
+func (s *AppSuite) TestApiSearchOptions() {
+	s.serveCounted("/search", "./testdata/search.json")
+	var body struct {
+		Total    int                `json:"total"`
+		Limit    int                `json:"limit"`
+		Offset   int                `json:"offset"`
+		Podcasts []apiSearchPodcast `json:"podcasts"`
+	}
+
+	resp := s.getJson("/api/v1/search?q=hello&sort=released&limit=2&offset=1", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(5, body.Total)
+	s.Equal(2, body.Limit)
+	s.Equal(1, body.Offset)
+	s.Equal([]string{"811377230", "151622312"}, []string{body.Podcasts[0].Id, body.Podcasts[1].Id})
+	s.Equal(100, body.Podcasts[0].EpisodeCount)
+	s.Equal(time.Date(2020, 2, 28, 10, 13, 0, 0, time.UTC), body.Podcasts[0].Released)
+	s.Equal("Education", body.Podcasts[0].Genre)
+}
+
+func (s *AppSuite) TestApiSearchInvalidOptions() {
+	for _, query := range []string{"attribute=description", "genre=26", "explicit=no", "sort=rating", "limit=0", "limit=201", "offset=-1"} {
+		var body struct {
+			Error apiError `json:"error"`
+		}
+
+		resp := s.getJson("/api/v1/search?q=hello&"+query, &body)
+
+		s.Equal(http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func (s *AppSuite) TestApiSearchEpisodes() {
+	s.serveCounted("/search", "./testdata/episodes.json")
+	var body struct {
+		Query    string       `json:"query"`
+		Episodes []apiEpisode `json:"episodes"`
+	}
+
+	resp := s.getJson("/api/v1/search/episodes?q=dog+bingo", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("dog bingo", body.Query)
+	s.Equal(2, len(body.Episodes))
+	s.Equal(7815, body.Episodes[0].Duration)
+	s.Equal("811377230", body.Episodes[0].ShowId)
+}
+
+func (s *AppSuite) TestApiSearchWithoutQuery() {
+	var body struct {
+		Error apiError `json:"error"`
+	}
+
+	resp := s.getJson("/api/v1/search", &body)
+
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+	s.Equal(apiError{http.StatusBadRequest, "query parameter q is required"}, body.Error)
+}
+
+func (s *AppSuite) TestApiSearchLimit() {
+	s.app.isLimiterEnabled = true
+	s.app.limiter = limiter.NewKeyed(&limiter.KeyedConfig{
+		Budgets: map[string]limiter.Budget{"search": {Every: time.Minute, Burst: 0}},
+	})
+	var body struct {
+		Error apiError `json:"error"`
+	}
+
+	resp := s.getJson("/api/v1/search?q=hello+internet", &body)
+
+	s.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	s.Equal("60", resp.Header.Get("Retry-After"))
+	s.Equal(http.StatusTooManyRequests, body.Error.Status)
+}
+
+func (s *AppSuite) TestApiPodcast() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	var body struct {
+		Podcast apiPodcastDetail `json:"podcast"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts/811377230", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(apiPodcastDetail{
+		Id:           "811377230",
+		Artist:       "CGP Grey & Brady Haran",
+		Name:         "Hello Internet",
+		Image:        "https://is5-ssl.mzstatic.com/image/thumb/Podcasts6/v4/19/33/fe/1933fe85-cd86-2191-8187-d725ca7359bf/mza_8038397602264410223.png/600x600bb.jpg",
+		EpisodeCount: 100,
+		Url:          "https://podcasts.apple.com/us/podcast/hello-internet/id811377230?uo=4",
+		FeedUrl:      "http://www.hellointernet.fm/podcast?format=rss",
+		Genres:       []string{"Education", "Podcasts"},
+	}, body.Podcast)
+}
+
+func (s *AppSuite) TestApiPodcastNotFound() {
+	s.itunesMux.HandleFunc("/lookup", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"resultCount": 0, "results": []}`)
+	})
+	var body struct {
+		Error apiError `json:"error"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts/1", &body)
+
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+	s.Equal(apiError{http.StatusNotFound, "podcast not found"}, body.Error)
+}
+
+func (s *AppSuite) TestApiPodcastUpstreamError() {
+	s.itunesMux.HandleFunc("/lookup", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	var body struct {
+		Error apiError `json:"error"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts/1", &body)
+
+	s.Equal(http.StatusBadGateway, resp.StatusCode)
+	s.Equal(http.StatusBadGateway, body.Error.Status)
+}
+
+func (s *AppSuite) TestApiPodcasts() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	var body struct {
+		Podcasts []apiPodcastDetail `json:"podcasts"`
+		Missing  []string           `json:"missing"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts?ids=811377230,1", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Len(body.Podcasts, 1)
+	s.Equal("Hello Internet", body.Podcasts[0].Name)
+	s.Equal([]string{"1"}, body.Missing)
+}
+
+func (s *AppSuite) TestApiPodcastsRepeatedIds() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	var body struct {
+		Podcasts []apiPodcastDetail `json:"podcasts"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts?ids="+strings.Repeat("811377230,", maxLookupIds+1), &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Len(body.Podcasts, 1)
+}
+
+func (s *AppSuite) TestApiPodcastsInvalidIds() {
+	tooMany := make([]string, maxLookupIds+1)
+	for i := range tooMany {
+		tooMany[i] = strconv.Itoa(i + 1)
+	}
+
+	for _, query := range []string{"", "ids=", "ids=" + strings.Join(tooMany, ",")} {
+		var body struct {
+			Error apiError `json:"error"`
+		}
+
+		resp := s.getJson("/api/v1/podcasts?"+query, &body)
+
+		s.Equal(http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func (s *AppSuite) TestApiReviews() {
+	s.serveCounted("/us/rss/customerreviews/page=1/id=811377230/sortby=mostrecent/json", "./testdata/reviews.json")
+	var body struct {
+		Reviews []apiReview `json:"reviews"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts/811377230/reviews", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(50, len(body.Reviews))
+	s.Equal("8414391645", body.Reviews[0].Id)
+	s.Equal(5, body.Reviews[0].Rating)
+	s.Equal("positive", body.Reviews[0].Sentiment.Label)
+}
+
+func (s *AppSuite) TestApiReviewsPage() {
+	calls := s.serveCounted("/gb/rss/customerreviews/page=3/id=811377230/sortby=mosthelpful/json", "./testdata/reviews.json")
+	var body struct {
+		Region  string      `json:"region"`
+		Sort    string      `json:"sort"`
+		Page    int         `json:"page"`
+		Last    int         `json:"last"`
+		Reviews []apiReview `json:"reviews"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts/811377230/reviews?region=gb&sort=helpful&page=3", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(int32(1), calls.Load())
+	s.Equal("helpful", body.Sort)
+	s.Equal(3, body.Page)
+	s.Equal(10, body.Last)
+	s.Equal(50, len(body.Reviews))
+}
+
+func (s *AppSuite) TestApiReviewsInvalid() {
+	for _, query := range []string{"sort=oldest", "page=0", "page=11", "page=first"} {
+		var body struct {
+			Error apiError `json:"error"`
+		}
+
+		resp := s.getJson("/api/v1/podcasts/811377230/reviews?"+query, &body)
+
+		s.Equal(http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func (s *AppSuite) TestApiReviewSummary() {
+	calls := s.serveCounted("/us/rss/customerreviews/", "./testdata/reviews.json")
+	var body struct {
+		Count     int        `json:"count"`
+		Average   float64    `json:"average"`
+		Histogram [5]int     `json:"histogram"`
+		Months    []apiMonth `json:"months"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts/811377230/reviews/summary", &body)
+
+	// Every page serves the same reviews, which are counted once
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(int32(10), calls.Load())
+	s.Equal(50, body.Count)
+	s.InDelta(4.84, body.Average, 0.001)
+	s.Equal([5]int{1, 1, 0, 1, 47}, body.Histogram)
+	s.Equal("2020-08", body.Months[0].Month)
+	s.Equal("2022-03", body.Months[len(body.Months)-1].Month)
+}
+
+func (s *AppSuite) TestApiReviewSummaryLimit() {
+	calls := s.serveCounted("/us/rss/customerreviews/", "./testdata/reviews.json")
+	s.app.isLimiterEnabled = true
+	s.app.limiter = limiter.NewKeyed(&limiter.KeyedConfig{
+		Budgets: map[string]limiter.Budget{"summary": {Every: time.Minute, Burst: 1}},
+	})
+	var body struct {
+		Error apiError `json:"error"`
+	}
+
+	s.Equal(http.StatusOK, s.getJson("/api/v1/podcasts/811377230/reviews/summary", &body).StatusCode)
+	resp := s.getJson("/api/v1/podcasts/811377230/reviews/summary", &body)
+
+	s.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	s.Equal(int32(10), calls.Load())
+}
+
+func (s *AppSuite) TestApiReviewSummaryError() {
+	s.serveCounted("/us/rss/customerreviews/page=1/", "./testdata/reviews.json")
+	var body struct {
+		Error apiError `json:"error"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts/811377230/reviews/summary", &body)
+
+	s.Equal(http.StatusBadGateway, resp.StatusCode)
+}
+
+func (s *AppSuite) TestApiNotFound() {
+	var body struct {
+		Error apiError `json:"error"`
+	}
+
+	resp := s.getJson("/api/v1/unknown", &body)
+
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (s *AppSuite) TestApiReviewSummaryComplaints() {
+	s.serveCounted("/us/rss/customerreviews/", "./testdata/reviews.json")
+	var body struct {
+		Complaints []apiKeyword `json:"complaints"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts/811377230/reviews/summary", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal([]apiKeyword{{"better", 2}, {"come back", 2}, {"disappointed", 2}}, body.Complaints)
+}
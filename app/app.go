@@ -1,88 +1,239 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/accounts"
+	"github.com/timiskhakov/podfinder/app/charts"
+	"github.com/timiskhakov/podfinder/app/feed"
 	"github.com/timiskhakov/podfinder/app/itunes"
+	"github.com/timiskhakov/podfinder/app/limiter"
+	"github.com/timiskhakov/podfinder/app/mail"
+	"github.com/timiskhakov/podfinder/app/reviews"
+	"github.com/timiskhakov/podfinder/app/sentiment"
 	"html/template"
+	"io/fs"
 	"log"
+	"math"
 	"net/http"
-	"path/filepath"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
-const errorMessage = "Internal server error"
+const (
+	errorMessage    = "Internal server error"
+	episodesPerPage = 20
+	resultsPerPage  = 20
+)
+
+var funcs = template.FuncMap{
+	"duration":   formatDuration,
+	"pathescape": url.PathEscape,
+}
 
 type App struct {
 	store            Store
+	feeds            Feeds
 	isLimiterEnabled bool
 	limiter          Limiter
+	clients          Clients
+	regions          []RegionSource
+	cookies          *SignedCookies
+	history          ChartHistory
+	archive          ReviewArchive
+	accounts         Accounts
+	inbox            Inbox
+	webhooks         Webhooks
+	mailer           Mailer
+	url              string
+	sentiment        *sentiment.Analyzer
 	mux              http.Handler
+	assets           fs.FS
+	dev              bool
+	hashes           map[string]string
+	mu               sync.RWMutex
 	cache            map[string]*template.Template
+	parsed           time.Time
 }
 
 type Store interface {
-	Top(region string) ([]*itunes.Podcast, error)
-	Search(region, query string) ([]*itunes.Podcast, error)
+	Top(region string, chart itunes.Chart) ([]*itunes.Podcast, error)
+	Search(region string, q itunes.Query) (*itunes.SearchResults, error)
+	SearchEpisodes(region, query string) ([]*itunes.Episode, error)
 	Lookup(id string) (*itunes.PodcastDetail, error)
-	Reviews(id, region string) ([]*itunes.Review, error)
+	LookupMany(ids []string) (map[string]*itunes.PodcastDetail, error)
+	Reviews(id, region string, q itunes.ReviewsQuery) (*itunes.ReviewPage, error)
+}
+
+type Feeds interface {
+	Fetch(url string) (*feed.Feed, error)
 }
 
+// Limiter decides whether a client may make a request to a route.
 type Limiter interface {
-	Allow() bool
+	Allow(route, client string) limiter.Quota
+}
+
+// ChartHistory reports how top charts changed over time.
+type ChartHistory interface {
+	Report(region string, since time.Time) (*charts.Report, error)
+}
+
+// ReviewArchive keeps reviews of watched podcasts after the storefront stops serving them.
+type ReviewArchive interface {
+	Reviews(id string) ([]*reviews.Archived, error)
+}
+
+// Clients tells clients apart for the Limiter.
+type Clients interface {
+	Key(r *http.Request) string
 }
 
 type AppConfig struct {
 	Store            Store
+	Feeds            Feeds
 	IsLimiterEnabled bool
 	Limiter          Limiter
+	Clients          Clients
+	// Regions resolve the region of a request in order, the query parameter, cookie and Accept-Language are used by default.
+	Regions []RegionSource
+	// Cookies signs cookies of visitors, with a random key by default.
+	Cookies *SignedCookies
+	// History enables chart history pages, they are not found if it is nil.
+	History ChartHistory
+	// Archive enables review archive pages and adds archived reviews to summaries, archive pages are not found if it is nil.
+	Archive ReviewArchive
+	// Accounts enables signing in and account pages, they are not found if it is nil.
+	Accounts Accounts
+	// Inbox enables the inbox of new episodes of subscriptions, it is not found if it is nil.
+	Inbox Inbox
+	// Webhooks enables webhook pages of accounts, they are not found if it is nil.
+	Webhooks Webhooks
+	// Mailer sends sign in links, they are logged by default.
+	Mailer Mailer
+	// Url is the base url of links in emails, such as https://podfinder.example.com, the host of the request by default.
+	Url string
+	// Assets holds templates and www directories, files embedded into the binary are used by default.
+	Assets fs.FS
+	// Dev reparses templates once they change and serves assets without versioning.
+	Dev bool
 }
 
 func NewApp(config *AppConfig) (*App, error) {
 	a := &App{
 		store:            config.Store,
+		feeds:            config.Feeds,
 		isLimiterEnabled: config.IsLimiterEnabled,
 		limiter:          config.Limiter,
+		clients:          config.Clients,
+		regions:          config.Regions,
+		cookies:          config.Cookies,
+		history:          config.History,
+		archive:          config.Archive,
+		accounts:         config.Accounts,
+		inbox:            config.Inbox,
+		webhooks:         config.Webhooks,
+		mailer:           config.Mailer,
+		url:              config.Url,
+		sentiment:        sentiment.New(),
+		assets:           config.Assets,
+		dev:              config.Dev,
+	}
+	if a.clients == nil {
+		a.clients = &limiter.ClientIP{}
+	}
+	if a.cookies == nil {
+		cookies, err := NewSignedCookies(nil)
+		if err != nil {
+			return nil, err
+		}
+		a.cookies = cookies
+	}
+	if a.mailer == nil {
+		a.mailer = mail.Log{}
+	}
+	if a.regions == nil {
+		a.regions = []RegionSource{QueryRegion{}, CookieRegion{a.cookies}, LanguageRegion{}}
+		if a.accounts != nil {
+			a.regions = slices.Insert(a.regions, 1, RegionSource(AccountRegion{a.accounts, a.cookies}))
+		}
+	}
+	if a.assets == nil {
+		a.assets = embedded
+	}
+	if !a.dev {
+		hashes, err := hashAssets(a.assets)
+		if err != nil {
+			return nil, err
+		}
+		a.hashes = hashes
 	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/www/", http.StripPrefix("/www/", http.FileServer(http.Dir("./www/"))))
-	mux.HandleFunc("/search", a.limit(a.handleSearch()))
+	mux.Handle("/www/", a.handleAssets())
+	mux.HandleFunc("/search", a.limit("search", a.handleSearch()))
 	mux.HandleFunc("/podcast/{id}", a.handlePodcast())
+	mux.HandleFunc("/podcast/{id}/episode/{guid}", a.handleEpisode())
+	mux.HandleFunc("/podcast/{id}/reviews/archive", a.handleReviewArchive())
+	mux.HandleFunc("/compare", a.limit("compare", a.handleCompare()))
+	mux.HandleFunc("/favourites", a.handleFavourites())
+	mux.HandleFunc("/list", a.handleList())
+	mux.HandleFunc("GET /list/opml", a.handleExport())
+	mux.HandleFunc("POST /list/import", a.limit("search", a.handleImport()))
+	mux.HandleFunc("GET /signin", a.handleSignIn())
+	mux.HandleFunc("POST /signin", a.limit("signin", a.handleSignIn()))
+	mux.HandleFunc("/signin/link", a.handleSignInLink())
+	mux.HandleFunc("POST /signout", a.handleSignOut())
+	mux.HandleFunc("GET /account", a.auth(a.handleAccount()))
+	mux.HandleFunc("POST /account/podcasts", a.auth(a.handleAccountPodcasts()))
+	mux.HandleFunc("GET /account/webhooks", a.auth(a.handleWebhooks()))
+	mux.HandleFunc("POST /account/webhooks", a.auth(a.handleWebhooksUpdate()))
+	mux.HandleFunc("POST /account/webhooks/{hook}/deliveries/{delivery}/replay", a.auth(a.handleWebhookReplay()))
+	mux.HandleFunc("GET /inbox", a.auth(a.handleInbox()))
+	mux.HandleFunc("POST /inbox", a.auth(a.handleInboxPlayed()))
+	mux.HandleFunc("/charts/{region}/history", a.handleChartHistory())
+	mux.HandleFunc("/charts/{region}/{genre}", a.handleCharts())
+	mux.HandleFunc("GET /api/v1/top", a.limitApi("api", a.handleApiTop()))
+	mux.HandleFunc("GET /api/v1/search", a.limitApi("search", a.handleApiSearch()))
+	mux.HandleFunc("GET /api/v1/search/episodes", a.limitApi("search", a.handleApiSearchEpisodes()))
+	mux.HandleFunc("GET /api/v1/podcasts", a.limitApi("api", a.handleApiPodcasts()))
+	mux.HandleFunc("GET /api/v1/podcasts/{id}", a.limitApi("api", a.handleApiPodcast()))
+	mux.HandleFunc("GET /api/v1/podcasts/{id}/reviews", a.limitApi("api", a.handleApiReviews()))
+	mux.HandleFunc("GET /api/v1/podcasts/{id}/reviews/summary", a.limitApi("summary", a.handleApiReviewSummary()))
+	mux.HandleFunc("GET /api/v1/podcasts/{id}/reviews/archive", a.limitApi("api", a.handleApiReviewArchive()))
+	mux.HandleFunc("GET /api/v1/compare", a.limitApi("compare", a.handleApiCompare()))
+	mux.HandleFunc("GET /api/v1/charts/{region}/history", a.limitApi("api", a.handleApiChartHistory()))
+	mux.HandleFunc("POST /api/v1/opml/import", a.limitApi("search", a.handleApiImport()))
+	mux.HandleFunc("/api/", a.handleApiNotFound())
 	mux.HandleFunc("/", a.handleHome())
 	a.mux = mux
 
-	pages, err := filepath.Glob("./templates/*.html")
-	if err != nil {
+	if err := a.reloadTemplates(); err != nil {
 		return nil, err
 	}
 
-	a.cache = make(map[string]*template.Template, len(pages))
-	for _, page := range pages {
-		ts, err := template.ParseFiles("./templates/base.html", page)
-		if err != nil {
-			return nil, err
-		}
-
-		a.cache[filepath.Base(page)] = ts
-	}
-
 	return a, nil
 }
 
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// In development mode templates are checked for changes once per request, static files don't need them
+	if a.dev && !strings.HasPrefix(r.URL.Path, "/www/") {
+		if err := a.reloadTemplates(); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+
 	a.mux.ServeHTTP(w, r)
 }
 
 func (a *App) handleHome() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
-			podcasts, err := a.store.Top(region(r))
-			if err != nil {
-				log.Printf("%v", err)
-				a.render(w, r, nil, "error.html")
-				return
-			}
-
-			a.render(w, r, podcasts, "home.html")
+			a.renderChart(w, r, a.region(r), 0)
 			return
 		}
 
@@ -92,19 +243,110 @@ func (a *App) handleHome() http.HandlerFunc {
 			return
 		}
 
-		http.SetCookie(w, &http.Cookie{
+		// The picker only offers known regions, anything else isn't kept
+		region := r.Form.Get("region")
+		if _, ok := itunes.LookupRegion(region); !ok {
+			http.Redirect(w, r, r.Referer(), 301)
+			return
+		}
+
+		err := a.cookies.Set(w, &http.Cookie{
 			Name:     "region",
-			Value:    r.Form.Get("region"),
+			Value:    region,
 			HttpOnly: true,
 		})
+		if err != nil {
+			log.Printf("%v", err)
+		}
+		if user := a.user(r); user != nil {
+			_, err := a.accounts.Update(user.Id, func(u *accounts.User) error {
+				u.Region = region
+				return nil
+			})
+			if err != nil {
+				log.Printf("%v", err)
+			}
+		}
 		http.Redirect(w, r, r.Referer(), 301)
 	}
 }
 
+func (a *App) handleCharts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		region := r.PathValue("region")
+		if _, ok := itunes.LookupRegion(region); !ok {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+
+		genre := 0
+		if v := r.PathValue("genre"); v != "all" {
+			id, err := strconv.Atoi(v)
+			if _, ok := itunes.LookupGenre(id); err != nil || !ok {
+				a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+				return
+			}
+			genre = id
+		}
+
+		a.renderChart(w, r, region, genre)
+	}
+}
+
+// renderChart renders the home page with a chart, its type and limit are taken from the query.
+func (a *App) renderChart(w http.ResponseWriter, r *http.Request, region string, genre int) {
+	type response struct {
+		Region     string
+		Storefront itunes.Region
+		Chart      itunes.Chart
+		Genre      itunes.Genre
+		Genres     []itunes.Genre
+		Limits     []int
+		Podcasts   []*itunes.Podcast
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	chart := itunes.Chart{
+		Type:  itunes.ChartType("top" + r.URL.Query().Get("type")),
+		Genre: genre,
+		Limit: limit,
+	}.Normalize()
+
+	podcasts, err := a.store.Top(region, chart)
+	if err != nil {
+		log.Printf("%v", err)
+		a.render(w, r, nil, "error.html")
+		return
+	}
+
+	storefront, _ := itunes.LookupRegion(region)
+	g, _ := itunes.LookupGenre(chart.Genre)
+	a.render(w, r, response{
+		Region:     region,
+		Storefront: storefront,
+		Chart:      chart,
+		Genre:      g,
+		Genres:     itunes.Genres,
+		Limits:     []int{10, 25, 50, 100, itunes.MaxChartLimit},
+		Podcasts:   podcasts,
+	}, "home.html")
+}
+
 func (a *App) handleSearch() http.HandlerFunc {
 	type response struct {
 		Query    string
+		Type     string
+		Form     url.Values
+		Genres   []itunes.Genre
+		Error    string
+		Episodes []*itunes.Episode
 		Podcasts []*itunes.Podcast
+		Total    int
+		Page     Page
+		Prev     string
+		Next     string
+		// Favourites are ids of the visitor's favourite podcasts
+		Favourites map[string]bool
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -115,29 +357,89 @@ func (a *App) handleSearch() http.HandlerFunc {
 		}
 
 		query := r.Form.Get("query")
-		podcasts, err := a.store.Search(region(r), query)
+		if r.Form.Get("type") == "episodes" {
+			episodes, err := a.store.SearchEpisodes(a.region(r), query)
+			if err != nil {
+				log.Printf("%v", err)
+				a.render(w, r, nil, "error.html")
+				return
+			}
+
+			a.render(w, r, response{Query: query, Type: "episodes", Form: r.Form, Episodes: episodes, Total: len(episodes)}, "results.html")
+			return
+		}
+
+		q, err := searchQuery(r.Form)
+		if err != nil {
+			a.renderStatus(w, r, http.StatusBadRequest, response{Query: query, Type: "podcasts", Form: r.Form, Genres: itunes.Genres, Error: err.Error()}, "results.html")
+			return
+		}
+
+		number, err := strconv.Atoi(r.Form.Get("page"))
+		if err != nil || number < 1 {
+			number = 1
+		}
+		q.Term, q.Limit, q.Offset = query, resultsPerPage, (number-1)*resultsPerPage
+
+		results, err := a.store.Search(a.region(r), q)
 		if err != nil {
 			log.Printf("%v", err)
 			a.render(w, r, nil, "error.html")
 			return
 		}
 
-		a.render(w, r, response{query, podcasts}, "results.html")
+		page := Page{Number: number, Total: max(1, (results.Total+resultsPerPage-1)/resultsPerPage)}
+		a.render(w, r, response{
+			Query:      query,
+			Type:       "podcasts",
+			Form:       r.Form,
+			Genres:     itunes.Genres,
+			Podcasts:   results.Podcasts,
+			Total:      results.Total,
+			Page:       page,
+			Prev:       pageUrl(r, page.Prev()),
+			Next:       pageUrl(r, page.Next()),
+			Favourites: a.favourites(r),
+		}, "results.html")
 	}
 }
 
+// pageUrl links to another page of the same request.
+func pageUrl(r *http.Request, number int) string {
+	values := url.Values{}
+	for k, v := range r.Form {
+		values[k] = v
+	}
+	values.Set("page", strconv.Itoa(number))
+	return r.URL.Path + "?" + values.Encode()
+}
+
 func (a *App) handlePodcast() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		type response struct {
-			Podcast *itunes.PodcastDetail
-			Reviews []*itunes.Review
+			Podcast    *itunes.PodcastDetail
+			Episodes   []*feed.Episode
+			Page       Page
+			Reviews    *itunes.ReviewPage
+			Scored     []scoredReview
+			ReviewSort string
+			Summary    *reviews.Summary
+			Months     []reviews.Month
+			Complaints []sentiment.Keyword
+			Archived   int
+			Favourite  bool
 		}
 		var (
 			wg      sync.WaitGroup
 			pod     *itunes.PodcastDetail
 			podErr  error
-			rews    []*itunes.Review
+			fd      *feed.Feed
+			fdErr   error
+			rews    *itunes.ReviewPage
 			rewsErr error
+			// recent is the first page of the most recent reviews, which the summary covers whatever the sort
+			recent    *itunes.ReviewPage
+			recentErr error
 		)
 
 		id := r.PathValue("id")
@@ -145,16 +447,31 @@ func (a *App) handlePodcast() http.HandlerFunc {
 			a.render(w, r, nil, "404.html")
 			return
 		}
+		sort := r.URL.Query().Get("reviews")
+		if _, ok := reviewSorts[sort]; !ok {
+			sort = "recent"
+		}
 
 		wg.Add(2)
 		go func() {
 			defer wg.Done()
 			pod, podErr = a.store.Lookup(id)
+			if podErr != nil || pod.FeedUrl == "" {
+				return
+			}
+			fd, fdErr = a.feeds.Fetch(pod.FeedUrl)
 		}()
 		go func() {
 			defer wg.Done()
-			rews, rewsErr = a.store.Reviews(id, region(r))
+			rews, rewsErr = a.store.Reviews(id, a.region(r), itunes.ReviewsQuery{Sort: reviewSorts[sort]})
 		}()
+		if sort != "recent" {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				recent, recentErr = a.store.Reviews(id, a.region(r), itunes.ReviewsQuery{})
+			}()
+		}
 		wg.Wait()
 
 		if podErr != nil {
@@ -164,17 +481,83 @@ func (a *App) handlePodcast() http.HandlerFunc {
 		}
 		if rewsErr != nil {
 			log.Println(rewsErr)
-			rews = []*itunes.Review{}
+			rews = &itunes.ReviewPage{Page: 1}
+		}
+		if sort == "recent" {
+			recent = rews
+		} else if recentErr != nil {
+			log.Println(recentErr)
+			recent = &itunes.ReviewPage{Page: 1}
 		}
+		if fdErr != nil {
+			log.Println(fdErr)
+		}
+		archived, err := a.archivedReviews(id)
+		if err != nil {
+			log.Println(err)
+		}
+		// The summary covers the first page of recent reviews, fetching every page for it on each view is left to the API
+		all := withArchived(recent.Reviews, archived, a.region(r))
+		summary := reviews.Summarize(all)
+
+		var episodes []*feed.Episode
+		if fd != nil {
+			episodes = fd.Episodes
+		}
+
+		number, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		episodes, page := paginate(episodes, number, episodesPerPage)
 
-		a.render(w, r, response{pod, rews}, "podcast.html")
+		a.render(w, r, response{pod, episodes, page, rews, a.scoreReviews(rews.Reviews), sort, summary, summary.Recent(summaryMonths), a.complaints(all), len(archived), a.favourites(r)[id]}, "podcast.html")
 	}
 }
 
-func (a *App) limit(next http.HandlerFunc) http.HandlerFunc {
+func (a *App) handleEpisode() http.HandlerFunc {
+	type response struct {
+		Podcast *itunes.PodcastDetail
+		Episode *feed.Episode
+		Notes   template.HTML
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		pod, err := a.store.Lookup(r.PathValue("id"))
+		if err != nil {
+			log.Println(err)
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+		// Episodes come from the feed, a podcast without one has none
+		if pod.FeedUrl == "" {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+
+		fd, err := a.feeds.Fetch(pod.FeedUrl)
+		if err != nil {
+			log.Println(err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		ep, ok := fd.Episode(r.PathValue("guid"))
+		if !ok {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+
+		notes := ep.Content
+		if notes == "" {
+			notes = ep.Description
+		}
+
+		a.render(w, r, response{pod, ep, template.HTML(feed.Sanitize(notes))}, "episode.html")
+	}
+}
+
+func (a *App) limit(route string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if a.isLimiterEnabled && !a.limiter.Allow() {
-			a.render(w, r, nil, "limit.html")
+		if a.isLimiterEnabled && !a.allow(w, r, route) {
+			a.renderStatus(w, r, http.StatusTooManyRequests, nil, "limit.html")
 			return
 		}
 
@@ -182,36 +565,82 @@ func (a *App) limit(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// budget returns a function taking one more request from the client's quota for the route, for
+// requests that make a call to iTunes per item. It always allows requests if the limiter is off.
+func (a *App) budget(r *http.Request, route string) func() bool {
+	return func() bool {
+		return !a.isLimiterEnabled || a.limiter.Allow(route, a.clients.Key(r)).Allowed
+	}
+}
+
+// allow takes a request from the client's quota for the route and reports what is left in response headers.
+func (a *App) allow(w http.ResponseWriter, r *http.Request, route string) bool {
+	q := a.limiter.Allow(route, a.clients.Key(r))
+	if q.Limit < 0 {
+		return q.Allowed
+	}
+
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(q.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(q.Remaining))
+	h.Set("X-RateLimit-Reset", seconds(q.Reset))
+	if !q.Allowed {
+		h.Set("Retry-After", seconds(q.RetryAfter))
+	}
+
+	return q.Allowed
+}
+
 func (a *App) render(w http.ResponseWriter, r *http.Request, data any, tmpl string) {
+	a.renderStatus(w, r, http.StatusOK, data, tmpl)
+}
+
+func (a *App) renderStatus(w http.ResponseWriter, r *http.Request, status int, data any, tmpl string) {
 	type response struct {
-		Data    any
-		Region  string
-		Regions []itunes.Region
+		Data         any
+		Region       string
+		RegionSource string
+		Storefront   itunes.Region
+		Continents   []itunes.Continent
+		User         *accounts.User
 	}
 
-	t, ok := a.cache[tmpl]
+	t, ok := a.template(tmpl)
 	if !ok {
 		log.Printf("can't find template %s", tmpl)
 		http.Error(w, errorMessage, http.StatusInternalServerError)
 		return
 	}
 
-	if err := t.Execute(w, &response{
-		Data:    data,
-		Region:  region(r),
-		Regions: itunes.Regions,
+	reg, source := a.resolveRegion(r)
+	storefront, _ := itunes.LookupRegion(reg)
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, &response{
+		Data:         data,
+		Region:       reg,
+		RegionSource: source,
+		Storefront:   storefront,
+		Continents:   itunes.Continents,
+		User:         a.user(r),
 	}); err != nil {
 		log.Printf("%v", err)
 		http.Error(w, errorMessage, http.StatusInternalServerError)
 		return
 	}
+
+	w.WriteHeader(status)
+	_, _ = buf.WriteTo(w)
 }
 
-func region(r *http.Request) string {
-	cookie, err := r.Cookie("region")
-	if err != nil || cookie.Value == "" {
-		return itunes.DefaultRegion
+func formatDuration(d time.Duration) string {
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	if h > 0 {
+		return fmt.Sprintf("%d h %d min", h, m)
 	}
+	return fmt.Sprintf("%d min", m)
+}
 
-	return cookie.Value
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
 }
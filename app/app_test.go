@@ -1,15 +1,19 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/stretchr/testify/suite"
+	"github.com/timiskhakov/podfinder/app/feed"
 	"github.com/timiskhakov/podfinder/app/itunes"
-	"golang.org/x/time/rate"
+	"github.com/timiskhakov/podfinder/app/limiter"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -28,10 +32,15 @@ func (s *AppSuite) SetupTest() {
 	s.itunesServer = httptest.NewServer(s.itunesMux)
 	s.httpClient = s.itunesServer.Client()
 
+	itunesUrl, err := url.Parse(s.itunesServer.URL)
+	s.NoError(err)
+	feedClient := &http.Client{Transport: &standInTransport{itunesUrl, s.itunesServer.Client().Transport}}
+
 	app, err := NewApp(&AppConfig{
 		Store:            itunes.NewStore(s.itunesServer.URL, s.httpClient),
+		Feeds:            feed.NewClient(feedClient),
 		IsLimiterEnabled: false,
-		Limiter:          &rate.Limiter{},
+		Limiter:          limiter.NewKeyed(&limiter.KeyedConfig{}),
 	})
 	s.NoError(err)
 
@@ -44,13 +53,26 @@ func (s *AppSuite) TearDownTest() {
 	s.itunesServer.Close()
 }
 
+// standInTransport sends every request to the iTunes stand-in, so feed urls found in test data resolve to it.
+type standInTransport struct {
+	url *url.URL
+	rt  http.RoundTripper
+}
+
+func (t *standInTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.url.Scheme
+	r.URL.Host = t.url.Host
+	return t.rt.RoundTrip(r)
+}
+
 func TestAppSuite(t *testing.T) {
 	suite.Run(t, new(AppSuite))
 }
 
 func (s *AppSuite) TestNewApp() {
 	s.NotNil(s.app)
-	s.Equal(7, len(s.app.cache))
+	s.Equal(19, len(s.app.cache))
 }
 
 func (s *AppSuite) TestHandleHomeGet() {
@@ -80,6 +102,54 @@ func (s *AppSuite) TestHandleHomePost() {
 	resp, err := s.httpClient.PostForm(s.appServer.URL, url.Values{"region": []string{"fi"}})
 	s.NoError(err)
 	s.Equal(http.StatusMovedPermanently, resp.StatusCode)
+	s.Len(resp.Cookies(), 1)
+
+	resp, err = s.httpClient.PostForm(s.appServer.URL, url.Values{"region": []string{"atlantis"}})
+	s.NoError(err)
+	s.Equal(http.StatusMovedPermanently, resp.StatusCode)
+	s.Empty(resp.Cookies())
+}
+
+func (s *AppSuite) TestHandleHomeWithoutCharts() {
+	req, err := http.NewRequest(http.MethodGet, s.appServer.URL, nil)
+	s.NoError(err)
+	req.AddCookie(s.signedCookie("region", "cn"))
+
+	resp, err := http.DefaultClient.Do(req)
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(string(body), "Top charts aren't published in China.")
+	s.Contains(string(body), `<optgroup label="Asia">`)
+	s.Contains(string(body), `<option value="cn" selected>中国 (China)</option>`)
+}
+
+func (s *AppSuite) TestHandleCharts() {
+	s.serveCounted("/gb/rss/topepisodes/limit=25/genre=1303/json", "./testdata/top.json")
+
+	resp, err := http.Get(s.appServer.URL + "/charts/gb/1303?type=episodes&limit=25")
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(string(body), "Top episodes in Comedy")
+	s.Contains(string(body), `<a class="active item" href="/charts/gb/1303">Comedy</a>`)
+	s.Contains(string(body), `<a href="/podcast/1612875889">Very Scary People</a>`)
+}
+
+func (s *AppSuite) TestHandleChartsNotFound() {
+	for _, path := range []string{"/charts/zz/all", "/charts/gb/26", "/charts/gb/comedy"} {
+		resp, err := http.Get(s.appServer.URL + path)
+		s.NoError(err)
+		_ = resp.Body.Close()
+
+		s.Equal(http.StatusNotFound, resp.StatusCode, path)
+	}
 }
 
 func (s *AppSuite) TestHandleSearch() {
@@ -105,6 +175,82 @@ func (s *AppSuite) TestHandleSearch() {
 	s.Contains(string(body), "Search results")
 }
 
+func (s *AppSuite) TestHandleSearchFilters() {
+	var query url.Values
+	s.itunesMux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		http.ServeFile(w, r, "./testdata/search.json")
+	})
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/search?query=hello&attribute=title&genre=1303&explicit=false&sort=episodes", s.appServer.URL))
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("titleTerm", query.Get("attribute"))
+	s.Equal("No", query.Get("explicit"))
+	s.Contains(string(body), "2 found")
+	s.Contains(string(body), `<option value="episodes" selected>Most episodes</option>`)
+	s.Less(strings.Index(string(body), "Hello Teen Power"), strings.Index(string(body), "The Future Of Computers"))
+}
+
+func (s *AppSuite) TestHandleSearchPages() {
+	s.itunesMux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		results := make([]map[string]any, 25)
+		for i := range results {
+			results[i] = map[string]any{"collectionId": i + 1, "collectionName": fmt.Sprintf("Podcast %02d", i+1)}
+		}
+		s.NoError(json.NewEncoder(w).Encode(map[string]any{"resultCount": len(results), "results": results}))
+	})
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/search?query=hello&sort=name&page=2", s.appServer.URL))
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(string(body), "25 found")
+	s.Contains(string(body), "Page 2 of 2")
+	s.Contains(string(body), `href="/search?page=1&amp;query=hello&amp;sort=name"`)
+	s.Contains(string(body), "Podcast 21")
+	s.NotContains(string(body), "Podcast 20")
+}
+
+func (s *AppSuite) TestHandleSearchEpisodes() {
+	var query url.Values
+	s.itunesMux.HandleFunc("/search", func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		http.ServeFile(w, r, "./testdata/episodes.json")
+	})
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/search?type=episodes&query=dog+bingo", s.appServer.URL))
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("podcastEpisode", query.Get("entity"))
+	s.Contains(string(body), `<a class="active item" href="/search?type=episodes&query=dog%20bingo">Episodes</a>`)
+	s.Contains(string(body), "H.I. #136: Dog Bingo")
+	s.Contains(string(body), `<a href="/podcast/811377230">Hello Internet</a>`)
+	s.Contains(string(body), "2 h 10 min")
+}
+
+func (s *AppSuite) TestHandleSearchInvalid() {
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/search?query=hello&sort=rating", s.appServer.URL))
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+	s.Contains(string(body), "query parameter sort must be")
+}
+
 func (s *AppSuite) TestHandlePodcast() {
 	s.itunesMux.HandleFunc("/lookup", func(w http.ResponseWriter, r *http.Request) {
 		f, err := os.Open("./testdata/lookup.json")
@@ -117,7 +263,7 @@ func (s *AppSuite) TestHandlePodcast() {
 		_, err = w.Write(bytes)
 		s.NoError(err)
 	})
-	s.itunesMux.HandleFunc("/us/rss/customerreviews/id=123/json", func(w http.ResponseWriter, r *http.Request) {
+	s.itunesMux.HandleFunc("/us/rss/customerreviews/page=1/id=123/sortby=mostrecent/json", func(w http.ResponseWriter, r *http.Request) {
 		f, err := os.Open("./testdata/reviews.json")
 		s.NoError(err)
 		defer func() { _ = f.Close() }()
@@ -140,15 +286,199 @@ func (s *AppSuite) TestHandlePodcast() {
 	s.Contains(string(body), "Re-listening to the show.") // Review is in the page
 }
 
+func (s *AppSuite) TestHandlePodcastReviews() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	calls := s.serveCounted("/us/rss/customerreviews/page=1/id=811377230/sortby=mosthelpful/json", "./testdata/reviews.json")
+	recent := s.serveCounted("/us/rss/customerreviews/page=1/id=811377230/sortby=mostrecent/json", "./testdata/reviews.json")
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/podcast/811377230?reviews=helpful", s.appServer.URL))
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(int32(1), calls.Load())
+	s.Contains(string(body), `<a class="active item" href="?reviews=helpful">Most helpful</a>`)
+	s.Contains(string(body), `data-url="/api/v1/podcasts/811377230/reviews?sort=helpful" data-page="2"`)
+	// The summary covers recent reviews whatever the sort
+	s.Equal(int32(1), recent.Load())
+	s.Contains(string(body), "Covers the 50 most recent reviews")
+}
+
+func (s *AppSuite) TestHandlePodcastSummaryRecent() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	// Helpful reviews come back empty, so anything summarized is from the recent ones
+	s.serveCounted("/us/rss/customerreviews/page=1/id=811377230/sortby=mosthelpful/json", "./testdata/lookup.json")
+	s.serveCounted("/us/rss/customerreviews/page=1/id=811377230/sortby=mostrecent/json", "./testdata/reviews.json")
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/podcast/811377230?reviews=helpful", s.appServer.URL))
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.NotContains(string(body), "Re-listening to the show.")
+	s.Contains(string(body), `<div class="label">50 reviews</div>`)
+	s.Contains(string(body), "<td>Mar 2022</td>")
+	s.Contains(string(body), `<a href="/api/v1/podcasts/811377230/reviews/summary">summary of every review</a>`)
+}
+
+func (s *AppSuite) TestHandlePodcastSummary() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	calls := s.serveCounted("/us/rss/customerreviews/", "./testdata/reviews.json")
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/podcast/811377230", s.appServer.URL))
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	// The summary covers the shown page only
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(int32(1), calls.Load())
+	s.Contains(string(body), `<div class="label">50 reviews</div>`)
+	s.Contains(string(body), `<div class="review-bar-fill" style="width: 94%"></div>`)
+	s.Contains(string(body), "<td>Mar 2022</td>")
+	s.NotContains(string(body), "<td>Mar 2021</td>")
+	s.Contains(string(body), `<span class="ui basic label">disappointed <span class="detail">2</span></span>`)
+}
+
+func (s *AppSuite) TestHandlePodcastEpisodes() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	s.serveCounted("/podcast", "./testdata/feed.xml")
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/podcast/811377230?page=3", s.appServer.URL))
+
+	s.NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+	s.Contains(string(body), "H.I. #136: Dog Bingo")
+	s.Contains(string(body), "1 h 35 min")
+	s.Contains(string(body), "H.I. #134: Boaty McBoatface")
+	s.NotContains(string(body), "Page 1 of")
+}
+
+func (s *AppSuite) TestHandleEpisode() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	s.serveCounted("/podcast", "./testdata/feed.xml")
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/podcast/811377230/episode/%s", s.appServer.URL,
+		url.PathEscape("52d66949e4b0a8cec3bcdd46:52d67282e4b0cca8969714fa:5e58e0e0e4d6c9133c0e4b6a")))
+
+	s.NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+	s.Contains(string(body), "H.I. #136: Dog Bingo")
+	s.Contains(string(body), `<audio controls preload="none" src="http://traffic.libsyn.com/hellointernet/136.mp3">`)
+	s.Contains(string(body), `<a href="http://example.com/dogs" rel="nofollow noopener" target="_blank">dogs</a>`)
+	s.NotContains(string(body), "alert(1)")
+}
+
+func (s *AppSuite) TestHandleEpisodeUrlGuid() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	s.serveCounted("/podcast", "./testdata/feed.xml")
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/podcast/811377230/episode/%s", s.appServer.URL,
+		url.PathEscape("http://www.hellointernet.fm/podcast/134")))
+
+	s.NoError(err)
+	s.Equal(http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+	s.Contains(string(body), "H.I. #134: Boaty McBoatface")
+}
+
+func (s *AppSuite) TestHandleEpisodeNotFound() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	s.serveCounted("/podcast", "./testdata/feed.xml")
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/podcast/811377230/episode/unknown", s.appServer.URL))
+
+	s.NoError(err)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+	s.Contains(string(body), "is not found")
+}
+
+func (s *AppSuite) TestHandleEpisodeWithoutFeed() {
+	s.itunesMux.HandleFunc("/lookup", func(w http.ResponseWriter, r *http.Request) {
+		data, err := os.ReadFile("./testdata/lookup.json")
+		s.NoError(err)
+		_, err = w.Write(bytes.ReplaceAll(data, []byte("http://www.hellointernet.fm/podcast?format=rss"), nil))
+		s.NoError(err)
+	})
+	feeds := s.serveCounted("/podcast", "./testdata/feed.xml")
+
+	resp, err := s.httpClient.Get(fmt.Sprintf("%s/podcast/811377230/episode/unknown", s.appServer.URL))
+
+	s.NoError(err)
+	_ = resp.Body.Close()
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+	s.Zero(feeds.Load())
+}
+
 func (s *AppSuite) TestLimit() {
 	s.app.isLimiterEnabled = true
-	s.app.limiter = rate.NewLimiter(rate.Every(1*time.Minute), 0)
+	s.app.limiter = limiter.NewKeyed(&limiter.KeyedConfig{
+		Budgets: map[string]limiter.Budget{"search": {Every: time.Minute, Burst: 0}},
+	})
 
 	resp, err := s.httpClient.Get(fmt.Sprintf("%s/search?query=hello+internet", s.appServer.URL))
 	s.NoError(err)
-	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(http.StatusTooManyRequests, resp.StatusCode)
 
 	body, err := io.ReadAll(resp.Body)
 	s.NoError(err)
 	s.Contains(string(body), "An iTunes request limit has been reached")
 }
+
+func (s *AppSuite) TestLimitPerClient() {
+	s.serveCounted("/search", "./testdata/search.json")
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clients, err := limiter.NewClientIP([]string{"127.0.0.1"})
+	s.NoError(err)
+	s.app.isLimiterEnabled = true
+	s.app.clients = clients
+	s.app.limiter = limiter.NewKeyed(&limiter.KeyedConfig{
+		Budgets: map[string]limiter.Budget{"search": {Every: time.Minute, Burst: 1}},
+		Now:     func() time.Time { return now },
+	})
+	search := func(client string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/search?query=hello+internet", s.appServer.URL), nil)
+		s.NoError(err)
+		req.Header.Set("X-Forwarded-For", client)
+		resp, err := s.httpClient.Do(req)
+		s.NoError(err)
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		s.NoError(err)
+		return resp, string(body)
+	}
+
+	resp, body := search("203.0.113.1")
+	s.Contains(body, "Search results")
+	s.Equal("1", resp.Header.Get("X-RateLimit-Limit"))
+	s.Equal("0", resp.Header.Get("X-RateLimit-Remaining"))
+	s.Equal("60", resp.Header.Get("X-RateLimit-Reset"))
+
+	resp, body = search("203.0.113.1")
+	s.Equal(http.StatusTooManyRequests, resp.StatusCode)
+	s.Contains(body, "An iTunes request limit has been reached")
+	s.Equal("60", resp.Header.Get("Retry-After"))
+
+	_, body = search("203.0.113.2")
+	s.Contains(body, "Search results")
+
+	now = now.Add(time.Minute)
+	_, body = search("203.0.113.1")
+	s.Contains(body, "Search results")
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"github.com/timiskhakov/podfinder/app/reviews"
+	"io"
+	"net/http"
+	"time"
+)
+
+// archiveReviews archives a review the storefront no longer serves and an edit of one it does.
+func (s *AppSuite) archiveReviews() {
+	a := reviews.NewArchive(&reviews.ArchiveConfig{})
+	first := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err := a.Add("811377230", "us", first, []*itunes.Review{
+		{Id: "1", Title: "Gone", Content: "Old review", Rating: make([]struct{}, 1), Date: first},
+		{Id: "8414391645", Title: "Before", Content: "First version", Rating: make([]struct{}, 2), Date: first},
+	})
+	s.NoError(err)
+	_, err = a.Add("811377230", "us", first.Add(time.Hour), []*itunes.Review{
+		{Id: "8414391645", Title: "After", Content: "Second version", Rating: make([]struct{}, 5), Date: first.Add(time.Hour)},
+	})
+	s.NoError(err)
+	s.app.archive = a
+}
+
+func (s *AppSuite) TestReviewArchive() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	s.archiveReviews()
+
+	resp, err := http.Get(s.appServer.URL + "/podcast/811377230/reviews/archive")
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(string(body), "Review archive · 2 reviews, 1 edited")
+	s.Contains(string(body), "First version")
+	s.Contains(string(body), "edited by 1 Jan 2020")
+}
+
+func (s *AppSuite) TestReviewArchiveNotFound() {
+	resp, err := http.Get(s.appServer.URL + "/podcast/811377230/reviews/archive")
+	s.NoError(err)
+	_ = resp.Body.Close()
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+
+	var body struct {
+		Error apiError `json:"error"`
+	}
+	resp = s.getJson("/api/v1/podcasts/811377230/reviews/archive", &body)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (s *AppSuite) TestApiReviewArchive() {
+	s.archiveReviews()
+	var body struct {
+		Id      string              `json:"id"`
+		Reviews []apiArchivedReview `json:"reviews"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts/811377230/reviews/archive", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Len(body.Reviews, 2)
+	s.Equal("8414391645", body.Reviews[0].Id)
+	s.Equal(5, body.Reviews[0].Rating)
+	s.Len(body.Reviews[0].Edits, 1)
+	s.Equal("First version", body.Reviews[0].Edits[0].Content)
+	s.Equal(2, body.Reviews[0].Edits[0].Rating)
+	s.Empty(body.Reviews[1].Edits)
+
+	resp = s.getJson("/api/v1/podcasts/abc/reviews/archive", &body)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+}
+
+func (s *AppSuite) TestApiReviewSummaryArchived() {
+	s.serveCounted("/us/rss/customerreviews/", "./testdata/reviews.json")
+	s.archiveReviews()
+	var body struct {
+		Count  int        `json:"count"`
+		Months []apiMonth `json:"months"`
+	}
+
+	resp := s.getJson("/api/v1/podcasts/811377230/reviews/summary", &body)
+
+	// Archived reviews the storefront still serves are counted once
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(51, body.Count)
+	s.Equal("2020-01", body.Months[0].Month)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"time"
+)
+
+//go:embed templates www
+var embedded embed.FS
+
+// parseTemplates parses every page together with the base layout.
+func (a *App) parseTemplates() (map[string]*template.Template, error) {
+	pages, err := fs.Glob(a.assets, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	cache := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		ts, err := template.New("base.html").Funcs(funcs).Funcs(template.FuncMap{
+			"asset": a.asset,
+		}).ParseFS(a.assets, "templates/base.html", page)
+		if err != nil {
+			return nil, err
+		}
+
+		cache[path.Base(page)] = ts
+	}
+
+	return cache, nil
+}
+
+// template returns a parsed template, in development mode ServeHTTP parses them again once any of them changes.
+func (a *App) template(name string) (*template.Template, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	t, ok := a.cache[name]
+	return t, ok
+}
+
+// reloadTemplates parses templates unless they were parsed after their last modification.
+func (a *App) reloadTemplates() error {
+	modified, err := lastModified(a.assets, "templates")
+	if err != nil {
+		return err
+	}
+
+	a.mu.RLock()
+	current := a.cache != nil && !modified.After(a.parsed)
+	a.mu.RUnlock()
+	if current {
+		return nil
+	}
+
+	cache, err := a.parseTemplates()
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cache = cache
+	a.parsed = modified
+
+	return nil
+}
+
+func lastModified(fsys fs.FS, dir string) (time.Time, error) {
+	var last time.Time
+	err := fs.WalkDir(fsys, dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+		return nil
+	})
+
+	return last, err
+}
+
+// hashAssets fingerprints static files, so that their urls change whenever their content does.
+func hashAssets(fsys fs.FS) (map[string]string, error) {
+	hashes := make(map[string]string)
+	err := fs.WalkDir(fsys, "www", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		hashes[p[len("www/"):]] = hex.EncodeToString(sum[:])[:12]
+
+		return nil
+	})
+
+	return hashes, err
+}
+
+// asset returns the url of a static file, versioned with a hash of its content outside of development mode.
+func (a *App) asset(name string) string {
+	if h, ok := a.hashes[name]; ok {
+		return "/www/" + name + "?v=" + h
+	}
+	return "/www/" + name
+}
+
+// handleAssets serves static files, versioned urls are cached by browsers for good.
+func (a *App) handleAssets() http.Handler {
+	www, err := fs.Sub(a.assets, "www")
+	if err != nil {
+		panic(err)
+	}
+	files := http.StripPrefix("/www/", http.FileServerFS(www))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := a.hashes[r.URL.Path[len("/www/"):]]
+		if ok && r.URL.Query().Get("v") == h {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+func (s *AppSuite) TestAssets() {
+	s.itunesMux.HandleFunc("/us/rss/toppodcasts/limit=10/json", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./testdata/lookup.json")
+	})
+
+	resp, err := http.Get(s.appServer.URL)
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	link := regexp.MustCompile(`/www/css/podfinder\.css\?v=[0-9a-f]{12}`).Find(body)
+	s.NotNil(link)
+
+	cases := map[string]string{
+		string(link):                 "public, max-age=31536000, immutable",
+		"/www/css/podfinder.css":     "no-cache",
+		"/www/css/podfinder.css?v=1": "no-cache",
+	}
+	for path, cacheControl := range cases {
+		resp, err := http.Get(s.appServer.URL + path)
+		s.NoError(err)
+		_ = resp.Body.Close()
+
+		s.Equal(http.StatusOK, resp.StatusCode, path)
+		s.Equal(cacheControl, resp.Header.Get("Cache-Control"), path)
+		s.Equal("text/css; charset=utf-8", resp.Header.Get("Content-Type"), path)
+	}
+}
+
+func (s *AppSuite) TestAssetsDev() {
+	dir := s.T().TempDir()
+	s.NoError(os.CopyFS(dir, embedded))
+	app, err := NewApp(&AppConfig{
+		Store:  itunes.NewStore(s.itunesServer.URL, s.httpClient),
+		Assets: os.DirFS(dir),
+		Dev:    true,
+	})
+	s.NoError(err)
+
+	render := func() string {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/podcast/811377230/reviews/archive", nil))
+		s.Equal(http.StatusNotFound, w.Code)
+		return w.Body.String()
+	}
+
+	s.Contains(render(), `href="/www/css/podfinder.css"`)
+	s.NotContains(render(), "Gone fishing")
+
+	page := filepath.Join(dir, "templates", "404.html")
+	s.NoError(os.WriteFile(page, []byte(`{{define "content"}}Gone fishing{{end}}`), 0o644))
+	later := time.Now().Add(time.Minute)
+	s.NoError(os.Chtimes(page, later, later))
+
+	// Rendering alone doesn't look for changes, requests do
+	w := httptest.NewRecorder()
+	app.render(w, httptest.NewRequest(http.MethodGet, "/", nil), nil, "404.html")
+	s.NotContains(w.Body.String(), "Gone fishing")
+	s.Contains(render(), "Gone fishing")
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/timiskhakov/podfinder/app/accounts"
+	"github.com/timiskhakov/podfinder/app/mail"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const sessionCookie = "session"
+
+type userKey struct{}
+
+// Accounts signs visitors in with links sent to their email and keeps what they follow.
+type Accounts interface {
+	RequestLink(email string) (string, error)
+	Redeem(token string) (string, *accounts.User, error)
+	User(session string) (*accounts.User, error)
+	SignOut(session string) error
+	Update(id string, fn func(u *accounts.User) error) (*accounts.User, error)
+}
+
+// Mailer delivers sign in links.
+type Mailer interface {
+	Send(m mail.Message) error
+}
+
+// auth lets signed in users through and sends everyone else to sign in, back to the page they wanted afterwards.
+func (a *App) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.accounts == nil {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+
+		user := a.user(r)
+		if user == nil {
+			back := r.URL.RequestURI()
+			if r.Method != http.MethodGet {
+				back = backTo(r, "/account")
+			}
+			http.Redirect(w, r, "/signin?"+url.Values{"next": {back}}.Encode(), http.StatusSeeOther)
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	}
+}
+
+// user returns the signed in user of a request, or nil for visitors.
+func (a *App) user(r *http.Request) *accounts.User {
+	if user, ok := r.Context().Value(userKey{}).(*accounts.User); ok {
+		return user
+	}
+	return sessionUser(r, a.cookies, a.accounts)
+}
+
+// sessionUser looks up the user of the session cookie.
+func sessionUser(r *http.Request, cookies *SignedCookies, accs Accounts) *accounts.User {
+	if accs == nil {
+		return nil
+	}
+	session, ok := cookies.Get(r, sessionCookie)
+	if !ok {
+		return nil
+	}
+
+	user, err := accs.User(session)
+	if err != nil {
+		if !errors.Is(err, accounts.ErrInvalidToken) {
+			log.Println(err)
+		}
+		return nil
+	}
+	return user
+}
+
+type signInResponse struct {
+	Email string
+	Next  string
+	Token string
+	Sent  bool
+	Error string
+}
+
+func (a *App) handleSignIn() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.accounts == nil {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		next := safeNext(r.Form.Get("next"))
+		if r.Method == http.MethodGet {
+			a.render(w, r, signInResponse{Next: next}, "signin.html")
+			return
+		}
+
+		email := strings.TrimSpace(r.Form.Get("email"))
+		token, err := a.accounts.RequestLink(email)
+		if errors.Is(err, accounts.ErrInvalidEmail) {
+			a.renderStatus(w, r, http.StatusBadRequest, signInResponse{Email: email, Next: next, Error: "Enter an email address, such as grey@example.com."}, "signin.html")
+			return
+		}
+		if err == nil {
+			err = a.mailer.Send(mail.Message{
+				To:      email,
+				Subject: "Sign in to podfinder",
+				Body: "Open this link to sign in to podfinder:\n\n" +
+					a.signInLink(r, token, next) + "\n\n" +
+					"The link works once and only for a few minutes. If you didn't ask to sign in, ignore this email.\n",
+			})
+		}
+		if err != nil {
+			log.Printf("%v", err)
+			a.renderStatus(w, r, http.StatusInternalServerError, signInResponse{Email: email, Next: next, Error: "The link couldn't be sent, try again later."}, "signin.html")
+			return
+		}
+
+		a.render(w, r, signInResponse{Email: email, Next: next, Sent: true}, "signin.html")
+	}
+}
+
+// handleSignInLink asks to confirm before redeeming a link, mail scanners that open links would use it up otherwise.
+func (a *App) handleSignInLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.accounts == nil {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		token, next := r.Form.Get("token"), safeNext(r.Form.Get("next"))
+		if r.Method == http.MethodGet {
+			a.render(w, r, signInResponse{Token: token, Next: next}, "signin_link.html")
+			return
+		}
+
+		session, _, err := a.accounts.Redeem(token)
+		if errors.Is(err, accounts.ErrInvalidToken) {
+			a.renderStatus(w, r, http.StatusBadRequest, signInResponse{Next: next, Error: "This link has expired or was already used, request a new one."}, "signin.html")
+			return
+		}
+		if err == nil {
+			err = a.cookies.Set(w, &http.Cookie{
+				Name:     sessionCookie,
+				Value:    session,
+				Path:     "/",
+				MaxAge:   365 * 24 * 60 * 60,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		if err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		http.Redirect(w, r, next, http.StatusSeeOther)
+	}
+}
+
+func (a *App) handleSignOut() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if session, ok := a.cookies.Get(r, sessionCookie); ok && a.accounts != nil {
+			if err := a.accounts.SignOut(session); err != nil {
+				log.Printf("%v", err)
+			}
+		}
+
+		http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1, HttpOnly: true})
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+// signInLink builds the link of an email, on the configured url or the host of the request if there is none.
+func (a *App) signInLink(r *http.Request, token, next string) string {
+	base := a.url
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return strings.TrimSuffix(base, "/") + "/signin/link?" + url.Values{"token": {token}, "next": {next}}.Encode()
+}
+
+// safeNext keeps paths of this site, so that sign in links can't send visitors elsewhere.
+func safeNext(next string) string {
+	u, err := url.Parse(next)
+	if err != nil || u.Scheme != "" || u.Host != "" || !strings.HasPrefix(u.Path, "/") || strings.HasPrefix(next, "//") || strings.Contains(next, "\\") {
+		return "/account"
+	}
+	return (&url.URL{Path: u.Path, RawQuery: u.RawQuery}).String()
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"github.com/timiskhakov/podfinder/app/accounts"
+	"github.com/timiskhakov/podfinder/app/mail"
+	"github.com/timiskhakov/podfinder/app/mail/mailtest"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var linkRegexp = regexp.MustCompile(`http://\S+`)
+
+// enableAccounts keeps accounts in memory and sends sign in links to an SMTP stand-in.
+func (s *AppSuite) enableAccounts() *mailtest.Server {
+	accs, err := accounts.Open(&accounts.Config{})
+	s.NoError(err)
+	server := mailtest.NewServer()
+	s.T().Cleanup(server.Close)
+
+	s.app.accounts = accs
+	s.app.mailer = mail.NewSMTP(&mail.SMTPConfig{Addr: server.Addr, From: "podfinder@example.com"})
+	s.app.regions = slices.Insert(s.app.regions, 1, RegionSource(AccountRegion{accs, s.app.cookies}))
+	s.httpClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return server
+}
+
+func (s *AppSuite) do(method, path string, form url.Values, cookie *http.Cookie) (*http.Response, string) {
+	req, err := http.NewRequest(method, s.appServer.URL+path, strings.NewReader(form.Encode()))
+	s.NoError(err)
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	resp, err := s.httpClient.Do(req)
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+	return resp, string(body)
+}
+
+// requestLink asks for a sign in link and returns the one that was emailed.
+func (s *AppSuite) requestLink(server *mailtest.Server, email, next string) *url.URL {
+	resp, body := s.do(http.MethodPost, "/signin", url.Values{"email": {email}, "next": {next}}, nil)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(body, "We sent a link to "+email)
+
+	messages := server.Messages()
+	s.NotEmpty(messages)
+	msg := messages[len(messages)-1]
+	s.Equal([]string{email}, msg.To)
+	s.Contains(msg.Data, "Subject: Sign in to podfinder\r\n")
+	link, err := url.Parse(linkRegexp.FindString(msg.Data))
+	s.NoError(err)
+	return link
+}
+
+// signIn follows a sign in link and returns the session cookie.
+func (s *AppSuite) signIn(server *mailtest.Server, email string) *http.Cookie {
+	link := s.requestLink(server, email, "/")
+	resp, _ := s.do(http.MethodPost, "/signin/link", link.Query(), nil)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	s.Len(resp.Cookies(), 1)
+	return resp.Cookies()[0]
+}
+
+func (s *AppSuite) TestSignIn() {
+	server := s.enableAccounts()
+	s.serveCounted("/us/rss/toppodcasts/limit=10/json", "./testdata/top.json")
+
+	link := s.requestLink(server, "grey@example.com", "/account?tab=watched")
+	s.Equal(s.appServer.URL+"/signin/link", link.Scheme+"://"+link.Host+link.Path)
+
+	// Opening the link doesn't use it up
+	resp, body := s.do(http.MethodGet, link.RequestURI(), nil, nil)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(body, "Continue to podfinder")
+	resp, _ = s.do(http.MethodPost, "/signin/link", link.Query(), nil)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	s.Equal("/account?tab=watched", resp.Header.Get("Location"))
+	session := resp.Cookies()[0]
+	s.Equal(sessionCookie, session.Name)
+	s.True(session.HttpOnly)
+
+	resp, body = s.do(http.MethodGet, "/", nil, session)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(body, `<a class="item" href="/account">grey@example.com</a>`)
+	s.NotContains(body, "Sign in")
+
+	resp, _ = s.do(http.MethodPost, "/signout", nil, session)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	_, body = s.do(http.MethodGet, "/", nil, session)
+	s.Contains(body, `<a class="item" href="/signin">Sign in</a>`)
+}
+
+func (s *AppSuite) TestSignInLinkUsedOnce() {
+	server := s.enableAccounts()
+	link := s.requestLink(server, "grey@example.com", "/")
+	resp, _ := s.do(http.MethodPost, "/signin/link", link.Query(), nil)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+
+	resp, body := s.do(http.MethodPost, "/signin/link", link.Query(), nil)
+
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+	s.Contains(body, "This link has expired or was already used, request a new one.")
+	s.Empty(resp.Cookies())
+}
+
+func (s *AppSuite) TestSignInInvalidEmail() {
+	server := s.enableAccounts()
+
+	resp, body := s.do(http.MethodPost, "/signin", url.Values{"email": {"grey"}}, nil)
+
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+	s.Contains(body, "Enter an email address")
+	s.Empty(server.Messages())
+}
+
+func (s *AppSuite) TestSignInMailFails() {
+	server := s.enableAccounts()
+	server.Close()
+
+	resp, body := s.do(http.MethodPost, "/signin", url.Values{"email": {"grey@example.com"}}, nil)
+
+	s.Equal(http.StatusInternalServerError, resp.StatusCode)
+	s.Contains(body, "The link couldn&#39;t be sent, try again later.")
+}
+
+func (s *AppSuite) TestSignInDisabled() {
+	resp, err := http.Get(s.appServer.URL + "/signin")
+	s.NoError(err)
+	_ = resp.Body.Close()
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(s.appServer.URL + "/account")
+	s.NoError(err)
+	_ = resp.Body.Close()
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func (s *AppSuite) TestAuthRedirects() {
+	s.enableAccounts()
+
+	resp, _ := s.do(http.MethodGet, "/account", nil, nil)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	s.Equal("/signin?next=%2Faccount", resp.Header.Get("Location"))
+
+	resp, _ = s.do(http.MethodGet, "/account", nil, s.signedCookie(sessionCookie, "forged"))
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+
+	resp, _ = s.do(http.MethodPost, "/account/podcasts", url.Values{"action": {"subscribe"}, "podcast": {"811377230"}}, nil)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	s.Equal("/signin?next=%2Faccount", resp.Header.Get("Location"))
+}
+
+func (s *AppSuite) TestAccountPodcasts() {
+	server := s.enableAccounts()
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	session := s.signIn(server, "grey@example.com")
+
+	for _, action := range []string{"subscribe", "watch"} {
+		resp, _ := s.do(http.MethodPost, "/account/podcasts", url.Values{"action": {action}, "podcast": {"811377230"}}, session)
+		s.Equal(http.StatusSeeOther, resp.StatusCode)
+		s.Equal("/account", resp.Header.Get("Location"))
+	}
+	resp, body := s.do(http.MethodGet, "/account", nil, session)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(2, strings.Count(body, `<a href="/podcast/811377230">Hello Internet</a>`))
+	s.Contains(body, `<a href="/podcast/811377230/reviews/archive">Archived reviews</a>`)
+	_, body = s.do(http.MethodGet, "/podcast/811377230", nil, session)
+	s.Contains(body, "Unsubscribe")
+	s.Contains(body, "Stop watching reviews")
+
+	resp, _ = s.do(http.MethodPost, "/account/podcasts", url.Values{"action": {"unsubscribe"}, "podcast": {"811377230"}}, session)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	_, body = s.do(http.MethodGet, "/account", nil, session)
+	s.Equal(1, strings.Count(body, `<a href="/podcast/811377230">Hello Internet</a>`))
+
+	resp, body = s.do(http.MethodPost, "/account/podcasts", url.Values{"action": {"watch"}, "podcast": {"../811377230"}}, session)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+	s.Contains(body, "Pick a podcast to follow.")
+}
+
+func (s *AppSuite) TestAccountRegion() {
+	server := s.enableAccounts()
+	s.serveCounted("/fi/rss/toppodcasts/limit=10/json", "./testdata/top.json")
+	session := s.signIn(server, "grey@example.com")
+
+	resp, _ := s.do(http.MethodPost, "/", url.Values{"region": {"fi"}}, session)
+	s.Equal(http.StatusMovedPermanently, resp.StatusCode)
+
+	// Another device only has the session
+	resp, body := s.do(http.MethodGet, "/", nil, session)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(body, "Your account's region")
+	s.Contains(body, `<option value="fi" selected>`)
+
+	resp, _ = s.do(http.MethodPost, "/", url.Values{"region": {"atlantis"}}, session)
+	s.Equal(http.StatusMovedPermanently, resp.StatusCode)
+	user, err := s.app.accounts.(*accounts.Accounts).Find(s.hookUser(session))
+	s.NoError(err)
+	s.Equal("fi", user.Region)
+}
+
+func (s *AppSuite) TestSafeNext() {
+	tests := map[string]string{
+		"/account":                  "/account",
+		"/podcast/811377230?page=2": "/podcast/811377230?page=2",
+		"":                          "/account",
+		"account":                   "/account",
+		"//evil.example.com/":       "/account",
+		"/\\evil.example.com":       "/account",
+		"https://evil.example.com/": "/account",
+		"javascript:alert(1)":       "/account",
+	}
+
+	for next, expected := range tests {
+		s.Equal(expected, safeNext(next), next)
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"container/list"
+	"errors"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"sync"
+	"time"
+)
+
+// CacheConfig sets how long results of each Store method stay fresh and how many
+// entries the cache holds. A non-positive TTL disables caching for that method.
+// Once an entry expires it is still served for Stale more time while a single
+// background refresh replaces it.
+type CacheConfig struct {
+	TopTTL     time.Duration
+	SearchTTL  time.Duration
+	LookupTTL  time.Duration
+	ReviewsTTL time.Duration
+	Stale      time.Duration
+	Size       int
+}
+
+type CacheStats struct {
+	Hits   uint64
+	Stale  uint64
+	Misses uint64
+	Size   int
+}
+
+// CachedStore is a Store that keeps recent results of another Store in a bounded LRU cache.
+type CachedStore struct {
+	store  Store
+	config CacheConfig
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	hits    uint64
+	stale   uint64
+	misses  uint64
+}
+
+type cacheEntry struct {
+	key        string
+	value      any
+	expires    time.Time
+	refreshing bool
+}
+
+func NewCachedStore(store Store, config *CacheConfig) *CachedStore {
+	return &CachedStore{
+		store:   store,
+		config:  *config,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (c *CachedStore) Top(region string, chart itunes.Chart) ([]*itunes.Podcast, error) {
+	return cached(c, "top:"+region+":"+chart.Key(), c.config.TopTTL, func() ([]*itunes.Podcast, error) {
+		return c.store.Top(region, chart)
+	})
+}
+
+// Search caches every match of a query, sorting and paging happen after the cache so that they need no requests.
+func (c *CachedStore) Search(region string, q itunes.Query) (*itunes.SearchResults, error) {
+	all, err := cached(c, "search:"+region+":"+q.Key(), c.config.SearchTTL, func() (*itunes.SearchResults, error) {
+		return c.store.Search(region, q.All())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return q.Page(all.Podcasts), nil
+}
+
+func (c *CachedStore) SearchEpisodes(region, query string) ([]*itunes.Episode, error) {
+	return cached(c, "episodes:"+region+":"+query, c.config.SearchTTL, func() ([]*itunes.Episode, error) {
+		return c.store.SearchEpisodes(region, query)
+	})
+}
+
+func (c *CachedStore) Lookup(id string) (*itunes.PodcastDetail, error) {
+	return cached(c, "lookup:"+id, c.config.LookupTTL, func() (*itunes.PodcastDetail, error) {
+		return c.store.Lookup(id)
+	})
+}
+
+// LookupMany serves cached details and fetches the rest in one call, details are cached under the same keys as Lookup.
+func (c *CachedStore) LookupMany(ids []string) (map[string]*itunes.PodcastDetail, error) {
+	ttl := c.config.LookupTTL
+	if ttl <= 0 || c.config.Size <= 0 {
+		return c.store.LookupMany(ids)
+	}
+
+	details := make(map[string]*itunes.PodcastDetail, len(ids))
+	seen := make(map[string]bool, len(ids))
+	var misses []string
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		key := "lookup:" + id
+		v, refresh, ok := c.get(key)
+		if !ok {
+			misses = append(misses, id)
+			continue
+		}
+		if refresh {
+			go c.refresh(key, ttl, func() (any, error) { return c.store.Lookup(id) })
+		}
+		details[id] = v.(*itunes.PodcastDetail)
+	}
+	if len(misses) == 0 {
+		return details, nil
+	}
+
+	fetched, err := c.store.LookupMany(misses)
+	if err != nil && !errors.Is(err, itunes.ErrNotFound) {
+		return nil, err
+	}
+	for id, d := range fetched {
+		c.set("lookup:"+id, d, ttl)
+		details[id] = d
+	}
+
+	return details, err
+}
+
+func (c *CachedStore) Reviews(id, region string, q itunes.ReviewsQuery) (*itunes.ReviewPage, error) {
+	return cached(c, "reviews:"+id+":"+region+":"+q.Key(), c.config.ReviewsTTL, func() (*itunes.ReviewPage, error) {
+		return c.store.Reviews(id, region, q)
+	})
+}
+
+func (c *CachedStore) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{
+		Hits:   c.hits,
+		Stale:  c.stale,
+		Misses: c.misses,
+		Size:   c.order.Len(),
+	}
+}
+
+// cached returns a value stored under key or fetches and stores a new one. A stale value
+// is returned as is and refreshed in the background. Errors are never cached.
+func cached[T any](c *CachedStore, key string, ttl time.Duration, fetch func() (T, error)) (T, error) {
+	if ttl <= 0 || c.config.Size <= 0 {
+		return fetch()
+	}
+
+	if v, refresh, ok := c.get(key); ok {
+		if refresh {
+			go c.refresh(key, ttl, func() (any, error) { return fetch() })
+		}
+		return v.(T), nil
+	}
+
+	v, err := fetch()
+	if err != nil {
+		return v, err
+	}
+
+	c.set(key, v, ttl)
+	return v, nil
+}
+
+// get looks up a value by key and reports whether the caller should start a refresh of a stale entry.
+func (c *CachedStore) get(key string) (any, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	now := c.now()
+	if now.Before(entry.expires) {
+		c.order.MoveToFront(el)
+		c.hits++
+		return entry.value, false, true
+	}
+
+	if now.Before(entry.expires.Add(c.config.Stale)) {
+		c.order.MoveToFront(el)
+		c.stale++
+		refresh := !entry.refreshing
+		entry.refreshing = true
+		return entry.value, refresh, true
+	}
+
+	c.order.Remove(el)
+	delete(c.entries, key)
+	c.misses++
+	return nil, false, false
+}
+
+func (c *CachedStore) refresh(key string, ttl time.Duration, fetch func() (any, error)) {
+	v, err := fetch()
+	if err == nil {
+		c.set(key, v, ttl)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*cacheEntry).refreshing = false
+	}
+}
+
+func (c *CachedStore) set(key string, value any, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(ttl)
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.value = value
+		entry.expires = expires
+		entry.refreshing = false
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.config.Size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
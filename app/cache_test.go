@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+func (s *AppSuite) newCachedStore(size int) (*CachedStore, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewCachedStore(itunes.NewStore(s.itunesServer.URL, s.httpClient), &CacheConfig{
+		TopTTL:     time.Minute,
+		SearchTTL:  time.Minute,
+		LookupTTL:  time.Hour,
+		ReviewsTTL: time.Minute,
+		Size:       size,
+	})
+	store.now = func() time.Time { return now }
+
+	return store, &now
+}
+
+func (s *AppSuite) serveCounted(pattern, file string) *atomic.Int32 {
+	calls := &atomic.Int32{}
+	s.itunesMux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		f, err := os.Open(file)
+		s.NoError(err)
+		defer func() { _ = f.Close() }()
+
+		bytes, err := io.ReadAll(f)
+		s.NoError(err)
+
+		_, err = w.Write(bytes)
+		s.NoError(err)
+	})
+
+	return calls
+}
+
+func (s *AppSuite) TestCachedStoreTop() {
+	calls := s.serveCounted("/us/rss/toppodcasts/limit=10/json", "./testdata/top.json")
+	store, _ := s.newCachedStore(10)
+
+	first, err := store.Top("us", itunes.Chart{})
+	s.NoError(err)
+	second, err := store.Top("us", itunes.Chart{})
+	s.NoError(err)
+
+	s.Equal(int32(1), calls.Load())
+	s.Equal(first, second)
+	s.Equal(CacheStats{Hits: 1, Misses: 1, Size: 1}, store.Stats())
+}
+
+func (s *AppSuite) TestCachedStoreExpiry() {
+	calls := s.serveCounted("/search", "./testdata/search.json")
+	store, now := s.newCachedStore(10)
+
+	_, err := store.Search("us", itunes.Query{Term: "hello internet"})
+	s.NoError(err)
+	*now = now.Add(59 * time.Second)
+	_, err = store.Search("us", itunes.Query{Term: "hello internet"})
+	s.NoError(err)
+	s.Equal(int32(1), calls.Load())
+
+	*now = now.Add(time.Second)
+	_, err = store.Search("us", itunes.Query{Term: "hello internet"})
+	s.NoError(err)
+	s.Equal(int32(2), calls.Load())
+}
+
+func (s *AppSuite) TestCachedStoreKeys() {
+	calls := s.serveCounted("/search", "./testdata/search.json")
+	store, _ := s.newCachedStore(10)
+
+	for _, args := range [][2]string{{"us", "a"}, {"us", "b"}, {"fi", "a"}, {"us", "a"}} {
+		_, err := store.Search(args[0], itunes.Query{Term: args[1]})
+		s.NoError(err)
+	}
+
+	s.Equal(int32(3), calls.Load())
+}
+
+func (s *AppSuite) TestCachedStoreSearchKeys() {
+	calls := s.serveCounted("/search", "./testdata/search.json")
+	store, _ := s.newCachedStore(10)
+
+	queries := []itunes.Query{{Term: "a"}, {Term: "a", Limit: 20}, {Term: "a", Sort: itunes.SortName}, {Term: "a", Offset: 20}, {Term: "a", Sort: itunes.SortRelevance}, {Term: "a", Genre: 1303}}
+	for _, q := range queries {
+		_, err := store.Search("us", q)
+		s.NoError(err)
+	}
+
+	s.Equal(int32(2), calls.Load())
+	s.Equal(CacheStats{Hits: 4, Misses: 2, Size: 2}, store.Stats())
+}
+
+func (s *AppSuite) TestCachedStoreSearchPages() {
+	s.serveCounted("/search", "./testdata/search.json")
+	store, _ := s.newCachedStore(10)
+	uncached := itunes.NewStore(s.itunesServer.URL, s.httpClient)
+
+	queries := []itunes.Query{{Term: "a", Sort: itunes.SortEpisodes, Limit: 2, Offset: 1}, {Term: "a", Sort: itunes.SortName}, {Term: "a", Limit: 1}}
+	for _, q := range queries {
+		expected, err := uncached.Search("us", q)
+		s.NoError(err)
+		results, err := store.Search("us", q)
+		s.NoError(err)
+
+		s.Equal(expected, results)
+	}
+}
+
+func (s *AppSuite) TestCachedStoreChartKeys() {
+	calls := s.serveCounted("/us/rss/", "./testdata/top.json")
+	store, _ := s.newCachedStore(10)
+
+	charts := []itunes.Chart{{}, {Limit: 10}, {Genre: 1303}, {Type: itunes.ChartEpisodes}, {Type: itunes.ChartPodcasts, Genre: 1303}}
+	for _, chart := range charts {
+		_, err := store.Top("us", chart)
+		s.NoError(err)
+	}
+
+	s.Equal(int32(3), calls.Load())
+}
+
+func (s *AppSuite) TestCachedStoreEviction() {
+	calls := s.serveCounted("/us/rss/customerreviews/", "./testdata/reviews.json")
+	store, _ := s.newCachedStore(2)
+
+	for _, id := range []string{"1", "2", "1", "3", "1", "2"} {
+		_, err := store.Reviews(id, "us", itunes.ReviewsQuery{})
+		s.NoError(err)
+	}
+
+	// "2" is the least recently used entry when "3" arrives, so it's the one fetched again
+	s.Equal(int32(4), calls.Load())
+	s.Equal(2, store.Stats().Size)
+}
+
+func (s *AppSuite) TestCachedStoreErrors() {
+	calls := &atomic.Int32{}
+	s.itunesMux.HandleFunc("/lookup", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	store, _ := s.newCachedStore(10)
+
+	_, err := store.Lookup("123")
+	s.Error(err)
+	_, err = store.Lookup("123")
+	s.Error(err)
+
+	s.Equal(int32(2), calls.Load())
+	s.Equal(0, store.Stats().Size)
+}
+
+func (s *AppSuite) TestCachedStoreLookupMany() {
+	var ids []string
+	s.itunesMux.HandleFunc("/lookup", func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.URL.Query().Get("id"))
+		http.ServeFile(w, r, "./testdata/lookup.json")
+	})
+	store, _ := s.newCachedStore(10)
+
+	_, err := store.Lookup("811377230")
+	s.NoError(err)
+	details, err := store.LookupMany([]string{"811377230", "1", "1"})
+	s.ErrorIs(err, itunes.ErrNotFound)
+	s.Equal("Hello Internet", details["811377230"].Name)
+
+	_, err = store.LookupMany([]string{"811377230"})
+	s.NoError(err)
+
+	// Only the id missing from the cache is fetched, and a missing podcast isn't cached
+	s.Equal([]string{"811377230", "1"}, ids)
+}
+
+func (s *AppSuite) TestCachedStoreDisabled() {
+	calls := s.serveCounted("/lookup", "./testdata/lookup.json")
+	store, _ := s.newCachedStore(10)
+	store.config.LookupTTL = 0
+
+	for i := 0; i < 3; i++ {
+		_, err := store.Lookup("811377230")
+		s.NoError(err)
+	}
+
+	s.Equal(int32(3), calls.Load())
+}
+
+func (s *AppSuite) TestCachedStoreInApp() {
+	calls := s.serveCounted("/us/rss/toppodcasts/limit=10/json", "./testdata/top.json")
+	store, _ := s.newCachedStore(10)
+	s.app.store = store
+
+	for i := 0; i < 2; i++ {
+		resp, err := s.httpClient.Get(fmt.Sprintf("%s/", s.appServer.URL))
+		s.NoError(err)
+		s.Equal(http.StatusOK, resp.StatusCode)
+		_ = resp.Body.Close()
+	}
+
+	s.Equal(int32(1), calls.Load())
+}
+
+func (s *AppSuite) TestCachedStoreStale() {
+	calls := s.serveCounted("/lookup", "./testdata/lookup.json")
+	store, now := s.newCachedStore(10)
+	store.config.Stale = time.Hour
+
+	_, err := store.Lookup("811377230")
+	s.NoError(err)
+	*now = now.Add(90 * time.Minute)
+
+	for i := 0; i < 3; i++ {
+		pd, err := store.Lookup("811377230")
+		s.NoError(err)
+		s.Equal("Hello Internet", pd.Name)
+	}
+	s.Eventually(func() bool {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		return !store.entries["lookup:811377230"].Value.(*cacheEntry).refreshing
+	}, time.Second, time.Millisecond)
+
+	// One initial fetch and a single background refresh for three stale reads
+	s.Equal(int32(2), calls.Load())
+	_, err = store.Lookup("811377230")
+	s.NoError(err)
+	s.Equal(CacheStats{Hits: 1, Stale: 3, Misses: 1, Size: 1}, store.Stats())
+}
+
+func (s *AppSuite) TestCachedStoreStaleExpired() {
+	calls := s.serveCounted("/lookup", "./testdata/lookup.json")
+	store, now := s.newCachedStore(10)
+	store.config.Stale = time.Hour
+
+	_, err := store.Lookup("811377230")
+	s.NoError(err)
+	*now = now.Add(2 * time.Hour)
+	_, err = store.Lookup("811377230")
+	s.NoError(err)
+
+	s.Equal(int32(2), calls.Load())
+	s.Equal(uint64(2), store.Stats().Misses)
+}
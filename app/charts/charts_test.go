@@ -0,0 +1,105 @@
+package charts
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type ChartsSuite struct {
+	suite.Suite
+	server *httptest.Server
+	store  *itunes.Store
+	clock  *fakeClock
+}
+
+func TestChartsSuite(t *testing.T) {
+	suite.Run(t, new(ChartsSuite))
+}
+
+func (s *ChartsSuite) SetupTest() {
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/se/") {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeFile(w, r, "../testdata/top.json")
+	}))
+	s.store = itunes.NewStore(s.server.URL, s.server.Client())
+	s.clock = newFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+}
+
+func (s *ChartsSuite) TearDownTest() {
+	s.server.Close()
+}
+
+// fakeClock only moves when told to, timers fire once it passes their deadline.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []timer
+	waiting chan struct{}
+}
+
+type timer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, waiting: make(chan struct{}, 100)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+	} else {
+		c.timers = append(c.timers, timer{c.now.Add(d), ch})
+		c.waiting <- struct{}{}
+	}
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	var pending []timer
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- c.now
+	}
+	c.timers = pending
+}
+
+// waitForTimer blocks until somebody waits for the clock.
+func (c *fakeClock) waitForTimer() {
+	<-c.waiting
+}
+
+func podcasts(ids ...string) []*itunes.Podcast {
+	result := make([]*itunes.Podcast, len(ids))
+	for i, id := range ids {
+		result[i] = &itunes.Podcast{Id: id, Name: "Podcast " + id}
+	}
+	return result
+}
@@ -0,0 +1,173 @@
+package charts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"golang.org/x/sync/errgroup"
+	"log"
+	"sync"
+	"time"
+)
+
+type Store interface {
+	Top(region string, chart itunes.Chart) ([]*itunes.Podcast, error)
+}
+
+// Clock lets tests control when snapshots are taken.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Listener is told about every snapshot along with the one taken before it, previous is empty for the first snapshot of a region.
+type Listener interface {
+	Snapshot(region string, previous, current Snapshot)
+}
+
+type CollectorConfig struct {
+	Store   Store
+	History *History
+	// Regions to snapshot, every region with top charts by default
+	Regions []string
+	Every   time.Duration
+	// Concurrency limits how many charts are fetched at once, one by default
+	Concurrency int
+	// Listener is told about new snapshots, if it is set
+	Listener Listener
+	Clock    Clock
+}
+
+// Collector periodically snapshots top charts into History.
+type Collector struct {
+	store       Store
+	history     *History
+	regions     []string
+	every       time.Duration
+	concurrency int
+	listener    Listener
+	clock       Clock
+}
+
+func NewCollector(config *CollectorConfig) *Collector {
+	c := &Collector{
+		store:       config.Store,
+		history:     config.History,
+		regions:     config.Regions,
+		every:       config.Every,
+		concurrency: config.Concurrency,
+		listener:    config.Listener,
+		clock:       config.Clock,
+	}
+	if len(c.regions) == 0 {
+		for _, r := range itunes.Regions {
+			if r.HasCharts() {
+				c.regions = append(c.regions, r.Value)
+			}
+		}
+	}
+	if c.concurrency <= 0 {
+		c.concurrency = 1
+	}
+	if c.clock == nil {
+		c.clock = realClock{}
+	}
+
+	return c
+}
+
+// Run takes snapshots until the context is done. The first one is taken right away,
+// unless the history already has a snapshot younger than the interval.
+func (c *Collector) Run(ctx context.Context) error {
+	last, err := c.latest()
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.clock.After(c.every - c.clock.Now().Sub(last)):
+		}
+
+		last = c.clock.Now()
+		if err := c.Collect(ctx); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+}
+
+// Collect snapshots charts of every region, a region failing doesn't stop the others.
+func (c *Collector) Collect(ctx context.Context) error {
+	now := c.clock.Now()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	g := errgroup.Group{}
+	g.SetLimit(c.concurrency)
+	for _, region := range c.regions {
+		if ctx.Err() != nil {
+			break
+		}
+
+		g.Go(func() error {
+			err := c.snapshot(region, now)
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("can't snapshot %s chart: %w", region, err))
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (c *Collector) snapshot(region string, now time.Time) error {
+	podcasts, err := c.store.Top(region, itunes.Chart{})
+	if err != nil {
+		return err
+	}
+	previous, _, err := c.history.Last(region)
+	if err != nil {
+		return err
+	}
+	if err := c.history.Add(region, now, podcasts); err != nil {
+		return err
+	}
+
+	if c.listener != nil {
+		current := Snapshot{Time: now, Ids: make([]string, len(podcasts))}
+		for i, p := range podcasts {
+			current.Ids[i] = p.Id
+		}
+		c.listener.Snapshot(region, previous, current)
+	}
+	return nil
+}
+
+// latest returns the time of the most recent snapshot of any region.
+func (c *Collector) latest() (time.Time, error) {
+	var last time.Time
+	for _, region := range c.regions {
+		t, ok, err := c.history.Latest(region)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if ok && t.After(last) {
+			last = t
+		}
+	}
+	return last, nil
+}
@@ -0,0 +1,133 @@
+package charts
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type snapshots struct {
+	mu       sync.Mutex
+	previous map[string]Snapshot
+	current  map[string]Snapshot
+}
+
+func (l *snapshots) Snapshot(region string, previous, current Snapshot) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.previous[region], l.current[region] = previous, current
+}
+
+func (s *ChartsSuite) TestCollect() {
+	h := NewHistory(&HistoryConfig{})
+	c := NewCollector(&CollectorConfig{
+		Store:   s.store,
+		History: h,
+		Regions: []string{"us", "se", "fi"},
+		Clock:   s.clock,
+	})
+
+	err := c.Collect(context.Background())
+
+	s.ErrorContains(err, "can't snapshot se chart")
+	r, err := h.Report("fi", s.clock.Now())
+	s.NoError(err)
+	s.Len(r.Times, 1)
+	s.Len(r.Podcasts, 10)
+	s.Equal("Very Scary People", r.Podcasts[0].Name)
+	_, ok, err := h.Latest("se")
+	s.NoError(err)
+	s.False(ok)
+}
+
+func (s *ChartsSuite) TestCollectListener() {
+	h := NewHistory(&HistoryConfig{})
+	earlier := s.clock.Now().Add(-time.Hour)
+	s.NoError(h.Add("fi", earlier, podcasts("a", "b")))
+	l := &snapshots{previous: map[string]Snapshot{}, current: map[string]Snapshot{}}
+	c := NewCollector(&CollectorConfig{
+		Store:    s.store,
+		History:  h,
+		Regions:  []string{"us", "se", "fi"},
+		Listener: l,
+		Clock:    s.clock,
+	})
+
+	_ = c.Collect(context.Background())
+
+	// Regions that failed aren't reported
+	s.Len(l.current, 2)
+	s.Equal(Snapshot{Time: earlier, Ids: []string{"a", "b"}}, l.previous["fi"])
+	s.Equal(s.clock.Now(), l.current["fi"].Time)
+	s.Len(l.current["fi"].Ids, 10)
+	s.Equal(Snapshot{}, l.previous["us"])
+	s.Equal(l.current["fi"].Ids, l.current["us"].Ids)
+}
+
+func (s *ChartsSuite) TestCollectorRun() {
+	h := NewHistory(&HistoryConfig{})
+	c := NewCollector(&CollectorConfig{
+		Store:   s.store,
+		History: h,
+		Regions: []string{"us"},
+		Every:   time.Hour,
+		Clock:   s.clock,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	snapshots := func() int {
+		r, err := h.Report("us", time.Time{})
+		s.NoError(err)
+		return len(r.Times)
+	}
+
+	s.clock.waitForTimer()
+	s.Equal(1, snapshots())
+
+	s.clock.Advance(59 * time.Minute)
+	s.Equal(1, snapshots())
+
+	s.clock.Advance(time.Minute)
+	s.clock.waitForTimer()
+	s.Equal(2, snapshots())
+
+	cancel()
+	s.NoError(<-done)
+}
+
+func (s *ChartsSuite) TestCollectorRunAfterRestart() {
+	h := NewHistory(&HistoryConfig{})
+	s.NoError(h.Add("us", s.clock.Now().Add(-30*time.Minute), podcasts("a")))
+	c := NewCollector(&CollectorConfig{
+		Store:   s.store,
+		History: h,
+		Regions: []string{"us"},
+		Every:   time.Hour,
+		Clock:   s.clock,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = c.Run(ctx) }()
+
+	s.clock.waitForTimer()
+	latest, _, err := h.Latest("us")
+	s.NoError(err)
+	s.Equal(s.clock.Now().Add(-30*time.Minute), latest)
+
+	s.clock.Advance(30 * time.Minute)
+	s.clock.waitForTimer()
+	latest, _, err = h.Latest("us")
+	s.NoError(err)
+	s.Equal(s.clock.Now(), latest)
+}
+
+func (s *ChartsSuite) TestNewCollectorDefaults() {
+	c := NewCollector(&CollectorConfig{})
+
+	s.Contains(c.regions, "us")
+	s.NotContains(c.regions, "cn")
+	s.Equal(1, c.concurrency)
+	s.NotNil(c.clock)
+}
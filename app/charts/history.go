@@ -0,0 +1,175 @@
+package charts
+
+import (
+	"errors"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"github.com/timiskhakov/podfinder/app/storage"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrUnknownRegion = errors.New("unknown region")
+
+type Podcast struct {
+	Id     string `json:"id"`
+	Artist string `json:"artist"`
+	Name   string `json:"name"`
+	Image  string `json:"image"`
+}
+
+// Snapshot is a top chart at a point in time, Ids are ordered by rank.
+type Snapshot struct {
+	Time time.Time `json:"time"`
+	Ids  []string  `json:"ids"`
+}
+
+// chart is the stored history of a region, podcast details are kept once rather than in every snapshot.
+type chart struct {
+	Snapshots []Snapshot         `json:"snapshots"`
+	Podcasts  map[string]Podcast `json:"podcasts"`
+}
+
+type HistoryConfig struct {
+	// Dir holds a file per region, history is kept in memory only if it is empty.
+	Dir string
+	// Retention is how long snapshots are kept, they are kept forever if it is zero.
+	Retention time.Duration
+}
+
+// History stores top chart snapshots per region.
+type History struct {
+	dir       string
+	retention time.Duration
+	mu        sync.Mutex
+	files     map[string]*storage.File[chart]
+}
+
+func NewHistory(config *HistoryConfig) *History {
+	return &History{
+		dir:       config.Dir,
+		retention: config.Retention,
+		files:     make(map[string]*storage.File[chart]),
+	}
+}
+
+func (h *History) file(region string) (*storage.File[chart], error) {
+	if _, ok := itunes.LookupRegion(region); !ok {
+		return nil, ErrUnknownRegion
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if f, ok := h.files[region]; ok {
+		return f, nil
+	}
+
+	path := ""
+	if h.dir != "" {
+		path = filepath.Join(h.dir, region+".json")
+	}
+	f, err := storage.Open[chart](path)
+	if err != nil {
+		return nil, err
+	}
+	h.files[region] = f
+
+	return f, nil
+}
+
+// Add saves a snapshot of a region's chart and drops snapshots older than the retention.
+func (h *History) Add(region string, at time.Time, podcasts []*itunes.Podcast) error {
+	f, err := h.file(region)
+	if err != nil {
+		return err
+	}
+
+	return f.Update(func(c *chart) error {
+		if c.Podcasts == nil {
+			c.Podcasts = make(map[string]Podcast)
+		}
+
+		s := Snapshot{Time: at, Ids: make([]string, len(podcasts))}
+		for i, p := range podcasts {
+			s.Ids[i] = p.Id
+			c.Podcasts[p.Id] = Podcast{Id: p.Id, Artist: p.Artist, Name: p.Name, Image: p.Image}
+		}
+
+		i := len(c.Snapshots)
+		for i > 0 && c.Snapshots[i-1].Time.After(at) {
+			i--
+		}
+		c.Snapshots = append(c.Snapshots[:i], append([]Snapshot{s}, c.Snapshots[i:]...)...)
+
+		if h.retention > 0 {
+			c.prune(at.Add(-h.retention))
+		}
+
+		return nil
+	})
+}
+
+// prune drops snapshots taken before a time and podcasts no snapshot refers to anymore.
+func (c *chart) prune(before time.Time) {
+	i := 0
+	for i < len(c.Snapshots) && c.Snapshots[i].Time.Before(before) {
+		i++
+	}
+	c.Snapshots = c.Snapshots[i:]
+
+	seen := make(map[string]bool)
+	for _, s := range c.Snapshots {
+		for _, id := range s.Ids {
+			seen[id] = true
+		}
+	}
+	for id := range c.Podcasts {
+		if !seen[id] {
+			delete(c.Podcasts, id)
+		}
+	}
+}
+
+// Latest returns the time of the latest snapshot of a region.
+func (h *History) Latest(region string) (time.Time, bool, error) {
+	s, ok, err := h.Last(region)
+	return s.Time, ok, err
+}
+
+// Last returns the latest snapshot of a region.
+func (h *History) Last(region string) (Snapshot, bool, error) {
+	f, err := h.file(region)
+	if err != nil {
+		return Snapshot{}, false, err
+	}
+
+	var (
+		last Snapshot
+		ok   bool
+	)
+	f.View(func(c *chart) {
+		if n := len(c.Snapshots); n > 0 {
+			last, ok = c.Snapshots[n-1], true
+			last.Ids = slices.Clone(last.Ids)
+		}
+	})
+
+	return last, ok, nil
+}
+
+// Report analyses snapshots of a region taken since a time.
+func (h *History) Report(region string, since time.Time) (*Report, error) {
+	f, err := h.file(region)
+	if err != nil {
+		return nil, err
+	}
+
+	var r *Report
+	f.View(func(c *chart) {
+		r = report(region, c, since)
+	})
+
+	return r, nil
+}
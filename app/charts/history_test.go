@@ -0,0 +1,98 @@
+package charts
+
+import (
+	"time"
+)
+
+func (s *ChartsSuite) TestReport() {
+	h := NewHistory(&HistoryConfig{})
+	start := s.clock.Now()
+	snapshots := [][]string{
+		{"a", "b", "c"},
+		{"b", "a", "d"},
+		{"b", "a", "c"},
+		{"e", "b", "c"},
+	}
+	for i, ids := range snapshots {
+		s.NoError(h.Add("us", start.Add(time.Duration(i)*time.Hour), podcasts(ids...)))
+	}
+
+	r, err := h.Report("us", start.Add(time.Hour))
+
+	s.NoError(err)
+	s.Equal("us", r.Region)
+	s.Equal([]time.Time{start.Add(time.Hour), start.Add(2 * time.Hour), start.Add(3 * time.Hour)}, r.Times)
+
+	ids := func(entries []*Entry) []string {
+		result := make([]string, len(entries))
+		for i, e := range entries {
+			result[i] = e.Id
+		}
+		return result
+	}
+	s.Equal([]string{"e", "b", "c", "a", "d"}, ids(r.Podcasts))
+	s.Equal([]string{"e"}, ids(r.NewEntries))
+	s.Equal([]string{"a"}, ids(r.DropOuts))
+	s.Equal([]string{"b", "c", "a", "e", "d"}, ids(r.LongestRunning))
+
+	b := r.Podcasts[1]
+	s.Equal("Podcast b", b.Name)
+	s.Equal([]int{1, 1, 2}, b.Ranks)
+	s.Equal(2, b.Rank)
+	s.Equal(1, b.Best)
+	s.Equal(3, b.Run)
+	s.Equal(start.Add(time.Hour), b.RunStart)
+	s.Equal(start.Add(3*time.Hour), b.RunEnd)
+
+	c := r.Podcasts[2]
+	s.Equal([]int{0, 3, 3}, c.Ranks)
+	s.Equal(2, c.Run)
+	s.Equal(start.Add(2*time.Hour), c.RunStart)
+}
+
+func (s *ChartsSuite) TestReportEmpty() {
+	h := NewHistory(&HistoryConfig{})
+
+	r, err := h.Report("us", s.clock.Now())
+
+	s.NoError(err)
+	s.Empty(r.Times)
+	s.Empty(r.Podcasts)
+	s.Empty(r.NewEntries)
+}
+
+func (s *ChartsSuite) TestHistoryRetention() {
+	h := NewHistory(&HistoryConfig{Retention: 2 * time.Hour})
+	start := s.clock.Now()
+
+	s.NoError(h.Add("us", start, podcasts("a")))
+	s.NoError(h.Add("us", start.Add(time.Hour), podcasts("b")))
+	s.NoError(h.Add("us", start.Add(3*time.Hour), podcasts("b")))
+
+	f, err := h.file("us")
+	s.NoError(err)
+	f.View(func(c *chart) {
+		s.Len(c.Snapshots, 2)
+		s.Equal(start.Add(time.Hour), c.Snapshots[0].Time)
+		s.NotContains(c.Podcasts, "a")
+	})
+}
+
+func (s *ChartsSuite) TestHistoryPersistence() {
+	dir := s.T().TempDir()
+	s.NoError(NewHistory(&HistoryConfig{Dir: dir}).Add("fi", s.clock.Now(), podcasts("a", "b")))
+
+	latest, ok, err := NewHistory(&HistoryConfig{Dir: dir}).Latest("fi")
+
+	s.NoError(err)
+	s.True(ok)
+	s.True(s.clock.Now().Equal(latest))
+}
+
+func (s *ChartsSuite) TestHistoryUnknownRegion() {
+	h := NewHistory(&HistoryConfig{Dir: s.T().TempDir()})
+
+	s.ErrorIs(h.Add("../us", s.clock.Now(), podcasts("a")), ErrUnknownRegion)
+	_, err := h.Report("zz", s.clock.Now())
+	s.ErrorIs(err, ErrUnknownRegion)
+}
@@ -0,0 +1,114 @@
+package charts
+
+import (
+	"sort"
+	"time"
+)
+
+// longestRunning is how many entries Report.LongestRunning lists.
+const longestRunning = 10
+
+// Report describes how a chart changed over a period.
+type Report struct {
+	Region string
+	// Times of the snapshots in the period, oldest first
+	Times []time.Time
+	// Podcasts lists every podcast charted in the period, by their latest rank, then the best one
+	Podcasts []*Entry
+	// NewEntries are podcasts in the latest snapshot, which weren't in the one before
+	NewEntries []*Entry
+	// DropOuts are podcasts in the snapshot before the latest one, which aren't in the latest
+	DropOuts []*Entry
+	// LongestRunning are podcasts with the longest uninterrupted stays in the chart
+	LongestRunning []*Entry
+}
+
+// Entry is the history of a podcast in a chart.
+type Entry struct {
+	Podcast
+	// Ranks follow Report.Times, zero means the podcast wasn't in the chart
+	Ranks []int
+	// Rank is the rank in the latest snapshot
+	Rank int
+	Best int
+	// Run is the longest uninterrupted stay, counted in snapshots
+	Run      int
+	RunStart time.Time
+	RunEnd   time.Time
+}
+
+func report(region string, c *chart, since time.Time) *Report {
+	r := &Report{Region: region}
+
+	var snapshots []Snapshot
+	for _, s := range c.Snapshots {
+		if !s.Time.Before(since) {
+			snapshots = append(snapshots, s)
+		}
+	}
+
+	entries := make(map[string]*Entry)
+	for i, s := range snapshots {
+		r.Times = append(r.Times, s.Time)
+		for rank, id := range s.Ids {
+			e, ok := entries[id]
+			if !ok {
+				e = &Entry{Podcast: c.Podcasts[id], Ranks: make([]int, len(snapshots))}
+				e.Id = id
+				entries[id] = e
+				r.Podcasts = append(r.Podcasts, e)
+			}
+			e.Ranks[i] = rank + 1
+		}
+	}
+
+	for _, e := range r.Podcasts {
+		e.Rank = e.Ranks[len(e.Ranks)-1]
+		start := 0
+		for i, rank := range e.Ranks {
+			if rank == 0 {
+				start = i + 1
+				continue
+			}
+			if e.Best == 0 || rank < e.Best {
+				e.Best = rank
+			}
+			if run := i - start + 1; run > e.Run {
+				e.Run, e.RunStart, e.RunEnd = run, r.Times[start], r.Times[i]
+			}
+		}
+	}
+
+	sort.SliceStable(r.Podcasts, func(i, j int) bool {
+		a, b := r.Podcasts[i], r.Podcasts[j]
+		if (a.Rank == 0) != (b.Rank == 0) {
+			return a.Rank != 0
+		}
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+		return a.Best < b.Best
+	})
+
+	if n := len(snapshots); n > 1 {
+		for _, e := range r.Podcasts {
+			latest, previous := e.Ranks[n-1], e.Ranks[n-2]
+			if latest != 0 && previous == 0 {
+				r.NewEntries = append(r.NewEntries, e)
+			}
+			if latest == 0 && previous != 0 {
+				r.DropOuts = append(r.DropOuts, e)
+			}
+		}
+	}
+
+	r.LongestRunning = append(r.LongestRunning, r.Podcasts...)
+	sort.SliceStable(r.LongestRunning, func(i, j int) bool {
+		return r.LongestRunning[i].Run > r.LongestRunning[j].Run
+	})
+	if len(r.LongestRunning) > longestRunning {
+		r.LongestRunning = r.LongestRunning[:longestRunning]
+	}
+
+	return r
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const maxCompareRegions = 10
+
+// comparison is a matrix of podcasts charting in several regions.
+type comparison struct {
+	Regions []itunes.Region
+	Rows    []*comparisonRow
+	// Unique rows chart in a single region only, Global ones chart everywhere
+	Unique []*comparisonRow
+	Global []*comparisonRow
+	// Failed lists regions whose charts couldn't be fetched
+	Failed []string
+}
+
+type comparisonRow struct {
+	Podcast *itunes.Podcast
+	// Ranks follow comparison.Regions, zero means the podcast doesn't chart in the region
+	Ranks []int
+	Count int
+	Best  int
+}
+
+type apiComparisonRow struct {
+	Podcast apiPodcast `json:"podcast"`
+	Ranks   []int      `json:"ranks"`
+	Count   int        `json:"count"`
+	Best    int        `json:"best"`
+}
+
+func (a *App) handleCompare() http.HandlerFunc {
+	type response struct {
+		Query      string
+		Error      string
+		Comparison *comparison
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("regions")
+		if query == "" {
+			defaults := []string{"us", "gb"}
+			// Regions without charts can't be compared, visitors from them get the defaults alone
+			if reg, _ := itunes.LookupRegion(a.region(r)); reg.HasCharts() {
+				defaults = append([]string{reg.Value}, defaults...)
+			}
+			query = strings.Join(dedupe(defaults), ",")
+		}
+
+		regions, err := compareRegions(query)
+		if err != nil {
+			a.renderStatus(w, r, http.StatusBadRequest, response{query, err.Error(), nil}, "compare.html")
+			return
+		}
+
+		c := a.compare(regions, itunes.Chart{})
+		if len(c.Failed) == len(regions) {
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		a.render(w, r, response{query, "", c}, "compare.html")
+	}
+}
+
+func (a *App) handleApiCompare() http.HandlerFunc {
+	type response struct {
+		Regions []string           `json:"regions"`
+		Rows    []apiComparisonRow `json:"rows"`
+		Unique  []apiComparisonRow `json:"unique"`
+		Global  []apiComparisonRow `json:"global"`
+		Failed  []string           `json:"failed"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		regions, err := compareRegions(r.URL.Query().Get("regions"))
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		chart, err := apiChart(r)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		c := a.compare(regions, chart)
+		if len(c.Failed) == len(regions) {
+			writeApiError(w, http.StatusBadGateway, "can't fetch top podcasts")
+			return
+		}
+
+		writeJson(w, http.StatusOK, response{
+			Regions: regions,
+			Rows:    toApiComparisonRows(c.Rows),
+			Unique:  toApiComparisonRows(c.Unique),
+			Global:  toApiComparisonRows(c.Global),
+			Failed:  nonNil(c.Failed),
+		})
+	}
+}
+
+// compare fetches charts of the regions concurrently.
+func (a *App) compare(regions []string, chart itunes.Chart) *comparison {
+	var (
+		wg     sync.WaitGroup
+		charts = make([][]*itunes.Podcast, len(regions))
+		errs   = make([]error, len(regions))
+	)
+
+	wg.Add(len(regions))
+	for i, region := range regions {
+		go func() {
+			defer wg.Done()
+			charts[i], errs[i] = a.store.Top(region, chart)
+		}()
+	}
+	wg.Wait()
+
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			log.Println(err)
+			failed = append(failed, regions[i])
+		}
+	}
+
+	return compareCharts(regions, charts, failed)
+}
+
+// compareCharts lines up charts of the regions, failed regions have no chart and are left out of
+// what is unique or global.
+func compareCharts(regions []string, charts [][]*itunes.Podcast, failed []string) *comparison {
+	c := &comparison{Failed: failed}
+	for _, region := range regions {
+		reg, _ := itunes.LookupRegion(region)
+		c.Regions = append(c.Regions, reg)
+	}
+
+	rows := make(map[string]*comparisonRow)
+	for i, chart := range charts {
+		for rank, p := range chart {
+			row, ok := rows[p.Id]
+			if !ok {
+				row = &comparisonRow{Podcast: p, Ranks: make([]int, len(regions))}
+				rows[p.Id] = row
+				c.Rows = append(c.Rows, row)
+			}
+			if row.Ranks[i] != 0 {
+				continue
+			}
+			row.Ranks[i] = rank + 1
+			row.Count++
+			if row.Best == 0 || rank+1 < row.Best {
+				row.Best = rank + 1
+			}
+		}
+	}
+
+	sort.SliceStable(c.Rows, func(i, j int) bool {
+		if c.Rows[i].Count != c.Rows[j].Count {
+			return c.Rows[i].Count > c.Rows[j].Count
+		}
+		return c.Rows[i].Best < c.Rows[j].Best
+	})
+
+	fetched := len(regions) - len(failed)
+	for _, row := range c.Rows {
+		if row.Count == 1 && fetched > 1 {
+			c.Unique = append(c.Unique, row)
+		}
+		if row.Count == fetched {
+			c.Global = append(c.Global, row)
+		}
+	}
+
+	return c
+}
+
+// compareRegions parses a comma separated list of regions with charts.
+func compareRegions(query string) ([]string, error) {
+	var regions []string
+	for _, v := range strings.Split(query, ",") {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" {
+			continue
+		}
+		reg, ok := itunes.LookupRegion(v)
+		if !ok {
+			return nil, fmt.Errorf("unknown region %s", v)
+		}
+		if !reg.HasCharts() {
+			return nil, fmt.Errorf("top charts aren't published in %s", reg.Name)
+		}
+		regions = append(regions, v)
+	}
+
+	regions = dedupe(regions)
+	if len(regions) < 2 || len(regions) > maxCompareRegions {
+		return nil, fmt.Errorf("between 2 and %d regions can be compared", maxCompareRegions)
+	}
+
+	return regions, nil
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := values[:0:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func toApiComparisonRows(rows []*comparisonRow) []apiComparisonRow {
+	result := make([]apiComparisonRow, len(rows))
+	for i, row := range rows {
+		result[i] = apiComparisonRow{
+			Podcast: toApiPodcasts([]*itunes.Podcast{row.Podcast})[0],
+			Ranks:   row.Ranks,
+			Count:   row.Count,
+			Best:    row.Best,
+		}
+	}
+	return result
+}
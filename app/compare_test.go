@@ -0,0 +1,151 @@
+package main
+
+import (
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"github.com/timiskhakov/podfinder/app/limiter"
+	"io"
+	"net/http"
+	"time"
+)
+
+func (s *AppSuite) TestCompareCharts() {
+	chart := func(ids ...string) []*itunes.Podcast {
+		podcasts := make([]*itunes.Podcast, len(ids))
+		for i, id := range ids {
+			podcasts[i] = &itunes.Podcast{Id: id}
+		}
+		return podcasts
+	}
+	ids := func(rows []*comparisonRow) []string {
+		result := make([]string, len(rows))
+		for i, row := range rows {
+			result[i] = row.Podcast.Id
+		}
+		return result
+	}
+
+	c := compareCharts([]string{"us", "gb", "fi"}, [][]*itunes.Podcast{
+		chart("a", "b", "c"),
+		chart("b", "d", "a"),
+		chart("e", "a", "b"),
+	}, nil)
+
+	s.Equal("United Kingdom", c.Regions[1].Name)
+	s.Equal([]string{"a", "b", "e", "d", "c"}, ids(c.Rows))
+	s.Equal([]int{1, 3, 2}, c.Rows[0].Ranks)
+	s.Equal(3, c.Rows[0].Count)
+	s.Equal(1, c.Rows[0].Best)
+	s.Equal([]string{"a", "b"}, ids(c.Global))
+	s.Equal([]string{"e", "d", "c"}, ids(c.Unique))
+
+	// A region that failed has no chart and takes no part in what is unique or global
+	c = compareCharts([]string{"us", "gb", "fi"}, [][]*itunes.Podcast{
+		chart("a", "b", "c"),
+		nil,
+		chart("c", "d", "a"),
+	}, []string{"gb"})
+
+	s.Equal([]string{"gb"}, c.Failed)
+	s.Equal([]int{1, 0, 3}, c.Rows[0].Ranks)
+	s.Equal([]string{"a", "c"}, ids(c.Global))
+	s.Equal([]string{"b", "d"}, ids(c.Unique))
+}
+
+func (s *AppSuite) TestCompareRegions() {
+	regions, err := compareRegions(" US,gb,,us,fi ")
+	s.NoError(err)
+	s.Equal([]string{"us", "gb", "fi"}, regions)
+
+	for _, query := range []string{"", "us", "us,us", "us,zz", "us,cn", "us,gb,fi,se,no,dk,de,fr,it,es,pt"} {
+		_, err := compareRegions(query)
+		s.Error(err, query)
+	}
+}
+
+func (s *AppSuite) TestHandleCompare() {
+	s.serveCounted("/us/rss/toppodcasts/limit=10/json", "./testdata/top.json")
+	s.serveCounted("/fi/rss/toppodcasts/limit=10/json", "./testdata/top.json")
+
+	resp, err := http.Get(s.appServer.URL + "/compare?regions=us,fi,gb")
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(string(body), "Top chart of gb couldn't be fetched.")
+	s.Contains(string(body), `<th title="Finland">fi</th>`)
+	s.Contains(string(body), `<a href="/podcast/1612875889">Very Scary People</a>`)
+}
+
+func (s *AppSuite) TestHandleCompareDefault() {
+	s.serveCounted("/us/rss/toppodcasts/limit=10/json", "./testdata/top.json")
+	s.serveCounted("/gb/rss/toppodcasts/limit=10/json", "./testdata/top.json")
+	s.serveCounted("/fi/rss/toppodcasts/limit=10/json", "./testdata/top.json")
+
+	for language, heading := range map[string]string{"fi-FI": `<th title="Finland">fi</th>`, "zh-CN": `<th title="United Kingdom">gb</th>`} {
+		req, err := http.NewRequest(http.MethodGet, s.appServer.URL+"/compare", nil)
+		s.NoError(err)
+		req.Header.Set("Accept-Language", language)
+		resp, err := http.DefaultClient.Do(req)
+		s.NoError(err)
+		body, err := io.ReadAll(resp.Body)
+		s.NoError(err)
+		_ = resp.Body.Close()
+
+		s.Equal(http.StatusOK, resp.StatusCode, language)
+		s.Contains(string(body), heading, language)
+		s.NotContains(string(body), `title="China"`, language)
+	}
+}
+
+func (s *AppSuite) TestHandleCompareInvalid() {
+	resp, err := http.Get(s.appServer.URL + "/compare?regions=us,zz")
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+	s.Contains(string(body), "unknown region zz")
+}
+
+func (s *AppSuite) TestApiCompare() {
+	calls := s.serveCounted("/rss/toppodcasts/limit=25/genre=1303/json", "./testdata/top.json")
+	s.itunesMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		http.ServeFile(w, r, "./testdata/top.json")
+	})
+	var body struct {
+		Regions []string           `json:"regions"`
+		Rows    []apiComparisonRow `json:"rows"`
+		Global  []apiComparisonRow `json:"global"`
+		Unique  []apiComparisonRow `json:"unique"`
+		Failed  []string           `json:"failed"`
+	}
+
+	resp := s.getJson("/api/v1/compare?regions=us,gb&genre=1303&limit=25", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(int32(2), calls.Load())
+	s.Equal([]string{"us", "gb"}, body.Regions)
+	s.Len(body.Rows, 10)
+	s.Len(body.Global, 10)
+	s.Empty(body.Unique)
+	s.Empty(body.Failed)
+	s.Equal([]int{1, 1}, body.Rows[0].Ranks)
+}
+
+func (s *AppSuite) TestApiCompareLimit() {
+	s.app.isLimiterEnabled = true
+	s.app.limiter = limiter.NewKeyed(&limiter.KeyedConfig{
+		Budgets: map[string]limiter.Budget{"compare": {Every: time.Minute, Burst: 0}},
+	})
+	var body struct {
+		Error apiError `json:"error"`
+	}
+
+	resp := s.getJson("/api/v1/compare?regions=us,gb", &body)
+
+	s.Equal(http.StatusTooManyRequests, resp.StatusCode)
+}
@@ -0,0 +1,545 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"github.com/timiskhakov/podfinder/app/limiter"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const envPrefix = "PODFINDER_"
+
+type Config struct {
+	Server   Server   `yaml:"server"`
+	Http     Http     `yaml:"http"`
+	Itunes   Itunes   `yaml:"itunes"`
+	Cache    Cache    `yaml:"cache"`
+	Limiter  Limiter  `yaml:"limiter"`
+	Assets   Assets   `yaml:"assets"`
+	Geoip    Geoip    `yaml:"geoip"`
+	Cookies  Cookies  `yaml:"cookies"`
+	Charts   Charts   `yaml:"charts"`
+	Archive  Archive  `yaml:"archive"`
+	Accounts Accounts `yaml:"accounts"`
+	Inbox    Inbox    `yaml:"inbox"`
+	Webhooks Webhooks `yaml:"webhooks"`
+}
+
+type Server struct {
+	Port              int           `yaml:"port"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Http configures the client used for outgoing requests to iTunes and podcast feeds.
+type Http struct {
+	Timeout             time.Duration `yaml:"timeout"`
+	FeedTimeout         time.Duration `yaml:"feed_timeout"`
+	MaxIdleConns        int           `yaml:"max_idle_conns"`
+	MaxConnsPerHost     int           `yaml:"max_conns_per_host"`
+	MaxIdleConnsPerHost int           `yaml:"max_idle_conns_per_host"`
+}
+
+type Itunes struct {
+	Url string `yaml:"url"`
+}
+
+type Cache struct {
+	Enabled    bool          `yaml:"enabled"`
+	TopTTL     time.Duration `yaml:"top_ttl"`
+	SearchTTL  time.Duration `yaml:"search_ttl"`
+	LookupTTL  time.Duration `yaml:"lookup_ttl"`
+	ReviewsTTL time.Duration `yaml:"reviews_ttl"`
+	Stale      time.Duration `yaml:"stale"`
+	Size       int           `yaml:"size"`
+}
+
+type Limiter struct {
+	Enabled        bool             `yaml:"enabled"`
+	Idle           time.Duration    `yaml:"idle"`
+	TrustedProxies []string         `yaml:"trusted_proxies"`
+	Routes         map[string]Route `yaml:"routes"`
+}
+
+// Assets switches templates and static files from the ones embedded into the binary to a directory on disk.
+type Assets struct {
+	Dev bool   `yaml:"dev"`
+	Dir string `yaml:"dir"`
+}
+
+// Geoip points to a MaxMind DB file, such as GeoLite2 Country, used to detect regions of new visitors.
+type Geoip struct {
+	Database string `yaml:"database"`
+}
+
+// Cookies holds the key visitor cookies are signed with, a random key is used if it is empty,
+// which signs visitors out of their choices on every restart.
+type Cookies struct {
+	Key string `yaml:"key"`
+}
+
+// Charts configures collection of top chart snapshots for the chart history.
+type Charts struct {
+	Enabled     bool          `yaml:"enabled"`
+	Every       time.Duration `yaml:"every"`
+	Retention   time.Duration `yaml:"retention"`
+	Dir         string        `yaml:"dir"`
+	Concurrency int           `yaml:"concurrency"`
+}
+
+// Archive configures collection of reviews of watched podcasts, which are kept after the storefront stops serving them.
+type Archive struct {
+	Enabled     bool          `yaml:"enabled"`
+	Podcasts    []string      `yaml:"podcasts"`
+	Regions     []string      `yaml:"regions"`
+	Every       time.Duration `yaml:"every"`
+	Dir         string        `yaml:"dir"`
+	Concurrency int           `yaml:"concurrency"`
+}
+
+// Accounts configures signing in with links sent by email, links are logged instead if smtp.addr is empty.
+type Accounts struct {
+	Enabled    bool          `yaml:"enabled"`
+	File       string        `yaml:"file"`
+	Url        string        `yaml:"url"`
+	LinkTTL    time.Duration `yaml:"link_ttl"`
+	SessionTTL time.Duration `yaml:"session_ttl"`
+	Smtp       Smtp          `yaml:"smtp"`
+}
+
+// Inbox configures polling feeds of podcasts users subscribe to for their inboxes, it needs accounts.
+type Inbox struct {
+	Enabled     bool          `yaml:"enabled"`
+	Dir         string        `yaml:"dir"`
+	Every       time.Duration `yaml:"every"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+	Concurrency int           `yaml:"concurrency"`
+}
+
+// Webhooks configures sending events of podcasts users watch to urls they register, it needs accounts.
+// Chart events need charts and review events need the archive.
+type Webhooks struct {
+	Enabled     bool          `yaml:"enabled"`
+	File        string        `yaml:"file"`
+	Attempts    int           `yaml:"attempts"`
+	Backoff     time.Duration `yaml:"backoff"`
+	Timeout     time.Duration `yaml:"timeout"`
+	Concurrency int           `yaml:"concurrency"`
+	// AllowPrivate lets hooks reach loopback and private addresses, which is only safe if every user is trusted
+	AllowPrivate bool `yaml:"allow_private"`
+}
+
+type Smtp struct {
+	Addr     string `yaml:"addr"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
+type Route struct {
+	Every time.Duration `yaml:"every"`
+	Burst int           `yaml:"burst"`
+}
+
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:              3000,
+			ReadTimeout:       1 * time.Second,
+			ReadHeaderTimeout: 2 * time.Second,
+			WriteTimeout:      5 * time.Second,
+			IdleTimeout:       30 * time.Second,
+			ShutdownTimeout:   5 * time.Second,
+		},
+		Http: Http{
+			Timeout:             2 * time.Second,
+			FeedTimeout:         5 * time.Second,
+			MaxIdleConns:        100,
+			MaxConnsPerHost:     100,
+			MaxIdleConnsPerHost: 100,
+		},
+		Itunes: Itunes{
+			Url: "https://itunes.apple.com",
+		},
+		Cache: Cache{
+			Enabled:    true,
+			TopTTL:     10 * time.Minute,
+			SearchTTL:  5 * time.Minute,
+			LookupTTL:  time.Hour,
+			ReviewsTTL: 10 * time.Minute,
+			Stale:      time.Hour,
+			Size:       1000,
+		},
+		Limiter: Limiter{
+			Enabled:        true,
+			Idle:           10 * time.Minute,
+			TrustedProxies: []string{},
+			Routes: map[string]Route{
+				"search":  {Every: time.Minute, Burst: 20},
+				"api":     {Every: time.Second, Burst: 60},
+				"compare": {Every: 10 * time.Second, Burst: 10},
+				"summary": {Every: 30 * time.Second, Burst: 5},
+				"signin":  {Every: time.Minute, Burst: 5},
+			},
+		},
+		Assets: Assets{
+			Dir: ".",
+		},
+		Charts: Charts{
+			Every:       6 * time.Hour,
+			Retention:   90 * 24 * time.Hour,
+			Dir:         "data/charts",
+			Concurrency: 4,
+		},
+		Archive: Archive{
+			Podcasts:    []string{},
+			Regions:     []string{"us"},
+			Every:       24 * time.Hour,
+			Dir:         "data/reviews",
+			Concurrency: 2,
+		},
+		Accounts: Accounts{
+			File:       "data/accounts.json",
+			LinkTTL:    15 * time.Minute,
+			SessionTTL: 30 * 24 * time.Hour,
+			Smtp: Smtp{
+				From: "podfinder@localhost",
+			},
+		},
+		Inbox: Inbox{
+			Dir:         "data/inbox",
+			Every:       time.Hour,
+			MaxBackoff:  24 * time.Hour,
+			Concurrency: 4,
+		},
+		Webhooks: Webhooks{
+			File:        "data/webhooks.json",
+			Attempts:    6,
+			Backoff:     time.Minute,
+			Timeout:     5 * time.Second,
+			Concurrency: 4,
+		},
+	}
+}
+
+// Load builds the configuration from defaults, a YAML file, PODFINDER_* environment variables and
+// command line flags, each overriding the previous one. It also reports whether --print-config was given.
+func Load(args []string, getenv func(string) string) (*Config, bool, error) {
+	// The first pass only finds out where the file is, flags are applied for real once it's loaded,
+	// since flags of routes that only the file adds don't exist before
+	path, print, err := fileFlags(args)
+	if err != nil {
+		return nil, false, err
+	}
+	if path == "" {
+		path = getenv(envPrefix + "CONFIG")
+	}
+
+	c := Default()
+	if path != "" {
+		if err := c.readFile(path); err != nil {
+			return nil, false, err
+		}
+	}
+
+	fs := c.flagSet(&path, &print)
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "config" || f.Name == "print-config" {
+			return
+		}
+		if v := getenv(envName(f.Name)); v != "" {
+			if err := fs.Set(f.Name, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", envName(f.Name), err))
+			}
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, false, err
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, false, err
+	}
+
+	return c, print, nil
+}
+
+// fileFlags picks --config and --print-config out of the arguments and skips every other flag.
+func fileFlags(args []string) (path string, print bool, err error) {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			break
+		}
+		if !strings.HasPrefix(args[i], "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+
+		switch name {
+		case "config":
+			if !hasValue {
+				if i+1 == len(args) {
+					return "", false, errors.New("flag needs an argument: -config")
+				}
+				i++
+				value = args[i]
+			}
+			path = value
+		case "print-config":
+			print = true
+			if hasValue {
+				if print, err = strconv.ParseBool(value); err != nil {
+					return "", false, fmt.Errorf("invalid boolean value %q for -print-config: %w", value, err)
+				}
+			}
+		}
+	}
+
+	return path, print, nil
+}
+
+func (c *Config) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	// Decoding merges into maps, route budgets of the file replace the default ones instead, so that a budget can be removed
+	routes := c.Limiter.Routes
+	c.Limiter.Routes = nil
+
+	d := yaml.NewDecoder(f)
+	d.KnownFields(true)
+	if err := d.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if c.Limiter.Routes == nil {
+		c.Limiter.Routes = routes
+	}
+
+	return nil
+}
+
+// flagSet binds a flag to every setting, named after its YAML path: server.port is --server-port.
+func (c *Config) flagSet(path *string, print *bool) *flag.FlagSet {
+	fs := flag.NewFlagSet("podfinder", flag.ContinueOnError)
+
+	fs.StringVar(path, "config", *path, "path to a YAML configuration file")
+	fs.BoolVar(print, "print-config", *print, "print the effective configuration and exit")
+
+	fs.IntVar(&c.Server.Port, "server-port", c.Server.Port, "port to listen on")
+	fs.DurationVar(&c.Server.ReadTimeout, "server-read-timeout", c.Server.ReadTimeout, "maximum duration for reading a request")
+	fs.DurationVar(&c.Server.ReadHeaderTimeout, "server-read-header-timeout", c.Server.ReadHeaderTimeout, "maximum duration for reading request headers")
+	fs.DurationVar(&c.Server.WriteTimeout, "server-write-timeout", c.Server.WriteTimeout, "maximum duration for writing a response")
+	fs.DurationVar(&c.Server.IdleTimeout, "server-idle-timeout", c.Server.IdleTimeout, "how long keep-alive connections are kept idle")
+	fs.DurationVar(&c.Server.ShutdownTimeout, "server-shutdown-timeout", c.Server.ShutdownTimeout, "how long to wait for requests to finish on shutdown")
+
+	fs.DurationVar(&c.Http.Timeout, "http-timeout", c.Http.Timeout, "timeout of requests to iTunes")
+	fs.DurationVar(&c.Http.FeedTimeout, "http-feed-timeout", c.Http.FeedTimeout, "timeout of requests to podcast feeds")
+	fs.IntVar(&c.Http.MaxIdleConns, "http-max-idle-conns", c.Http.MaxIdleConns, "maximum number of idle outgoing connections")
+	fs.IntVar(&c.Http.MaxConnsPerHost, "http-max-conns-per-host", c.Http.MaxConnsPerHost, "maximum number of outgoing connections per host")
+	fs.IntVar(&c.Http.MaxIdleConnsPerHost, "http-max-idle-conns-per-host", c.Http.MaxIdleConnsPerHost, "maximum number of idle outgoing connections per host")
+
+	fs.StringVar(&c.Itunes.Url, "itunes-url", c.Itunes.Url, "base url of the iTunes API")
+
+	fs.BoolVar(&c.Cache.Enabled, "cache-enabled", c.Cache.Enabled, "cache iTunes responses")
+	fs.DurationVar(&c.Cache.TopTTL, "cache-top-ttl", c.Cache.TopTTL, "how long top charts stay fresh")
+	fs.DurationVar(&c.Cache.SearchTTL, "cache-search-ttl", c.Cache.SearchTTL, "how long search results stay fresh")
+	fs.DurationVar(&c.Cache.LookupTTL, "cache-lookup-ttl", c.Cache.LookupTTL, "how long podcast details stay fresh")
+	fs.DurationVar(&c.Cache.ReviewsTTL, "cache-reviews-ttl", c.Cache.ReviewsTTL, "how long reviews stay fresh")
+	fs.DurationVar(&c.Cache.Stale, "cache-stale", c.Cache.Stale, "how long expired entries are served while being refreshed")
+	fs.IntVar(&c.Cache.Size, "cache-size", c.Cache.Size, "maximum number of cached responses")
+
+	fs.BoolVar(&c.Limiter.Enabled, "limiter-enabled", c.Limiter.Enabled, "limit requests per client")
+	fs.DurationVar(&c.Limiter.Idle, "limiter-idle", c.Limiter.Idle, "how long idle clients are remembered")
+	fs.Var((*listValue)(&c.Limiter.TrustedProxies), "limiter-trusted-proxies", "comma separated addresses or CIDR ranges of trusted proxies")
+	for _, name := range sortedKeys(c.Limiter.Routes) {
+		route := routeValue{c.Limiter.Routes, name}
+		fs.Var(route.every(), "limiter-routes-"+name+"-every", "how often a request is added to the "+name+" budget")
+		fs.Var(route.burst(), "limiter-routes-"+name+"-burst", "maximum number of "+name+" requests at once")
+	}
+
+	fs.BoolVar(&c.Assets.Dev, "assets-dev", c.Assets.Dev, "read templates and static files from disk and reload templates on change")
+	fs.StringVar(&c.Assets.Dir, "assets-dir", c.Assets.Dir, "directory containing templates and www in development mode")
+
+	fs.StringVar(&c.Geoip.Database, "geoip-database", c.Geoip.Database, "path to a MaxMind DB file used to detect regions, detection by location is off if empty")
+
+	fs.StringVar(&c.Cookies.Key, "cookies-key", c.Cookies.Key, "key of at least 32 bytes to sign cookies with, random on every start if empty")
+
+	fs.BoolVar(&c.Charts.Enabled, "charts-enabled", c.Charts.Enabled, "collect top chart snapshots for the chart history")
+	fs.DurationVar(&c.Charts.Every, "charts-every", c.Charts.Every, "how often top charts are snapshotted")
+	fs.DurationVar(&c.Charts.Retention, "charts-retention", c.Charts.Retention, "how long snapshots are kept, forever if zero")
+	fs.StringVar(&c.Charts.Dir, "charts-dir", c.Charts.Dir, "directory snapshots are stored in, they are kept in memory only if empty")
+	fs.IntVar(&c.Charts.Concurrency, "charts-concurrency", c.Charts.Concurrency, "how many charts are fetched at once")
+
+	fs.BoolVar(&c.Archive.Enabled, "archive-enabled", c.Archive.Enabled, "archive reviews of watched podcasts")
+	fs.Var((*listValue)(&c.Archive.Podcasts), "archive-podcasts", "comma separated ids of podcasts to archive reviews of")
+	fs.Var((*listValue)(&c.Archive.Regions), "archive-regions", "comma separated regions to archive reviews from")
+	fs.DurationVar(&c.Archive.Every, "archive-every", c.Archive.Every, "how often reviews are archived")
+	fs.StringVar(&c.Archive.Dir, "archive-dir", c.Archive.Dir, "directory reviews are stored in, they are kept in memory only if empty")
+	fs.IntVar(&c.Archive.Concurrency, "archive-concurrency", c.Archive.Concurrency, "how many podcasts are fetched at once")
+
+	fs.BoolVar(&c.Accounts.Enabled, "accounts-enabled", c.Accounts.Enabled, "let visitors sign in with links sent by email")
+	fs.StringVar(&c.Accounts.File, "accounts-file", c.Accounts.File, "file accounts are stored in, they are kept in memory only if empty")
+	fs.StringVar(&c.Accounts.Url, "accounts-url", c.Accounts.Url, "base url of sign in links, such as https://podfinder.example.com")
+	fs.DurationVar(&c.Accounts.LinkTTL, "accounts-link-ttl", c.Accounts.LinkTTL, "how long a sign in link can be used")
+	fs.DurationVar(&c.Accounts.SessionTTL, "accounts-session-ttl", c.Accounts.SessionTTL, "how long users stay signed in")
+	fs.StringVar(&c.Accounts.Smtp.Addr, "accounts-smtp-addr", c.Accounts.Smtp.Addr, "host:port of the SMTP server, links are logged if empty")
+	fs.StringVar(&c.Accounts.Smtp.Username, "accounts-smtp-username", c.Accounts.Smtp.Username, "SMTP username, no authentication if empty")
+	fs.StringVar(&c.Accounts.Smtp.Password, "accounts-smtp-password", c.Accounts.Smtp.Password, "SMTP password")
+	fs.StringVar(&c.Accounts.Smtp.From, "accounts-smtp-from", c.Accounts.Smtp.From, "address emails are sent from")
+
+	fs.BoolVar(&c.Inbox.Enabled, "inbox-enabled", c.Inbox.Enabled, "poll feeds of subscribed podcasts for inboxes of new episodes")
+	fs.StringVar(&c.Inbox.Dir, "inbox-dir", c.Inbox.Dir, "directory inboxes are stored in, they are kept in memory only if empty")
+	fs.DurationVar(&c.Inbox.Every, "inbox-every", c.Inbox.Every, "how often a feed is polled")
+	fs.DurationVar(&c.Inbox.MaxBackoff, "inbox-max-backoff", c.Inbox.MaxBackoff, "longest delay before polling a failing feed again")
+	fs.IntVar(&c.Inbox.Concurrency, "inbox-concurrency", c.Inbox.Concurrency, "how many feeds are polled at once")
+
+	fs.BoolVar(&c.Webhooks.Enabled, "webhooks-enabled", c.Webhooks.Enabled, "send chart and review events of watched podcasts to webhooks users register")
+	fs.StringVar(&c.Webhooks.File, "webhooks-file", c.Webhooks.File, "file webhooks and their deliveries are stored in, they are kept in memory only if empty")
+	fs.IntVar(&c.Webhooks.Attempts, "webhooks-attempts", c.Webhooks.Attempts, "how many times a delivery is tried before it fails")
+	fs.DurationVar(&c.Webhooks.Backoff, "webhooks-backoff", c.Webhooks.Backoff, "delay before a failed delivery is tried again, doubled after every attempt")
+	fs.DurationVar(&c.Webhooks.Timeout, "webhooks-timeout", c.Webhooks.Timeout, "timeout of a delivery")
+	fs.IntVar(&c.Webhooks.Concurrency, "webhooks-concurrency", c.Webhooks.Concurrency, "how many deliveries are sent at once")
+	fs.BoolVar(&c.Webhooks.AllowPrivate, "webhooks-allow-private", c.Webhooks.AllowPrivate, "let webhooks reach loopback and private addresses")
+
+	return fs
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read_header_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+
+	check(c.Http.Timeout > 0, "http.timeout must be positive")
+	check(c.Http.FeedTimeout > 0, "http.feed_timeout must be positive")
+	check(c.Http.MaxIdleConns >= 0, "http.max_idle_conns can't be negative")
+	check(c.Http.MaxConnsPerHost >= 0, "http.max_conns_per_host can't be negative")
+	check(c.Http.MaxIdleConnsPerHost >= 0, "http.max_idle_conns_per_host can't be negative")
+
+	u, err := url.Parse(c.Itunes.Url)
+	check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "itunes.url must be an absolute http(s) url, got %q", c.Itunes.Url)
+
+	check(c.Cache.TopTTL >= 0, "cache.top_ttl can't be negative")
+	check(c.Cache.SearchTTL >= 0, "cache.search_ttl can't be negative")
+	check(c.Cache.LookupTTL >= 0, "cache.lookup_ttl can't be negative")
+	check(c.Cache.ReviewsTTL >= 0, "cache.reviews_ttl can't be negative")
+	check(c.Cache.Stale >= 0, "cache.stale can't be negative")
+	check(c.Cache.Size >= 0, "cache.size can't be negative")
+
+	check(c.Limiter.Idle >= 0, "limiter.idle can't be negative")
+	if _, err := limiter.NewClientIP(c.Limiter.TrustedProxies); err != nil {
+		errs = append(errs, fmt.Errorf("limiter.trusted_proxies: %w", err))
+	}
+	for _, name := range sortedKeys(c.Limiter.Routes) {
+		route := c.Limiter.Routes[name]
+		check(route.Every >= 0, "limiter.routes.%s.every can't be negative", name)
+		check(route.Burst >= 0, "limiter.routes.%s.burst can't be negative", name)
+	}
+
+	check(!c.Assets.Dev || c.Assets.Dir != "", "assets.dir is required in development mode")
+
+	check(c.Cookies.Key == "" || len(c.Cookies.Key) >= 32, "cookies.key must be at least 32 bytes long")
+
+	check(!c.Charts.Enabled || c.Charts.Every > 0, "charts.every must be positive")
+	check(c.Charts.Retention >= 0, "charts.retention can't be negative")
+	check(c.Charts.Concurrency > 0, "charts.concurrency must be positive")
+
+	check(!c.Archive.Enabled || c.Archive.Every > 0, "archive.every must be positive")
+	check(c.Archive.Concurrency > 0, "archive.concurrency must be positive")
+	for _, id := range c.Archive.Podcasts {
+		_, err := strconv.ParseUint(id, 10, 64)
+		check(err == nil, "archive.podcasts: invalid podcast id %q", id)
+	}
+	for _, region := range c.Archive.Regions {
+		r, ok := itunes.LookupRegion(region)
+		check(ok && r.HasReviews(), "archive.regions: region %q has no reviews", region)
+	}
+
+	if c.Accounts.Enabled {
+		u, err := url.Parse(c.Accounts.Url)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "accounts.url must be an absolute http(s) url, got %q", c.Accounts.Url)
+		check(c.Accounts.Smtp.Addr == "" || c.Accounts.Smtp.From != "", "accounts.smtp.from is required to send emails")
+	}
+	check(c.Accounts.LinkTTL > 0, "accounts.link_ttl must be positive")
+	check(c.Accounts.SessionTTL > 0, "accounts.session_ttl must be positive")
+
+	check(!c.Inbox.Enabled || c.Accounts.Enabled, "inbox.enabled needs accounts.enabled, inboxes belong to signed in users")
+	check(!c.Inbox.Enabled || c.Inbox.Every > 0, "inbox.every must be positive")
+	check(c.Inbox.MaxBackoff >= c.Inbox.Every, "inbox.max_backoff can't be shorter than inbox.every")
+	check(c.Inbox.Concurrency > 0, "inbox.concurrency must be positive")
+
+	check(!c.Webhooks.Enabled || c.Accounts.Enabled, "webhooks.enabled needs accounts.enabled, webhooks belong to signed in users")
+	check(c.Webhooks.Attempts > 0, "webhooks.attempts must be positive")
+	check(c.Webhooks.Backoff > 0, "webhooks.backoff must be positive")
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.Concurrency > 0, "webhooks.concurrency must be positive")
+
+	return errors.Join(errs...)
+}
+
+// redacted replaces secrets in printed configurations.
+const redacted = "REDACTED"
+
+// Write prints the configuration as YAML with secrets redacted.
+func (c *Config) Write(w io.Writer) error {
+	printed := *c
+	printed.Cookies.Key = redact(c.Cookies.Key)
+	printed.Accounts.Smtp.Password = redact(c.Accounts.Smtp.Password)
+
+	e := yaml.NewEncoder(w)
+	e.SetIndent(2)
+	if err := e.Encode(printed); err != nil {
+		return err
+	}
+	return e.Close()
+}
+
+// redact hides a secret, leaving it empty if it isn't set.
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// envName maps a flag name to its environment variable: server-port is PODFINDER_SERVER_PORT.
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
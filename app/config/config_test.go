@@ -0,0 +1,237 @@
+package config
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ConfigSuite struct {
+	suite.Suite
+	env map[string]string
+}
+
+func TestConfigSuite(t *testing.T) {
+	suite.Run(t, new(ConfigSuite))
+}
+
+func (s *ConfigSuite) SetupTest() {
+	s.env = map[string]string{}
+}
+
+func (s *ConfigSuite) getenv(key string) string {
+	return s.env[key]
+}
+
+func (s *ConfigSuite) writeFile(content string) string {
+	path := filepath.Join(s.T().TempDir(), "podfinder.yaml")
+	s.NoError(os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func (s *ConfigSuite) TestLoadDefaults() {
+	c, print, err := Load(nil, s.getenv)
+
+	s.NoError(err)
+	s.False(print)
+	s.Equal(Default(), c)
+}
+
+func (s *ConfigSuite) TestLoadFile() {
+	path := s.writeFile(`
+server:
+  port: 8080
+itunes:
+  url: http://localhost:9000
+limiter:
+  trusted_proxies: [10.0.0.0/8]
+  routes:
+    search:
+      every: 30s
+      burst: 5
+`)
+
+	c, _, err := Load([]string{"--config", path}, s.getenv)
+
+	s.NoError(err)
+	s.Equal(8080, c.Server.Port)
+	s.Equal(time.Second, c.Server.ReadTimeout)
+	s.Equal("http://localhost:9000", c.Itunes.Url)
+	s.Equal([]string{"10.0.0.0/8"}, c.Limiter.TrustedProxies)
+	s.Equal(map[string]Route{"search": {Every: 30 * time.Second, Burst: 5}}, c.Limiter.Routes)
+}
+
+func (s *ConfigSuite) TestLoadFileRoutes() {
+	tests := map[string]map[string]Route{
+		"limiter:\n  enabled: true\n":                      Default().Limiter.Routes,
+		"limiter:\n  routes: {}\n":                         {},
+		"limiter:\n  routes:\n    api:\n      every: 2s\n": {"api": {Every: 2 * time.Second}},
+	}
+
+	for content, routes := range tests {
+		c, _, err := Load([]string{"--config", s.writeFile(content)}, s.getenv)
+
+		s.NoError(err)
+		s.Equal(routes, c.Limiter.Routes, content)
+	}
+}
+
+func (s *ConfigSuite) TestLoadFileRouteFlags() {
+	path := s.writeFile("limiter:\n  routes:\n    feeds:\n      every: 2s\n      burst: 3\n")
+
+	c, _, err := Load([]string{"--limiter-routes-feeds-burst", "5", "--config=" + path}, s.getenv)
+
+	s.NoError(err)
+	s.Equal(map[string]Route{"feeds": {Every: 2 * time.Second, Burst: 5}}, c.Limiter.Routes)
+}
+
+func (s *ConfigSuite) TestLoadFileFromEnv() {
+	s.env["PODFINDER_CONFIG"] = s.writeFile("server:\n  port: 8080\n")
+
+	c, _, err := Load(nil, s.getenv)
+
+	s.NoError(err)
+	s.Equal(8080, c.Server.Port)
+}
+
+func (s *ConfigSuite) TestLoadPrecedence() {
+	path := s.writeFile("server:\n  port: 8080\n  write_timeout: 10s\ncache:\n  size: 10\n")
+	s.env["PODFINDER_SERVER_PORT"] = "8081"
+	s.env["PODFINDER_CACHE_SIZE"] = "20"
+	s.env["PODFINDER_LIMITER_TRUSTED_PROXIES"] = "10.0.0.1, 10.0.0.2"
+	s.env["PODFINDER_LIMITER_ROUTES_API_BURST"] = "5"
+
+	c, _, err := Load([]string{"--config", path, "--server-port", "8082", "--limiter-routes-api-every=2s"}, s.getenv)
+
+	s.NoError(err)
+	s.Equal(8082, c.Server.Port)
+	s.Equal(10*time.Second, c.Server.WriteTimeout)
+	s.Equal(20, c.Cache.Size)
+	s.Equal([]string{"10.0.0.1", "10.0.0.2"}, c.Limiter.TrustedProxies)
+	s.Equal(Route{Every: 2 * time.Second, Burst: 5}, c.Limiter.Routes["api"])
+}
+
+func (s *ConfigSuite) TestLoadPrintConfig() {
+	tests := map[string]bool{"--print-config": true, "-print-config=true": true, "--print-config=false": false}
+
+	for arg, expected := range tests {
+		_, print, err := Load([]string{arg}, s.getenv)
+
+		s.NoError(err, arg)
+		s.Equal(expected, print, arg)
+	}
+}
+
+func (s *ConfigSuite) TestLoadErrors() {
+	cases := []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+	}{
+		{"unknown flag", []string{"--unknown"}, nil, ""},
+		{"invalid flag", []string{"--server-port", "port"}, nil, ""},
+		{"invalid env", nil, map[string]string{"PODFINDER_HTTP_TIMEOUT": "soon"}, ""},
+		{"unknown field", nil, nil, "server:\n  prot: 8080\n"},
+		{"invalid yaml", nil, nil, "server: [\n"},
+		{"invalid value", []string{"--itunes-url", "itunes.apple.com"}, nil, ""},
+		{"missing file", []string{"--config", "/does/not/exist.yaml"}, nil, ""},
+		{"missing file path", []string{"--config"}, nil, ""},
+	}
+
+	for _, c := range cases {
+		s.Run(c.name, func() {
+			s.env = c.env
+			args := c.args
+			if c.file != "" {
+				args = append(args, "--config", s.writeFile(c.file))
+			}
+
+			_, _, err := Load(args, s.getenv)
+
+			s.Error(err)
+		})
+	}
+}
+
+func (s *ConfigSuite) TestValidate() {
+	c := Default()
+	c.Server.Port = 70000
+	c.Http.Timeout = 0
+	c.Cache.Size = -1
+	c.Limiter.TrustedProxies = []string{"proxy"}
+	c.Limiter.Routes["search"] = Route{Every: -time.Second}
+	c.Assets = Assets{Dev: true}
+	c.Cookies.Key = "secret"
+	c.Charts = Charts{Enabled: true, Concurrency: 1}
+	c.Archive.Podcasts = []string{"../811377230"}
+	c.Archive.Regions = []string{"us", "zz"}
+	c.Accounts = Accounts{Enabled: true, Url: "podfinder.example.com"}
+	c.Inbox.MaxBackoff = time.Minute
+	c.Webhooks.Attempts = 0
+
+	err := c.Validate()
+
+	s.ErrorContains(err, "server.port")
+	s.ErrorContains(err, "http.timeout")
+	s.ErrorContains(err, "cache.size")
+	s.ErrorContains(err, "limiter.trusted_proxies")
+	s.ErrorContains(err, "limiter.routes.search.every")
+	s.ErrorContains(err, "assets.dir")
+	s.ErrorContains(err, "cookies.key")
+	s.ErrorContains(err, "charts.every")
+	s.ErrorContains(err, `archive.podcasts: invalid podcast id "../811377230"`)
+	s.ErrorContains(err, `archive.regions: region "zz" has no reviews`)
+	s.ErrorContains(err, `accounts.url must be an absolute http(s) url, got "podfinder.example.com"`)
+	s.ErrorContains(err, "accounts.link_ttl")
+	s.ErrorContains(err, "accounts.session_ttl")
+	s.ErrorContains(err, "inbox.max_backoff can't be shorter than inbox.every")
+	s.ErrorContains(err, "webhooks.attempts")
+	s.NoError(Default().Validate())
+}
+
+func (s *ConfigSuite) TestValidateWithoutAccounts() {
+	c := Default()
+	c.Inbox.Enabled = true
+	c.Webhooks.Enabled = true
+
+	err := c.Validate()
+
+	s.ErrorContains(err, "inbox.enabled needs accounts.enabled")
+	s.ErrorContains(err, "webhooks.enabled needs accounts.enabled")
+	c.Accounts = Accounts{Enabled: true, Url: "https://podfinder.example.com", LinkTTL: time.Minute, SessionTTL: time.Hour}
+	s.NoError(c.Validate())
+}
+
+func (s *ConfigSuite) TestWrite() {
+	c := Default()
+	c.Limiter.TrustedProxies = []string{"10.0.0.0/8"}
+
+	var buf bytes.Buffer
+	s.NoError(c.Write(&buf))
+	path := s.writeFile(buf.String())
+	loaded, _, err := Load([]string{"--config", path}, s.getenv)
+
+	s.NoError(err)
+	s.Contains(buf.String(), "lookup_ttl: 1h0m0s")
+	s.Equal(c, loaded)
+}
+
+func (s *ConfigSuite) TestWriteSecrets() {
+	c := Default()
+	c.Cookies.Key = strings.Repeat("k", 32)
+	c.Accounts.Smtp.Password = "smtp-password"
+
+	var buf bytes.Buffer
+	s.NoError(c.Write(&buf))
+
+	s.NotContains(buf.String(), c.Cookies.Key)
+	s.NotContains(buf.String(), c.Accounts.Smtp.Password)
+	s.Contains(buf.String(), "key: REDACTED")
+	s.Contains(buf.String(), "password: REDACTED")
+	s.Equal("smtp-password", c.Accounts.Smtp.Password)
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// listValue is a comma separated list flag.
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(s string) error {
+	*l = []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// routeValue binds flags to a limiter route, which can't be addressed directly as it is a map value.
+type routeValue struct {
+	routes map[string]Route
+	name   string
+}
+
+func (r routeValue) every() *funcValue {
+	return &funcValue{
+		get: func() string { return r.routes[r.name].Every.String() },
+		set: func(s string) error {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return err
+			}
+			route := r.routes[r.name]
+			route.Every = d
+			r.routes[r.name] = route
+			return nil
+		},
+	}
+}
+
+func (r routeValue) burst() *funcValue {
+	return &funcValue{
+		get: func() string { return strconv.Itoa(r.routes[r.name].Burst) },
+		set: func(s string) error {
+			v, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			route := r.routes[r.name]
+			route.Burst = v
+			r.routes[r.name] = route
+			return nil
+		},
+	}
+}
+
+type funcValue struct {
+	get func() string
+	set func(string) error
+}
+
+func (f *funcValue) String() string {
+	if f == nil || f.get == nil {
+		return ""
+	}
+	return f.get()
+}
+
+func (f *funcValue) Set(s string) error {
+	return f.set(s)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// maxCookieSize leaves room for attributes within the 4096 bytes browsers keep per cookie.
+const maxCookieSize = 3800
+
+var (
+	ErrCookieTooLarge = errors.New("cookie too large")
+
+	idPattern = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// SignedCookies sets and reads cookies signed with HMAC-SHA256, visitors can see their values
+// but a changed value is ignored.
+type SignedCookies struct {
+	key []byte
+}
+
+// NewSignedCookies uses a key of at least 32 bytes, a random one if it is empty,
+// which makes cookies invalid once the process restarts.
+func NewSignedCookies(key []byte) (*SignedCookies, error) {
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+	return &SignedCookies{key: key}, nil
+}
+
+// Set signs the value of a cookie and sets it, cookies browsers would drop are refused with ErrCookieTooLarge.
+func (c *SignedCookies) Set(w http.ResponseWriter, cookie *http.Cookie) error {
+	signed := *cookie
+	signed.Value = cookie.Value + "." + c.sign(cookie.Name, cookie.Value)
+	if len(signed.Name)+len(signed.Value) > maxCookieSize {
+		return ErrCookieTooLarge
+	}
+	http.SetCookie(w, &signed)
+	return nil
+}
+
+// Get returns the value of a cookie, cookies without a valid signature are treated as missing.
+func (c *SignedCookies) Get(r *http.Request, name string) (string, bool) {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return "", false
+	}
+
+	i := strings.LastIndexByte(cookie.Value, '.')
+	if i < 0 {
+		return "", false
+	}
+	value, signature := cookie.Value[:i], cookie.Value[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(c.sign(name, value))) {
+		return "", false
+	}
+	return value, true
+}
+
+// sign covers the name too, so that a value can't be moved to another cookie.
+func (c *SignedCookies) sign(name, value string) string {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(name + "=" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// cookieIds reads podcast ids kept in a signed cookie.
+func (a *App) cookieIds(r *http.Request, name string) []string {
+	v, ok := a.cookies.Get(r, name)
+	if !ok {
+		return nil
+	}
+	return parseIds(strings.Split(v, "."))
+}
+
+// setCookieIds keeps podcast ids in a signed cookie for a year.
+func (a *App) setCookieIds(w http.ResponseWriter, name string, ids []string) error {
+	return a.cookies.Set(w, &http.Cookie{
+		Name:     name,
+		Value:    strings.Join(ids, "."),
+		Path:     "/",
+		MaxAge:   365 * 24 * 60 * 60,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// parseIds keeps unique podcast ids of values.
+func parseIds(values []string) []string {
+	var ids []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); idPattern.MatchString(v) {
+			ids = append(ids, v)
+		}
+	}
+	return dedupe(ids)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+)
+
+// signedCookie builds a cookie the app accepts.
+func (s *AppSuite) signedCookie(name, value string) *http.Cookie {
+	return &http.Cookie{Name: name, Value: value + "." + s.app.cookies.sign(name, value)}
+}
+
+// cookieValue checks the signature of a cookie set by the app and returns its value.
+func (s *AppSuite) cookieValue(cookie *http.Cookie) string {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(cookie)
+	v, ok := s.app.cookies.Get(r, cookie.Name)
+	s.True(ok, cookie.Name)
+	return v
+}
+
+func (s *AppSuite) TestSignedCookies() {
+	cookies, err := NewSignedCookies([]byte("0123456789abcdef0123456789abcdef"))
+	s.NoError(err)
+	w := httptest.NewRecorder()
+	s.NoError(cookies.Set(w, &http.Cookie{Name: "favourites", Value: "811377230.1200361736"}))
+	set := w.Result().Cookies()[0]
+
+	read := func(cookie *http.Cookie) (string, bool) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(cookie)
+		return cookies.Get(r, "favourites")
+	}
+
+	v, ok := read(set)
+	s.True(ok)
+	s.Equal("811377230.1200361736", v)
+
+	for _, value := range []string{
+		strings.Replace(set.Value, "811377230", "811377231", 1),
+		"811377230.1200361736",
+		"811377230",
+		"",
+	} {
+		_, ok := read(&http.Cookie{Name: "favourites", Value: value})
+		s.False(ok, value)
+	}
+
+	// A value signed for another cookie isn't accepted
+	w = httptest.NewRecorder()
+	s.NoError(cookies.Set(w, &http.Cookie{Name: "list", Value: "811377230.1200361736"}))
+	_, ok = read(&http.Cookie{Name: "favourites", Value: w.Result().Cookies()[0].Value})
+	s.False(ok)
+
+	other, err := NewSignedCookies(nil)
+	s.NoError(err)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(set)
+	_, ok = other.Get(r, "favourites")
+	s.False(ok)
+}
+
+func (s *AppSuite) TestSignedCookiesTooLarge() {
+	w := httptest.NewRecorder()
+
+	err := s.app.cookies.Set(w, &http.Cookie{Name: "favourites", Value: strings.Repeat("1", maxCookieSize)})
+
+	s.True(errors.Is(err, ErrCookieTooLarge))
+	s.Empty(w.Result().Cookies())
+}
+
+func (s *AppSuite) TestFavourites() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	req, err := http.NewRequest(http.MethodGet, s.appServer.URL+"/favourites", nil)
+	s.NoError(err)
+	req.AddCookie(s.signedCookie("favourites", "811377230.1200361736"))
+
+	resp, err := http.DefaultClient.Do(req)
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(string(body), `<a href="/podcast/811377230">Hello Internet</a>`)
+	s.Contains(string(body), "1 of your favourites are no longer in the directory.")
+}
+
+func (s *AppSuite) TestFavouritesTampered() {
+	req, err := http.NewRequest(http.MethodGet, s.appServer.URL+"/favourites", nil)
+	s.NoError(err)
+	req.AddCookie(&http.Cookie{Name: "favourites", Value: "811377230"})
+
+	resp, err := http.DefaultClient.Do(req)
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(string(body), "Podcasts you mark as favourites")
+}
+
+func (s *AppSuite) TestFavouritesToggle() {
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	post := func(action string, cookie *http.Cookie) *http.Response {
+		form := url.Values{"action": {action}, "podcast": {"811377230"}}
+		req, err := http.NewRequest(http.MethodPost, s.appServer.URL+"/favourites", strings.NewReader(form.Encode()))
+		s.NoError(err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Referer", s.appServer.URL+"/search?query=hello")
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		resp, err := client.Do(req)
+		s.NoError(err)
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	resp := post("add", s.signedCookie("favourites", "1200361736"))
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	s.Equal("/search?query=hello", resp.Header.Get("Location"))
+	s.Equal("1200361736.811377230", s.cookieValue(resp.Cookies()[0]))
+
+	resp = post("remove", resp.Cookies()[0])
+	s.Equal("1200361736", s.cookieValue(resp.Cookies()[0]))
+}
+
+func (s *AppSuite) TestFavouritesFull() {
+	ids := make([]string, 400)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%d", 1000000000+i)
+	}
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	form := url.Values{"action": {"add"}, "podcast": {"811377230"}}
+	req, err := http.NewRequest(http.MethodPost, s.appServer.URL+"/favourites", strings.NewReader(form.Encode()))
+	s.NoError(err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(s.signedCookie("favourites", strings.Join(ids[:340], ".")))
+
+	resp, err := http.DefaultClient.Do(req)
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+	s.Contains(string(body), "You have too many favourites to remember, remove some to add more.")
+	s.Empty(resp.Cookies())
+}
+
+func (s *AppSuite) TestSearchFavourites() {
+	s.serveCounted("/search", "./testdata/search.json")
+	req, err := http.NewRequest(http.MethodGet, s.appServer.URL+"/search?query=hello", nil)
+	s.NoError(err)
+	req.AddCookie(s.signedCookie("favourites", "811377230"))
+
+	resp, err := http.DefaultClient.Do(req)
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(1, strings.Count(string(body), `title="Remove from favourites"`))
+	s.Equal(4, strings.Count(string(body), `title="Add to favourites"`))
+}
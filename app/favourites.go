@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+)
+
+const favouritesCookie = "favourites"
+
+func (a *App) handleFavourites() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ids := a.cookieIds(r, favouritesCookie)
+		if r.Method == http.MethodGet {
+			a.renderFavourites(w, r, ids, http.StatusOK, "")
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		id := r.Form.Get("podcast")
+		if !idPattern.MatchString(id) {
+			a.renderFavourites(w, r, ids, http.StatusBadRequest, "Pick a podcast to add to your favourites.")
+			return
+		}
+		switch r.Form.Get("action") {
+		case "add":
+			ids = dedupe(append(ids, id))
+		case "remove":
+			ids = slices.DeleteFunc(ids, func(v string) bool { return v == id })
+		}
+
+		err := a.setCookieIds(w, favouritesCookie, ids)
+		if errors.Is(err, ErrCookieTooLarge) {
+			a.renderFavourites(w, r, a.cookieIds(r, favouritesCookie), http.StatusBadRequest, "You have too many favourites to remember, remove some to add more.")
+			return
+		}
+		if err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+		http.Redirect(w, r, backTo(r, "/favourites"), http.StatusSeeOther)
+	}
+}
+
+func (a *App) renderFavourites(w http.ResponseWriter, r *http.Request, ids []string, status int, message string) {
+	type response struct {
+		Podcasts []*itunes.PodcastDetail
+		// Gone counts favourites that are no longer in the directory
+		Gone  int
+		Error string
+	}
+
+	podcasts, err := a.lookupList(ids)
+	if err != nil {
+		log.Println(err)
+		message = "Your favourites couldn't be fetched."
+	}
+	a.renderStatus(w, r, status, response{podcasts, max(len(ids)-len(podcasts), 0), message}, "favourites.html")
+}
+
+// favourites tells which podcasts are favourites of the visitor.
+func (a *App) favourites(r *http.Request) map[string]bool {
+	ids := a.cookieIds(r, favouritesCookie)
+	result := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		result[id] = true
+	}
+	return result
+}
+
+// backTo returns the path of the page a form was sent from, so that visitors stay on it.
+// Other sites are never redirected to.
+func backTo(r *http.Request, fallback string) string {
+	u, err := url.Parse(r.Referer())
+	if err != nil || u.Path == "" || (u.Host != "" && u.Host != r.Host) {
+		return fallback
+	}
+	return (&url.URL{Path: u.Path, RawQuery: u.RawQuery}).String()
+}
@@ -0,0 +1,118 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxFeedSize caps how much of a feed is read, some shows publish feeds of tens of megabytes.
+const maxFeedSize = 20 << 20
+
+// ErrNotModified is returned by FetchIfModified when the feed didn't change since the validators were taken.
+var ErrNotModified = errors.New("feed not modified")
+
+// Validators identify a version of a feed for conditional requests, either may be empty.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+type Client struct {
+	hc HttpClient
+}
+
+func NewClient(hc HttpClient) *Client {
+	return &Client{hc}
+}
+
+func (c *Client) Fetch(url string) (*Feed, error) {
+	resp, err := c.hc.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feed error: %s returned %d", url, resp.StatusCode)
+	}
+
+	return Parse(io.LimitReader(resp.Body, maxFeedSize))
+}
+
+// FetchIfModified fetches a feed unless it is the version the validators were taken from,
+// and returns the validators of the version it fetched.
+func (c *Client) FetchIfModified(ctx context.Context, url string, v Validators) (*Feed, Validators, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, v, err
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return nil, v, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, v, ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, v, fmt.Errorf("feed error: %s returned %d", url, resp.StatusCode)
+	}
+
+	f, err := Parse(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, v, err
+	}
+	return f, Validators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}, nil
+}
+
+type Feed struct {
+	Title       string
+	Author      string
+	Description string
+	Link        string
+	Image       string
+	Episodes    []*Episode
+}
+
+// Episode finds an episode by its guid.
+func (f *Feed) Episode(guid string) (*Episode, bool) {
+	for _, ep := range f.Episodes {
+		if ep.Guid == guid {
+			return ep, true
+		}
+	}
+	return nil, false
+}
+
+type Episode struct {
+	Guid        string
+	Title       string
+	Link        string
+	Description string
+	Content     string
+	PubDate     time.Time
+	Duration    time.Duration
+	Enclosure   Enclosure
+	Season      int
+	Number      int
+	Explicit    bool
+	Image       string
+}
+
+type Enclosure struct {
+	Url    string
+	Type   string
+	Length int64
+}
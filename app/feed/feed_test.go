@@ -0,0 +1,95 @@
+package feed
+
+import (
+	"context"
+	"github.com/stretchr/testify/suite"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+type FeedSuite struct {
+	suite.Suite
+	mux    *http.ServeMux
+	server *httptest.Server
+	client *Client
+}
+
+func TestFeedSuite(t *testing.T) {
+	suite.Run(t, new(FeedSuite))
+}
+
+func (s *FeedSuite) SetupTest() {
+	s.mux = http.NewServeMux()
+	s.server = httptest.NewServer(s.mux)
+	s.client = NewClient(s.server.Client())
+}
+
+func (s *FeedSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *FeedSuite) TestFetch() {
+	s.mux.HandleFunc("/podcast", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "../testdata/feed.xml")
+	})
+
+	f, err := s.client.Fetch(s.server.URL + "/podcast?format=rss")
+
+	s.NoError(err)
+	s.Equal("Hello Internet", f.Title)
+	s.Equal(3, len(f.Episodes))
+}
+
+func (s *FeedSuite) TestFetchError() {
+	s.mux.HandleFunc("/podcast", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+
+	f, err := s.client.Fetch(s.server.URL + "/podcast")
+
+	s.Error(err)
+	s.Nil(f)
+}
+
+func (s *FeedSuite) TestFetchIfModified() {
+	modified := time.Date(2020, 2, 28, 10, 13, 0, 0, time.UTC)
+	s.mux.HandleFunc("/podcast", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		f, err := os.Open("../testdata/feed.xml")
+		s.NoError(err)
+		defer func() { _ = f.Close() }()
+		http.ServeContent(w, r, "feed.xml", modified, f)
+	})
+
+	f, v, err := s.client.FetchIfModified(context.Background(), s.server.URL+"/podcast", Validators{})
+	s.NoError(err)
+	s.Equal(3, len(f.Episodes))
+	s.Equal(Validators{ETag: `"v1"`, LastModified: "Fri, 28 Feb 2020 10:13:00 GMT"}, v)
+
+	f, same, err := s.client.FetchIfModified(context.Background(), s.server.URL+"/podcast", v)
+	s.ErrorIs(err, ErrNotModified)
+	s.Nil(f)
+	s.Equal(v, same)
+
+	// Without an ETag the server compares the modification time
+	_, _, err = s.client.FetchIfModified(context.Background(), s.server.URL+"/podcast", Validators{LastModified: v.LastModified})
+	s.ErrorIs(err, ErrNotModified)
+}
+
+func (s *FeedSuite) TestEpisode() {
+	f := &Feed{Episodes: []*Episode{{Guid: "1", Title: "One"}, {Guid: "2", Title: "Two"}}}
+
+	ep, ok := f.Episode("2")
+	s.True(ok)
+	s.Equal("Two", ep.Title)
+
+	_, ok = f.Episode("3")
+	s.False(ok)
+}
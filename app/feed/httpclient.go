@@ -0,0 +1,8 @@
+package feed
+
+import "net/http"
+
+type HttpClient interface {
+	Get(url string) (resp *http.Response, err error)
+	Do(req *http.Request) (*http.Response, error)
+}
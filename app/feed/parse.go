@@ -0,0 +1,383 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	itunesNamespace  = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+	contentNamespace = "http://purl.org/rss/1.0/modules/content/"
+)
+
+var (
+	ErrNotFeed = errors.New("not an rss feed")
+
+	// autoClose lists HTML void elements found unescaped in descriptions, except for link which
+	// in RSS is a regular element with content
+	autoClose = slices.DeleteFunc(slices.Clone(xml.HTMLAutoClose), func(s string) bool { return s == "link" })
+
+	encodingDecl = regexp.MustCompile(`(?i)^<\?xml[^>]*encoding=["']([^"']+)["']`)
+	dateLayouts  = []string{
+		time.RFC1123Z,
+		time.RFC1123,
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		"Mon, 2 Jan 2006 15:04 -0700",
+		"Mon, 2 Jan 2006 15:04 MST",
+		"2 Jan 2006 15:04:05 -0700",
+		"2 Jan 2006 15:04:05 MST",
+		"Mon, 2 January 2006 15:04:05 -0700",
+		"Mon, 2 January 2006 15:04:05 MST",
+		"Monday, 2 Jan 2006 15:04:05 -0700",
+		"Monday, 2 Jan 2006 15:04:05 MST",
+		time.RFC3339,
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+)
+
+// Parse reads an RSS 2.0 feed with iTunes extensions. It is lenient: unknown entities, undeclared
+// namespace prefixes, unclosed HTML tags, invalid UTF-8 and feeds cut off after the channel has
+// started are all accepted.
+func Parse(r io.Reader) (*Feed, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if m := encodingDecl.FindSubmatch(data); m == nil || isUTF8(string(m[1])) {
+		data = bytes.ToValidUTF8(data, []byte("�"))
+	}
+
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.AutoClose = autoClose
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = charsetReader
+
+	f := &Feed{}
+	var (
+		channel bool
+		ep      *Episode
+	)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if channel && (err == io.EOF || errors.As(err, new(*xml.SyntaxError))) {
+				break
+			}
+			if err == io.EOF {
+				return nil, ErrNotFeed
+			}
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := name(t.Name)
+			switch {
+			case n == "channel":
+				channel = true
+			case !channel:
+			case n == "item":
+				ep = &Episode{}
+			case ep != nil:
+				if err := parseEpisodeElement(d, t, ep); err != nil {
+					return finish(f, ep), nil
+				}
+			default:
+				if err := parseChannelElement(d, t, f); err != nil {
+					return finish(f, ep), nil
+				}
+			}
+		case xml.EndElement:
+			if ep != nil && name(t.Name) == "item" {
+				f.Episodes = appendEpisode(f.Episodes, ep)
+				ep = nil
+			}
+		}
+	}
+
+	if !channel {
+		return nil, ErrNotFeed
+	}
+
+	return finish(f, ep), nil
+}
+
+// finish appends an episode that was still open when the feed ended.
+func finish(f *Feed, ep *Episode) *Feed {
+	if ep != nil {
+		f.Episodes = appendEpisode(f.Episodes, ep)
+	}
+	return f
+}
+
+func appendEpisode(episodes []*Episode, ep *Episode) []*Episode {
+	if ep.Title == "" && ep.Enclosure.Url == "" {
+		return episodes
+	}
+
+	if ep.Guid == "" {
+		ep.Guid = ep.Enclosure.Url
+	}
+	if ep.Guid == "" {
+		ep.Guid = ep.Link
+	}
+	if ep.Guid == "" {
+		ep.Guid = ep.Title
+	}
+
+	return append(episodes, ep)
+}
+
+func parseChannelElement(d *xml.Decoder, start xml.StartElement, f *Feed) error {
+	switch name(start.Name) {
+	case "title":
+		return readText(d, &f.Title)
+	case "link":
+		return readText(d, &f.Link)
+	case "description":
+		return readText(d, &f.Description)
+	case "itunes:summary":
+		return readFallback(d, &f.Description)
+	case "itunes:author":
+		return readText(d, &f.Author)
+	case "itunes:image":
+		f.Image = attr(start, "href")
+		return d.Skip()
+	case "image":
+		return readImage(d, &f.Image)
+	}
+
+	return d.Skip()
+}
+
+func parseEpisodeElement(d *xml.Decoder, start xml.StartElement, ep *Episode) error {
+	var s string
+	switch name(start.Name) {
+	case "title":
+		return readText(d, &ep.Title)
+	case "itunes:title":
+		return readFallback(d, &ep.Title)
+	case "guid":
+		return readText(d, &ep.Guid)
+	case "link":
+		return readText(d, &ep.Link)
+	case "description":
+		return readText(d, &ep.Description)
+	case "itunes:summary":
+		return readFallback(d, &ep.Description)
+	case "content:encoded":
+		return readText(d, &ep.Content)
+	case "pubDate":
+		err := readText(d, &s)
+		ep.PubDate = parseDate(s)
+		return err
+	case "itunes:duration":
+		err := readText(d, &s)
+		ep.Duration = parseDuration(s)
+		return err
+	case "itunes:season":
+		err := readText(d, &s)
+		ep.Season, _ = strconv.Atoi(s)
+		return err
+	case "itunes:episode":
+		err := readText(d, &s)
+		ep.Number, _ = strconv.Atoi(s)
+		return err
+	case "itunes:explicit":
+		err := readText(d, &s)
+		ep.Explicit = parseExplicit(s)
+		return err
+	case "itunes:image":
+		ep.Image = attr(start, "href")
+	case "enclosure":
+		ep.Enclosure.Url = attr(start, "url")
+		ep.Enclosure.Type = attr(start, "type")
+		ep.Enclosure.Length, _ = strconv.ParseInt(attr(start, "length"), 10, 64)
+	}
+
+	return d.Skip()
+}
+
+// readText collects all character data up to the end of the current element, including text of
+// nested elements that lenient parsing turns unescaped HTML into.
+func readText(d *xml.Decoder, dst *string) error {
+	var b strings.Builder
+	depth := 1
+	for depth > 0 {
+		tok, err := d.Token()
+		if err != nil {
+			*dst = strings.TrimSpace(b.String())
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			b.Write(t)
+		}
+	}
+
+	*dst = strings.TrimSpace(b.String())
+	return nil
+}
+
+func readFallback(d *xml.Decoder, dst *string) error {
+	var s string
+	err := readText(d, &s)
+	if *dst == "" {
+		*dst = s
+	}
+	return err
+}
+
+func readImage(d *xml.Decoder, dst *string) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if name(t.Name) != "url" {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			var s string
+			if err := readText(d, &s); err != nil {
+				return err
+			}
+			if *dst == "" {
+				*dst = s
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// name returns an element name with its namespace replaced by a conventional prefix, so that
+// both properly declared and undeclared itunes: and content: elements are recognised.
+func name(n xml.Name) string {
+	switch strings.ToLower(strings.TrimSpace(n.Space)) {
+	case "":
+		return n.Local
+	case itunesNamespace, "itunes":
+		return "itunes:" + n.Local
+	case contentNamespace, "content":
+		return "content:" + n.Local
+	}
+
+	return n.Space + ":" + n.Local
+}
+
+func attr(start xml.StartElement, name string) string {
+	for _, a := range start.Attr {
+		if strings.EqualFold(a.Name.Local, name) {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+func parseDate(s string) time.Time {
+	s = strings.Join(strings.Fields(s), " ")
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseDuration accepts durations given in seconds or as [[HH:]MM:]SS with an optional fraction.
+func parseDuration(s string) time.Duration {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0
+	}
+
+	var seconds float64
+	for _, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || v < 0 {
+			return 0
+		}
+		seconds = seconds*60 + v
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func parseExplicit(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "yes", "true", "explicit":
+		return true
+	}
+	return false
+}
+
+func isUTF8(encoding string) bool {
+	switch strings.ToLower(encoding) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return true
+	}
+	return false
+}
+
+// charsetReader decodes the single byte encodings feeds still occasionally declare. Anything
+// else is passed through and read as UTF-8, which is usually what such feeds actually contain.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	var windows bool
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "iso8859-1", "latin1", "latin-1":
+	case "windows-1252", "cp1252":
+		windows = true
+	default:
+		return input, nil
+	}
+
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	return strings.NewReader(decode(data, windows)), nil
+}
+
+// windows1252 maps bytes 0x80 to 0x9f, which are control characters in ISO-8859-1, to what
+// windows-1252 puts there. The five bytes it leaves undefined keep their ISO-8859-1 meaning.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// decode reads ISO-8859-1, or windows-1252 if windows is set, as UTF-8.
+func decode(data []byte, windows bool) string {
+	b := make([]byte, 0, len(data))
+	for _, c := range data {
+		r := rune(c)
+		if windows && c >= 0x80 && c <= 0x9f {
+			r = windows1252[c-0x80]
+		}
+		b = utf8.AppendRune(b, r)
+	}
+	return string(b)
+}
@@ -0,0 +1,147 @@
+package feed
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+func (s *FeedSuite) TestParse() {
+	fh, err := os.Open("../testdata/feed.xml")
+	s.NoError(err)
+	defer func() { _ = fh.Close() }()
+
+	f, err := Parse(fh)
+
+	s.NoError(err)
+	s.Equal("Hello Internet", f.Title)
+	s.Equal("CGP Grey & Brady Haran", f.Author)
+	s.Equal("http://www.hellointernet.fm/artwork.png", f.Image)
+	s.Equal(3, len(f.Episodes))
+	s.True(time.Date(2020, 2, 28, 10, 13, 0, 0, time.UTC).Equal(f.Episodes[0].PubDate))
+	f.Episodes[0].PubDate = time.Time{}
+	s.Equal(&Episode{
+		Guid:        "52d66949e4b0a8cec3bcdd46:52d67282e4b0cca8969714fa:5e58e0e0e4d6c9133c0e4b6a",
+		Title:       "H.I. #136: Dog Bingo",
+		Link:        "http://www.hellointernet.fm/podcast/136",
+		Description: "Grey and Brady discuss dogs, bingo and the end of the world.",
+		Content:     `<p>Grey and Brady discuss <a href="http://example.com/dogs">dogs</a>, bingo and the end of the world.</p><script>alert(1)</script>`,
+		Duration:    time.Hour + 35*time.Minute + 38*time.Second,
+		Enclosure: Enclosure{
+			Url:    "http://traffic.libsyn.com/hellointernet/136.mp3",
+			Type:   "audio/mpeg",
+			Length: 91812345,
+		},
+		Season:   3,
+		Number:   136,
+		Explicit: false,
+		Image:    "http://www.hellointernet.fm/136.png",
+	}, f.Episodes[0])
+	s.True(f.Episodes[1].Explicit)
+	s.Equal(90*time.Minute, f.Episodes[1].Duration)
+	s.Equal("Grey and Brady discuss boats.", f.Episodes[2].Description)
+	s.Equal("http://www.hellointernet.fm/podcast/134", f.Episodes[2].Guid)
+}
+
+func (s *FeedSuite) TestParseMalformed() {
+	fh, err := os.Open("../testdata/feed_malformed.xml")
+	s.NoError(err)
+	defer func() { _ = fh.Close() }()
+
+	f, err := Parse(fh)
+
+	s.NoError(err)
+	s.Equal("Tom & Jerry Show", f.Title)
+	s.Equal("http://example.com/show.jpg", f.Image)
+	s.Equal(3, len(f.Episodes))
+
+	s.Equal("Episode 2 — Cats & Mice", f.Episodes[0].Title)
+	s.Equal("http://example.com/2.mp3", f.Episodes[0].Guid)
+	s.Equal("2020-02-03 08:00", f.Episodes[0].PubDate.Format("2006-01-02 15:04"))
+	s.Contains(f.Episodes[0].Description, "Unclosed paragraph")
+	s.Equal(45*time.Minute, f.Episodes[0].Duration)
+	s.True(f.Episodes[0].Explicit)
+	s.Equal(int64(0), f.Episodes[0].Enclosure.Length)
+
+	s.Equal("http://example.com/1", f.Episodes[1].Guid)
+	s.True(f.Episodes[1].PubDate.IsZero())
+	s.Equal(time.Duration(0), f.Episodes[1].Duration)
+
+	s.Equal("Episode 0 – Truncated", f.Episodes[2].Title)
+}
+
+func (s *FeedSuite) TestParseLatin1() {
+	f, err := Parse(strings.NewReader("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n" +
+		"<rss><channel><title>Caf\xe9</title><item><title>\xc9pisode</title></item></channel></rss>"))
+
+	s.NoError(err)
+	s.Equal("Café", f.Title)
+	s.Equal("Épisode", f.Episodes[0].Title)
+}
+
+func (s *FeedSuite) TestParseWindows1252() {
+	f, err := Parse(strings.NewReader("<?xml version=\"1.0\" encoding=\"windows-1252\"?>\n" +
+		"<rss><channel><title>Caf\xe9 \x93Talk\x94</title><item><title>Part 1 \x97 Don\x92t stop\x85</title></item></channel></rss>"))
+
+	s.NoError(err)
+	s.Equal("Café “Talk”", f.Title)
+	s.Equal("Part 1 — Don’t stop…", f.Episodes[0].Title)
+}
+
+func (s *FeedSuite) TestParseNotFeed() {
+	cases := []string{
+		"",
+		"<html><body>Not found</body></html>",
+		`{"feed": []}`,
+	}
+
+	for _, c := range cases {
+		s.Run(c, func() {
+			_, err := Parse(strings.NewReader(c))
+			s.Error(err)
+		})
+	}
+}
+
+func (s *FeedSuite) TestParseDuration() {
+	cases := []struct {
+		s string
+		d time.Duration
+	}{
+		{"", 0},
+		{"90", 90 * time.Second},
+		{"05:30", 5*time.Minute + 30*time.Second},
+		{"1:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"1:02:03.5", time.Hour + 2*time.Minute + 3500*time.Millisecond},
+		{"1:2:3:4", 0},
+		{"-10", 0},
+		{"about an hour", 0},
+	}
+
+	for _, c := range cases {
+		s.Run(c.s, func() {
+			s.Equal(c.d, parseDuration(c.s))
+		})
+	}
+}
+
+func (s *FeedSuite) TestParseDate() {
+	cases := []struct {
+		s string
+		t time.Time
+	}{
+		{"Fri, 28 Feb 2020 10:13:00 +0000", time.Date(2020, 2, 28, 10, 13, 0, 0, time.UTC)},
+		{"Fri, 28 Feb 2020 10:13:00 GMT", time.Date(2020, 2, 28, 10, 13, 0, 0, time.UTC)},
+		{"Fri,  28 Feb 2020   10:13 +0000", time.Date(2020, 2, 28, 10, 13, 0, 0, time.UTC)},
+		{"28 Feb 2020 10:13:00 +0000", time.Date(2020, 2, 28, 10, 13, 0, 0, time.UTC)},
+		{"2020-02-28T10:13:00Z", time.Date(2020, 2, 28, 10, 13, 0, 0, time.UTC)},
+		{"2020-02-28", time.Date(2020, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{"yesterday", time.Time{}},
+	}
+
+	for _, c := range cases {
+		s.Run(c.s, func() {
+			s.True(c.t.Equal(parseDate(c.s)), parseDate(c.s))
+		})
+	}
+}
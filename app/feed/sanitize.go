@@ -0,0 +1,146 @@
+package feed
+
+import (
+	"encoding/xml"
+	"html"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// allowedTags maps tags kept in show notes to the attributes kept on them.
+var allowedTags = map[string][]string{
+	"a":          {"href"},
+	"b":          nil,
+	"blockquote": nil,
+	"br":         nil,
+	"code":       nil,
+	"em":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"h5":         nil,
+	"h6":         nil,
+	"hr":         nil,
+	"i":          nil,
+	"li":         nil,
+	"ol":         nil,
+	"p":          nil,
+	"pre":        nil,
+	"strong":     nil,
+	"u":          nil,
+	"ul":         nil,
+}
+
+// droppedTags are removed together with everything inside them.
+var droppedTags = map[string]bool{
+	"head":     true,
+	"iframe":   true,
+	"noscript": true,
+	"object":   true,
+	"script":   true,
+	"style":    true,
+	"svg":      true,
+	"template": true,
+}
+
+var voidTags = map[string]bool{
+	"br": true,
+	"hr": true,
+}
+
+// Sanitize turns show notes into HTML that is safe to render: only basic formatting tags and
+// links to http(s) and mailto urls are kept, everything else is reduced to its text. Notes
+// without any markup keep their line breaks.
+func Sanitize(s string) string {
+	if !strings.Contains(s, "<") {
+		return strings.ReplaceAll(html.EscapeString(strings.TrimSpace(s)), "\n", "<br>")
+	}
+
+	d := xml.NewDecoder(strings.NewReader(s))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+
+	var (
+		b       strings.Builder
+		open    []string
+		dropped int
+	)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			tag := strings.ToLower(t.Name.Local)
+			if dropped > 0 || droppedTags[tag] {
+				dropped++
+				continue
+			}
+			attrs, ok := allowedTags[tag]
+			if !ok {
+				continue
+			}
+			b.WriteString("<" + tag)
+			for _, a := range t.Attr {
+				writeAttr(&b, tag, attrs, a)
+			}
+			if tag == "a" {
+				b.WriteString(` rel="nofollow noopener" target="_blank"`)
+			}
+			b.WriteString(">")
+			if !voidTags[tag] {
+				open = append(open, tag)
+			}
+		case xml.EndElement:
+			tag := strings.ToLower(t.Name.Local)
+			if dropped > 0 {
+				dropped--
+				continue
+			}
+			if len(open) > 0 && open[len(open)-1] == tag {
+				b.WriteString("</" + tag + ">")
+				open = open[:len(open)-1]
+			}
+		case xml.CharData:
+			if dropped == 0 {
+				b.WriteString(html.EscapeString(string(t)))
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func writeAttr(b *strings.Builder, tag string, allowed []string, a xml.Attr) {
+	name := strings.ToLower(a.Name.Local)
+	if a.Name.Space != "" || !slices.Contains(allowed, name) {
+		return
+	}
+
+	value := strings.TrimSpace(a.Value)
+	if tag == "a" && name == "href" && !isSafeUrl(value) {
+		return
+	}
+
+	b.WriteString(" " + name + `="` + html.EscapeString(value) + `"`)
+}
+
+func isSafeUrl(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package feed
+
+func (s *FeedSuite) TestSanitize() {
+	cases := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{"plain text", "First line\nSecond & last", "First line<br>Second &amp; last"},
+		{"formatting", "<p>Hello <b>there</b></p>", "<p>Hello <b>there</b></p>"},
+		{"uppercase", "<P>Hello<BR>there</P>", "<p>Hello<br>there</p>"},
+		{"link", `<a href="http://example.com/" onclick="steal()">link</a>`, `<a href="http://example.com/" rel="nofollow noopener" target="_blank">link</a>`},
+		{"script link", `<a href="javascript:alert(1)">link</a>`, `<a rel="nofollow noopener" target="_blank">link</a>`},
+		{"script", "<p>Hi</p><script>alert(1)</script><style>p {}</style>", "<p>Hi</p>"},
+		{"unknown tags", `<div class="x"><img src="a.png"><span>text</span></div>`, "text"},
+		{"unclosed", "<ul><li>One<li>Two", "<ul><li>One<li>Two</li></li></ul>"},
+		{"entities", "<p>Tom &amp; Jerry&nbsp;&mdash; &lt;3</p>", "<p>Tom &amp; Jerry — &lt;3</p>"},
+		{"event handlers", `<p onmouseover="alert(1)">x</p>`, "<p>x</p>"},
+	}
+
+	for _, c := range cases {
+		s.Run(c.name, func() {
+			s.Equal(c.out, Sanitize(c.in))
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/netip"
+	"sort"
+)
+
+// builder writes MaxMind DB files mapping networks to countries, just enough to test Reader.
+type builder struct {
+	recordSize int
+	ipVersion  int
+	// nodes hold child node indexes, negative values point to countries and zero means empty
+	nodes     [][2]int
+	countries []string
+}
+
+func newBuilder(recordSize, ipVersion int) *builder {
+	return &builder{recordSize: recordSize, ipVersion: ipVersion, nodes: make([][2]int, 1)}
+}
+
+func (b *builder) insert(network, country string) {
+	prefix := netip.MustParsePrefix(network)
+	ip, bits := prefix.Addr().AsSlice(), prefix.Bits()
+	if prefix.Addr().Is4() && b.ipVersion == 6 {
+		ip, bits = append(make([]byte, 12), ip...), bits+96
+	}
+
+	b.countries = append(b.countries, country)
+	node := 0
+	for i := 0; i < bits; i++ {
+		bit := ip[i/8] >> (7 - i%8) & 1
+		if i == bits-1 {
+			b.nodes[node][bit] = -len(b.countries)
+			break
+		}
+		if b.nodes[node][bit] <= 0 {
+			b.nodes = append(b.nodes, [2]int{})
+			b.nodes[node][bit] = len(b.nodes) - 1
+		}
+		node = b.nodes[node][bit]
+	}
+}
+
+func (b *builder) bytes() []byte {
+	var data []byte
+	offsets := make([]int, len(b.countries))
+	for i, c := range b.countries {
+		offsets[i] = len(data)
+		data = append(data, encode(map[string]any{"country": map[string]any{"iso_code": c}})...)
+	}
+
+	var buf bytes.Buffer
+	count := len(b.nodes)
+	for _, n := range b.nodes {
+		var records [2]uint32
+		for i, v := range n {
+			switch {
+			case v > 0:
+				records[i] = uint32(v)
+			case v < 0:
+				records[i] = uint32(count + dataSeparator + offsets[-v-1])
+			default:
+				records[i] = uint32(count)
+			}
+		}
+		buf.Write(encodeNode(b.recordSize, records))
+	}
+
+	buf.Write(make([]byte, dataSeparator))
+	buf.Write(data)
+	buf.Write(metadataStart)
+	buf.Write(encode(map[string]any{
+		"node_count":    uint32(count),
+		"record_size":   uint16(b.recordSize),
+		"ip_version":    uint16(b.ipVersion),
+		"database_type": "Test-Country",
+	}))
+
+	return buf.Bytes()
+}
+
+func encodeNode(recordSize int, r [2]uint32) []byte {
+	switch recordSize {
+	case 24:
+		return []byte{byte(r[0] >> 16), byte(r[0] >> 8), byte(r[0]), byte(r[1] >> 16), byte(r[1] >> 8), byte(r[1])}
+	case 28:
+		return []byte{
+			byte(r[0] >> 16), byte(r[0] >> 8), byte(r[0]),
+			byte(r[0]>>24&0x0f)<<4 | byte(r[1]>>24&0x0f),
+			byte(r[1] >> 16), byte(r[1] >> 8), byte(r[1]),
+		}
+	default:
+		return binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, r[0]), r[1])
+	}
+}
+
+func encode(v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return append(control(typeString, len(v)), v...)
+	case uint16:
+		return append(control(typeUint16, 2), byte(v>>8), byte(v))
+	case uint32:
+		return binary.BigEndian.AppendUint32(control(typeUint32, 4), v)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b := control(typeMap, len(v))
+		for _, k := range keys {
+			b = append(b, encode(k)...)
+			b = append(b, encode(v[k])...)
+		}
+		return b
+	default:
+		panic("unsupported type")
+	}
+}
+
+func control(typ, size int) []byte {
+	if size >= 29 {
+		panic("unsupported size")
+	}
+	if typ > 7 {
+		return []byte{byte(size), byte(typ - 7)}
+	}
+	return []byte{byte(typ<<5 | size)}
+}
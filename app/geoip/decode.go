@@ -0,0 +1,208 @@
+package geoip
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// maxDepth bounds nesting of maps and arrays, so that a broken file can't exhaust the stack.
+const maxDepth = 32
+
+var errTruncated = errors.New("geoip: data is truncated")
+
+// decoder reads values of the MaxMind DB data section. Maps are decoded into map[string]any,
+// arrays into []any, integers into uint64 or int64, floating point numbers into float64.
+type decoder []byte
+
+func (d decoder) decode(offset uint) (any, uint, error) {
+	return d.value(offset, 0)
+}
+
+func (d decoder) value(offset uint, depth int) (any, uint, error) {
+	if depth > maxDepth {
+		return nil, 0, errors.New("geoip: data is nested too deep")
+	}
+
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.value(target, depth+1)
+		return v, next, err
+	}
+
+	if typ == typeMap {
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			var k, v any
+			if k, offset, err = d.value(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, 0, errors.New("geoip: map key is not a string")
+			}
+			if v, offset, err = d.value(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			m[key] = v
+		}
+		return m, offset, nil
+	}
+
+	if typ == typeArray {
+		a := make([]any, 0, min(size, 1024))
+		for i := uint(0); i < size; i++ {
+			var v any
+			if v, offset, err = d.value(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, v)
+		}
+		return a, offset, nil
+	}
+
+	if typ == typeBool {
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d)) {
+		return nil, 0, errTruncated
+	}
+	b, next := d[offset:offset+size], offset+size
+
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes, typeUint128:
+		return []byte(b), next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("geoip: invalid double size %d", size)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("geoip: invalid float size %d", size)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, fmt.Errorf("geoip: invalid integer size %d", size)
+		}
+		var u uint64
+		for _, c := range b {
+			u = u<<8 | uint64(c)
+		}
+		return u, next, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, fmt.Errorf("geoip: invalid integer size %d", size)
+		}
+		var u uint32
+		for _, c := range b {
+			u = u<<8 | uint32(c)
+		}
+		return int64(int32(u)), next, nil
+	default:
+		return nil, 0, fmt.Errorf("geoip: unsupported data type %d", typ)
+	}
+}
+
+// control reads the control byte of a value and returns its type, size and where the payload begins.
+func (d decoder) control(offset uint) (int, uint, uint, error) {
+	if offset >= uint(len(d)) {
+		return 0, 0, 0, errTruncated
+	}
+	c := d[offset]
+	offset++
+
+	typ := int(c >> 5)
+	if typ == typeExtended {
+		if offset >= uint(len(d)) {
+			return 0, 0, 0, errTruncated
+		}
+		typ = int(d[offset]) + 7
+		offset++
+	}
+
+	// Pointers keep their own size encoding in the lower five bits
+	if typ == typePointer {
+		return typ, uint(c & 0x1f), offset, nil
+	}
+
+	size := uint(c & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d)) {
+			return 0, 0, 0, errTruncated
+		}
+		var v uint
+		for _, b := range d[offset : offset+n] {
+			v = v<<8 | uint(b)
+		}
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + v
+		case 2:
+			size = 285 + v
+		default:
+			size = 65821 + v
+		}
+	}
+
+	return typ, size, offset, nil
+}
+
+// pointer resolves a pointer value into an offset within the data section.
+func (d decoder) pointer(bits, offset uint) (uint, uint, error) {
+	n := bits>>3&0x3 + 1
+	if offset+n > uint(len(d)) {
+		return 0, 0, errTruncated
+	}
+
+	v := uint(0)
+	if n < 4 {
+		v = bits & 0x7
+	}
+	for _, b := range d[offset : offset+n] {
+		v = v<<8 | uint(b)
+	}
+
+	switch n {
+	case 2:
+		v += 2048
+	case 3:
+		v += 526336
+	}
+
+	return v, offset + n, nil
+}
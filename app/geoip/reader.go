@@ -0,0 +1,171 @@
+package geoip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// ErrNotFound is returned for addresses the database has no country for.
+var ErrNotFound = errors.New("address not found")
+
+// metadataStart marks the beginning of the metadata section, see: https://maxmind.github.io/MaxMind-DB/
+var metadataStart = []byte("\xab\xcd\xefMaxMind.com")
+
+// dataSeparator is the size of the zero filled gap between the search tree and the data section.
+const dataSeparator = 16
+
+// Reader looks up countries in a MaxMind DB file, such as GeoLite2 Country or City.
+// The whole file is kept in memory, so Reader is safe for concurrent use.
+type Reader struct {
+	tree       []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// ipv4Start is the node IPv4 addresses start from in an IPv6 tree
+	ipv4Start uint
+}
+
+func Open(path string) (*Reader, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(b)
+}
+
+// New reads a database from its contents.
+func New(b []byte) (*Reader, error) {
+	i := bytes.LastIndex(b, metadataStart)
+	if i < 0 {
+		return nil, errors.New("geoip: metadata not found")
+	}
+
+	meta, _, err := decoder(b[i+len(metadataStart):]).decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("geoip: can't read metadata: %w", err)
+	}
+	m, ok := meta.(map[string]any)
+	if !ok {
+		return nil, errors.New("geoip: metadata is not a map")
+	}
+
+	r := &Reader{
+		nodeCount:  uint(toUint(m["node_count"])),
+		recordSize: uint(toUint(m["record_size"])),
+		ipVersion:  uint(toUint(m["ip_version"])),
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("geoip: unsupported record size %d", r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("geoip: unsupported ip version %d", r.ipVersion)
+	}
+
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+dataSeparator > uint(i) {
+		return nil, errors.New("geoip: search tree is larger than the file")
+	}
+	r.tree = b[:treeSize]
+	r.data = b[treeSize+dataSeparator : i]
+
+	if r.ipVersion == 6 {
+		for n := 0; n < 96 && r.ipv4Start < r.nodeCount; n++ {
+			r.ipv4Start = r.record(r.ipv4Start, 0)
+		}
+	}
+
+	return r, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country an address is located in,
+// falling back to the country it is registered in.
+func (r *Reader) Country(addr netip.Addr) (string, error) {
+	record, err := r.lookup(addr.Unmap())
+	if err != nil {
+		return "", err
+	}
+
+	for _, key := range []string{"country", "registered_country"} {
+		country, _ := record[key].(map[string]any)
+		if code, ok := country["iso_code"].(string); ok && code != "" {
+			return strings.ToLower(code), nil
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+func (r *Reader) lookup(addr netip.Addr) (map[string]any, error) {
+	if !addr.IsValid() {
+		return nil, ErrNotFound
+	}
+
+	ip := addr.AsSlice()
+	node := uint(0)
+	if addr.Is4() && r.ipVersion == 6 {
+		node = r.ipv4Start
+	}
+	if addr.Is6() && r.ipVersion == 4 {
+		return nil, ErrNotFound
+	}
+
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		bit := uint(ip[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+	if node <= r.nodeCount {
+		return nil, ErrNotFound
+	}
+
+	offset := node - r.nodeCount - dataSeparator
+	if offset >= uint(len(r.data)) {
+		return nil, errors.New("geoip: invalid data pointer")
+	}
+
+	v, _, err := decoder(r.data).decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return m, nil
+}
+
+// record reads the left (0) or the right (1) record of a node.
+func (r *Reader) record(node, bit uint) uint {
+	b := r.tree[node*r.recordSize/4:]
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+func toUint(v any) uint64 {
+	switch v := v.(type) {
+	case uint64:
+		return v
+	case float64:
+		if v >= 0 && v <= math.MaxUint32 {
+			return uint64(v)
+		}
+	}
+	return 0
+}
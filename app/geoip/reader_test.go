@@ -0,0 +1,122 @@
+package geoip
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type ReaderSuite struct {
+	suite.Suite
+}
+
+func TestReaderSuite(t *testing.T) {
+	suite.Run(t, new(ReaderSuite))
+}
+
+func (s *ReaderSuite) TestCountry() {
+	for _, version := range []int{4, 6} {
+		for _, size := range []int{24, 28, 32} {
+			s.Run(fmt.Sprintf("ipv%d/%d", version, size), func() {
+				b := newBuilder(size, version)
+				b.insert("85.76.0.0/14", "FI")
+				b.insert("81.2.69.0/24", "GB")
+				if version == 6 {
+					b.insert("2a00:1450::/32", "IE")
+				}
+				r, err := New(b.bytes())
+				s.NoError(err)
+
+				cases := map[string]string{
+					"85.76.10.1":       "fi",
+					"85.79.255.255":    "fi",
+					"81.2.69.160":      "gb",
+					"::ffff:81.2.69.1": "gb",
+				}
+				if version == 6 {
+					cases["2a00:1450:4001::1"] = "ie"
+				}
+				for ip, country := range cases {
+					c, err := r.Country(netip.MustParseAddr(ip))
+					s.NoError(err, ip)
+					s.Equal(country, c, ip)
+				}
+
+				for _, ip := range []string{"85.80.0.1", "10.0.0.1", "2a01::1"} {
+					_, err := r.Country(netip.MustParseAddr(ip))
+					s.ErrorIs(err, ErrNotFound, ip)
+				}
+			})
+		}
+	}
+}
+
+func (s *ReaderSuite) TestOpen() {
+	b := newBuilder(24, 6)
+	b.insert("85.76.0.0/14", "FI")
+	path := filepath.Join(s.T().TempDir(), "country.mmdb")
+	s.NoError(os.WriteFile(path, b.bytes(), 0o644))
+
+	r, err := Open(path)
+	s.NoError(err)
+	c, err := r.Country(netip.MustParseAddr("85.76.0.1"))
+	s.NoError(err)
+	s.Equal("fi", c)
+
+	_, err = Open(filepath.Join(s.T().TempDir(), "missing.mmdb"))
+	s.Error(err)
+}
+
+func (s *ReaderSuite) TestNewInvalid() {
+	cases := map[string][]byte{
+		"empty":       nil,
+		"no metadata": []byte("not a database"),
+		"record size": append(append([]byte{}, metadataStart...), encode(map[string]any{
+			"node_count": uint32(0), "record_size": uint16(20), "ip_version": uint16(6),
+		})...),
+		"tree size": append(append([]byte{}, metadataStart...), encode(map[string]any{
+			"node_count": uint32(100), "record_size": uint16(24), "ip_version": uint16(6),
+		})...),
+	}
+
+	for name, b := range cases {
+		s.Run(name, func() {
+			_, err := New(b)
+			s.Error(err)
+		})
+	}
+}
+
+func (s *ReaderSuite) TestDecode() {
+	long := strings.Repeat("a", 300)
+	cases := []struct {
+		name  string
+		data  []byte
+		value any
+	}{
+		{"string", encode("fi"), "fi"},
+		{"long string", append([]byte{typeString<<5 | 30, 0, 15}, long...), long},
+		{"uint16", encode(uint16(24)), uint64(24)},
+		{"int32", []byte{0x04, 0x01, 0xff, 0xff, 0xff, 0xfe}, int64(-2)},
+		{"bool", []byte{0x00, 0x07}, false},
+		{"array", []byte{0x02, 0x04, 0x42, 'f', 'i', 0x42, 'g', 'b'}, []any{"fi", "gb"}},
+		{"pointer", append([]byte{typePointer<<5 | 0, 3, 0}, encode("se")...), "se"},
+	}
+
+	for _, c := range cases {
+		s.Run(c.name, func() {
+			v, _, err := decoder(c.data).decode(0)
+			s.NoError(err)
+			s.Equal(c.value, v)
+		})
+	}
+
+	_, _, err := decoder([]byte{typeString<<5 | 5, 'f'}).decode(0)
+	s.ErrorIs(err, errTruncated)
+	_, _, err = decoder([]byte{typePointer << 5, 0}).decode(0)
+	s.Error(err)
+}
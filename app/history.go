@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"github.com/timiskhakov/podfinder/app/charts"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultHistoryDays = 7
+	maxHistoryDays     = 365
+)
+
+type apiChartEntry struct {
+	Id       string    `json:"id"`
+	Artist   string    `json:"artist"`
+	Name     string    `json:"name"`
+	Image    string    `json:"image"`
+	Ranks    []int     `json:"ranks"`
+	Rank     int       `json:"rank"`
+	Best     int       `json:"best"`
+	Run      int       `json:"run"`
+	RunStart time.Time `json:"runStart"`
+	RunEnd   time.Time `json:"runEnd"`
+}
+
+func (a *App) handleChartHistory() http.HandlerFunc {
+	type response struct {
+		Days    int
+		Periods []int
+		Report  *charts.Report
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		region := r.PathValue("region")
+		if !a.hasHistory(region) {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+
+		days, err := historyDays(r)
+		if err != nil {
+			days = defaultHistoryDays
+		}
+
+		report, err := a.history.Report(region, time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		a.render(w, r, response{days, []int{1, 7, 30, 90}, report}, "history.html")
+	}
+}
+
+func (a *App) handleApiChartHistory() http.HandlerFunc {
+	type response struct {
+		Region         string          `json:"region"`
+		Since          time.Time       `json:"since"`
+		Times          []time.Time     `json:"times"`
+		Podcasts       []apiChartEntry `json:"podcasts"`
+		NewEntries     []apiChartEntry `json:"newEntries"`
+		DropOuts       []apiChartEntry `json:"dropOuts"`
+		LongestRunning []apiChartEntry `json:"longestRunning"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		region := r.PathValue("region")
+		if !a.hasHistory(region) {
+			writeApiError(w, http.StatusNotFound, "chart history not found")
+			return
+		}
+
+		days, err := historyDays(r)
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		since := time.Now().AddDate(0, 0, -days)
+		report, err := a.history.Report(region, since)
+		if err != nil {
+			log.Println(err)
+			writeApiError(w, http.StatusInternalServerError, "can't read chart history")
+			return
+		}
+
+		writeJson(w, http.StatusOK, response{
+			Region:         region,
+			Since:          since,
+			Times:          nonNil(report.Times),
+			Podcasts:       toApiChartEntries(report.Podcasts),
+			NewEntries:     toApiChartEntries(report.NewEntries),
+			DropOuts:       toApiChartEntries(report.DropOuts),
+			LongestRunning: toApiChartEntries(report.LongestRunning),
+		})
+	}
+}
+
+// hasHistory tells whether chart history is collected and the region has a chart.
+func (a *App) hasHistory(region string) bool {
+	reg, ok := itunes.LookupRegion(region)
+	return a.history != nil && ok && reg.HasCharts()
+}
+
+// historyDays reads how many days of history to show.
+func historyDays(r *http.Request) (int, error) {
+	v := r.URL.Query().Get("days")
+	if v == "" {
+		return defaultHistoryDays, nil
+	}
+
+	days, err := strconv.Atoi(v)
+	if err != nil || days < 1 || days > maxHistoryDays {
+		return 0, errors.New("query parameter days must be between 1 and " + strconv.Itoa(maxHistoryDays))
+	}
+	return days, nil
+}
+
+func toApiChartEntries(entries []*charts.Entry) []apiChartEntry {
+	result := make([]apiChartEntry, len(entries))
+	for i, e := range entries {
+		result[i] = apiChartEntry{
+			Id:       e.Id,
+			Artist:   e.Artist,
+			Name:     e.Name,
+			Image:    e.Image,
+			Ranks:    e.Ranks,
+			Rank:     e.Rank,
+			Best:     e.Best,
+			Run:      e.Run,
+			RunStart: e.RunStart,
+			RunEnd:   e.RunEnd,
+		}
+	}
+	return result
+}
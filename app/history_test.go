@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"github.com/timiskhakov/podfinder/app/charts"
+	"io"
+	"net/http"
+	"time"
+)
+
+func (s *AppSuite) collectHistory() {
+	s.serveCounted("/fi/rss/toppodcasts/limit=10/json", "./testdata/top.json")
+	h := charts.NewHistory(&charts.HistoryConfig{})
+	c := charts.NewCollector(&charts.CollectorConfig{Store: s.app.store, History: h, Regions: []string{"fi"}})
+	s.NoError(c.Collect(context.Background()))
+	s.app.history = h
+}
+
+func (s *AppSuite) TestChartHistory() {
+	s.collectHistory()
+
+	resp, err := http.Get(s.appServer.URL + "/charts/fi/history?days=30")
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(string(body), "<b>30 d</b>")
+	s.Contains(string(body), `<a href="/podcast/1612875889">Very Scary People</a>`)
+}
+
+func (s *AppSuite) TestChartHistoryNotFound() {
+	tests := []struct {
+		name    string
+		history ChartHistory
+		path    string
+	}{
+		{"disabled", nil, "/charts/fi/history"},
+		{"no charts", charts.NewHistory(&charts.HistoryConfig{}), "/charts/cn/history"},
+		{"unknown region", charts.NewHistory(&charts.HistoryConfig{}), "/charts/zz/history"},
+	}
+
+	for _, test := range tests {
+		s.Run(test.name, func() {
+			s.app.history = test.history
+
+			resp, err := http.Get(s.appServer.URL + test.path)
+			s.NoError(err)
+			_ = resp.Body.Close()
+
+			s.Equal(http.StatusNotFound, resp.StatusCode)
+		})
+	}
+}
+
+func (s *AppSuite) TestApiChartHistory() {
+	s.collectHistory()
+	var body struct {
+		Region     string          `json:"region"`
+		Since      time.Time       `json:"since"`
+		Times      []time.Time     `json:"times"`
+		Podcasts   []apiChartEntry `json:"podcasts"`
+		NewEntries []apiChartEntry `json:"newEntries"`
+	}
+
+	resp := s.getJson("/api/v1/charts/fi/history", &body)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("fi", body.Region)
+	s.WithinDuration(time.Now().AddDate(0, 0, -7), body.Since, time.Minute)
+	s.Len(body.Times, 1)
+	s.Len(body.Podcasts, 10)
+	s.Equal([]int{1}, body.Podcasts[0].Ranks)
+	s.Equal(1, body.Podcasts[0].Best)
+	s.NotNil(body.NewEntries)
+}
+
+func (s *AppSuite) TestApiChartHistoryErrors() {
+	s.collectHistory()
+	var body struct {
+		Error apiError `json:"error"`
+	}
+
+	resp := s.getJson("/api/v1/charts/fi/history?days=0", &body)
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	resp = s.getJson("/api/v1/charts/zz/history", &body)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
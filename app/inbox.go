@@ -0,0 +1,96 @@
+package main
+
+import (
+	"github.com/timiskhakov/podfinder/app/inbox"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Inbox merges new episodes of podcasts users subscribe to and keeps which ones they played.
+type Inbox interface {
+	Entries(user string, podcasts []string) []*inbox.Entry
+	SetPlayed(user string, keys []string, played bool, at time.Time) error
+}
+
+func (a *App) handleInbox() http.HandlerFunc {
+	type response struct {
+		Entries  []*inbox.Entry
+		Page     Page
+		Prev     string
+		Next     string
+		Unplayed int
+		Show     string
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.inbox == nil {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		user := a.user(r)
+		show := r.Form.Get("show")
+		if show != "unplayed" {
+			show = "all"
+		}
+
+		var entries []*inbox.Entry
+		unplayed := 0
+		for _, e := range a.inbox.Entries(user.Id, user.Subscriptions) {
+			if !e.Played {
+				unplayed++
+			}
+			if show == "all" || !e.Played {
+				entries = append(entries, e)
+			}
+		}
+
+		number, _ := strconv.Atoi(r.Form.Get("page"))
+		entries, page := paginate(entries, number, episodesPerPage)
+
+		a.render(w, r, response{entries, page, pageUrl(r, page.Prev()), pageUrl(r, page.Next()), unplayed, show}, "inbox.html")
+	}
+}
+
+// handleInboxPlayed marks episodes as played or unplayed, or every episode of the inbox as played.
+func (a *App) handleInboxPlayed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.inbox == nil {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		user := a.user(r)
+		keys, played := r.Form["episode"], true
+		switch r.Form.Get("action") {
+		case "unplayed":
+			played = false
+		case "all":
+			keys = nil
+			for _, e := range a.inbox.Entries(user.Id, user.Subscriptions) {
+				if !e.Played {
+					keys = append(keys, e.Key)
+				}
+			}
+		}
+
+		if err := a.inbox.SetPlayed(user.Id, keys, played, time.Now()); err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+		http.Redirect(w, r, backTo(r, "/inbox"), http.StatusSeeOther)
+	}
+}
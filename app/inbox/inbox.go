@@ -0,0 +1,327 @@
+// Package inbox polls feeds of subscribed podcasts and keeps their new episodes with what users played.
+package inbox
+
+import (
+	"errors"
+	"github.com/timiskhakov/podfinder/app/feed"
+	"github.com/timiskhakov/podfinder/app/storage"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidId = errors.New("invalid podcast id")
+
+	idRegexp = regexp.MustCompile(`^[0-9]+$`)
+)
+
+const (
+	// maxEpisodes is how many of the newest episodes are kept per feed
+	maxEpisodes = 100
+	// backfill is how many episodes of a feed polled for the first time make it into the inbox
+	backfill = 3
+)
+
+// Feed is the polling state of a podcast's feed with its recorded episodes, newest first.
+type Feed struct {
+	Podcast      string    `json:"podcast"`
+	Url          string    `json:"url"`
+	Title        string    `json:"title"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	Checked      time.Time `json:"checked"`
+	Next         time.Time `json:"next"`
+	// Failures counts polls that failed in a row, Error is the last one
+	Failures int        `json:"failures,omitempty"`
+	Error    string     `json:"error,omitempty"`
+	Episodes []*Episode `json:"episodes,omitempty"`
+}
+
+// Episode is a recorded episode, Found is when it was first seen.
+type Episode struct {
+	Guid      string        `json:"guid"`
+	Title     string        `json:"title"`
+	Link      string        `json:"link,omitempty"`
+	Published time.Time     `json:"published"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	Found     time.Time     `json:"found"`
+	// Backlog episodes were published long before the feed was first polled, they are kept out of inboxes
+	Backlog bool `json:"backlog,omitempty"`
+}
+
+// Entry is an episode in the inbox of a user.
+type Entry struct {
+	Key     string
+	Podcast string
+	// Show is the title of the podcast's feed
+	Show string
+	*Episode
+	Played bool
+}
+
+type Config struct {
+	// Dir holds played.json and a file per feed in feeds, the inbox is kept in memory only if it is empty.
+	Dir string
+}
+
+// Inbox keeps episodes of polled feeds and which of them each user played.
+type Inbox struct {
+	dir string
+	mu  sync.Mutex
+	// feeds maps podcasts to files of their feeds, every feed on disk is opened up front
+	feeds map[string]*storage.File[Feed]
+	// played maps users to keys of episodes they played
+	played *storage.File[map[string]map[string]time.Time]
+}
+
+func Open(config *Config) (*Inbox, error) {
+	i := &Inbox{feeds: make(map[string]*storage.File[Feed])}
+	played := ""
+	if config.Dir != "" {
+		i.dir = filepath.Join(config.Dir, "feeds")
+		played = filepath.Join(config.Dir, "played.json")
+	}
+
+	var err error
+	if i.played, err = storage.Open[map[string]map[string]time.Time](played); err != nil {
+		return nil, err
+	}
+
+	if i.dir == "" {
+		return i, nil
+	}
+	entries, err := os.ReadDir(i.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return i, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		podcast, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || !idRegexp.MatchString(podcast) {
+			continue
+		}
+		if _, err := i.file(podcast, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return i, nil
+}
+
+// file returns the file of a podcast's feed, creating one if create is set, or nil.
+func (i *Inbox) file(podcast string, create bool) (*storage.File[Feed], error) {
+	if !idRegexp.MatchString(podcast) {
+		return nil, ErrInvalidId
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if f, ok := i.feeds[podcast]; ok || !create {
+		return f, nil
+	}
+
+	f, err := storage.Open[Feed](i.path(podcast))
+	if err != nil {
+		return nil, err
+	}
+	i.feeds[podcast] = f
+
+	return f, nil
+}
+
+func (i *Inbox) path(podcast string) string {
+	if i.dir == "" {
+		return ""
+	}
+	return filepath.Join(i.dir, podcast+".json")
+}
+
+// files returns files of every recorded feed by podcast.
+func (i *Inbox) files() map[string]*storage.File[Feed] {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return maps.Clone(i.feeds)
+}
+
+// Feed returns the state of a podcast's feed, false if it was never polled.
+func (i *Inbox) Feed(podcast string) (Feed, bool) {
+	file, _ := i.file(podcast, false)
+	if file == nil {
+		return Feed{}, false
+	}
+
+	var f Feed
+	file.View(func(stored *Feed) {
+		f = *stored
+		f.Episodes = append([]*Episode(nil), stored.Episodes...)
+	})
+	return f, true
+}
+
+// Entries merges episodes of podcasts into the inbox of a user, newest first.
+func (i *Inbox) Entries(user string, podcasts []string) []*Entry {
+	var entries []*Entry
+	for _, id := range podcasts {
+		file, _ := i.file(id, false)
+		if file == nil {
+			continue
+		}
+		file.View(func(f *Feed) {
+			for _, ep := range f.Episodes {
+				if ep.Backlog {
+					continue
+				}
+				e := *ep
+				entries = append(entries, &Entry{Key: key(id, ep.Guid), Podcast: id, Show: f.Title, Episode: &e})
+			}
+		})
+	}
+	i.played.View(func(played *map[string]map[string]time.Time) {
+		for _, e := range entries {
+			_, e.Played = (*played)[user][e.Key]
+		}
+	})
+
+	sort.SliceStable(entries, func(a, b int) bool {
+		return entries[a].Published.After(entries[b].Published)
+	})
+	return entries
+}
+
+// SetPlayed marks episodes of the inbox of a user as played or unplayed, by their entry keys.
+// Keys of episodes that are no longer recorded are forgotten.
+func (i *Inbox) SetPlayed(user string, keys []string, played bool, at time.Time) error {
+	known := make(map[string]bool)
+	for id, file := range i.files() {
+		file.View(func(f *Feed) {
+			for _, ep := range f.Episodes {
+				known[key(id, ep.Guid)] = true
+			}
+		})
+	}
+
+	return i.played.Update(func(p *map[string]map[string]time.Time) error {
+		if *p == nil {
+			*p = make(map[string]map[string]time.Time)
+		}
+		marks := (*p)[user]
+		if marks == nil {
+			marks = make(map[string]time.Time)
+		}
+		for _, k := range keys {
+			if played && known[k] {
+				marks[k] = at
+			} else {
+				delete(marks, k)
+			}
+		}
+		for k := range marks {
+			if !known[k] {
+				delete(marks, k)
+			}
+		}
+
+		if len(marks) == 0 {
+			delete(*p, user)
+		} else {
+			(*p)[user] = marks
+		}
+		return nil
+	})
+}
+
+// update changes the state of a feed, creating it if it was never polled. Each feed is saved to a file
+// of its own, so that polling one doesn't rewrite the others.
+func (i *Inbox) update(podcast string, fn func(f *Feed)) error {
+	file, err := i.file(podcast, true)
+	if err != nil {
+		return err
+	}
+
+	return file.Update(func(f *Feed) error {
+		f.Podcast = podcast
+		fn(f)
+		return nil
+	})
+}
+
+// retain forgets feeds of podcasts nobody subscribes to anymore.
+func (i *Inbox) retain(podcasts []string) error {
+	keep := make(map[string]bool, len(podcasts))
+	for _, id := range podcasts {
+		keep[id] = true
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var errs []error
+	for id := range i.feeds {
+		if keep[id] {
+			continue
+		}
+		if path := i.path(id); path != "" {
+			if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		delete(i.feeds, id)
+	}
+	return errors.Join(errs...)
+}
+
+// record adds episodes of a fetched feed that weren't seen before and returns how many there were.
+// All but the newest few episodes of a feed that is recorded for the first time go to the backlog.
+func (f *Feed) record(fd *feed.Feed, now time.Time) int {
+	episodes := append([]*feed.Episode(nil), fd.Episodes...)
+	sort.SliceStable(episodes, func(a, b int) bool { return episodes[a].PubDate.After(episodes[b].PubDate) })
+	first := f.Checked.IsZero() && len(f.Episodes) == 0
+
+	seen := make(map[string]bool, len(f.Episodes))
+	for _, ep := range f.Episodes {
+		seen[ep.Guid] = true
+	}
+	for i, ep := range episodes {
+		if ep.Guid == "" || seen[ep.Guid] {
+			continue
+		}
+		seen[ep.Guid] = true
+		f.Episodes = append(f.Episodes, &Episode{
+			Guid:      ep.Guid,
+			Title:     ep.Title,
+			Link:      ep.Link,
+			Published: ep.PubDate,
+			Duration:  ep.Duration,
+			Found:     now,
+			Backlog:   first && i >= backfill,
+		})
+	}
+
+	sort.SliceStable(f.Episodes, func(a, b int) bool { return f.Episodes[a].Published.After(f.Episodes[b].Published) })
+	f.Episodes = f.Episodes[:min(len(f.Episodes), maxEpisodes)]
+	f.Title = fd.Title
+
+	// Old episodes past the limit are found again on every poll, only the ones that are kept count
+	added := 0
+	for _, ep := range f.Episodes {
+		if ep.Found.Equal(now) && !ep.Backlog {
+			added++
+		}
+	}
+	return added
+}
+
+func key(podcast, guid string) string {
+	return podcast + "/" + guid
+}
@@ -0,0 +1,218 @@
+package inbox
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/suite"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type InboxSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	mu       sync.Mutex
+	episodes map[string][]string
+	status   map[string]int
+	requests map[string][]*http.Request
+	dir      string
+	inbox    *Inbox
+	clock    *fixedClock
+	store    *stubStore
+	subs     subscriptions
+}
+
+func TestInboxSuite(t *testing.T) {
+	suite.Run(t, new(InboxSuite))
+}
+
+type fixedClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fixedClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (c *fixedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type stubStore struct {
+	url     string
+	lookups atomic.Int32
+}
+
+func (s *stubStore) Lookup(id string) (*itunes.PodcastDetail, error) {
+	s.lookups.Add(1)
+	return &itunes.PodcastDetail{Id: id, FeedUrl: s.url + "/feed/" + id}, nil
+}
+
+type subscriptions []string
+
+func (s subscriptions) Subscribed() []string { return s }
+
+func (s *InboxSuite) SetupTest() {
+	s.episodes = map[string][]string{}
+	s.status = map[string]int{}
+	s.requests = map[string][]*http.Request{}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveFeed))
+	s.store = &stubStore{url: s.server.URL}
+	s.clock = &fixedClock{now: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	s.subs = subscriptions{"811377230"}
+
+	s.dir = filepath.Join(s.T().TempDir(), "inbox")
+	inbox, err := Open(&Config{Dir: s.dir})
+	s.NoError(err)
+	s.inbox = inbox
+}
+
+func (s *InboxSuite) TearDownTest() {
+	s.server.Close()
+}
+
+// serveFeed serves a feed of the episodes of a podcast, tagged with an ETag of their count.
+func (s *InboxSuite) serveFeed(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := strings.TrimPrefix(r.URL.Path, "/feed/")
+	s.requests[id] = append(s.requests[id], r)
+	if status := s.status[id]; status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	etag := fmt.Sprintf(`"%d"`, len(s.episodes[id]))
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var items strings.Builder
+	for i, title := range s.episodes[id] {
+		// Episodes of podcasts with longer ids come out later in the day, so that inboxes have a single order
+		date := time.Date(2024, 1, 1+i, len(id), 0, 0, 0, time.UTC).Format(time.RFC1123Z)
+		_, _ = fmt.Fprintf(&items, "<item><guid>%s-%d</guid><title>%s</title><pubDate>%s</pubDate></item>", id, i, title, date)
+	}
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Show %s</title>%s</channel></rss>`, id, items.String())
+}
+
+func (s *InboxSuite) publish(id string, titles ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.episodes[id] = append(s.episodes[id], titles...)
+}
+
+func (s *InboxSuite) fail(id string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status[id] = status
+}
+
+func (s *InboxSuite) lastRequest(id string) *http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[id][len(s.requests[id])-1]
+}
+
+func (s *InboxSuite) requestCount(id string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests[id])
+}
+
+func (s *InboxSuite) titles(entries []*Entry) []string {
+	var result []string
+	for _, e := range entries {
+		result = append(result, e.Title)
+	}
+	return result
+}
+
+func (s *InboxSuite) TestEntries() {
+	poller := s.poller()
+	s.subs = append(s.subs, "1200361736")
+	poller.subscriptions = s.subs
+	s.publish("811377230", "One", "Two")
+	s.publish("1200361736", "First", "Second", "Third")
+	s.NoError(poller.Poll(context.Background()))
+
+	entries := s.inbox.Entries("user", []string{"811377230", "1200361736"})
+
+	s.Equal([]string{"Third", "Second", "Two", "First", "One"}, s.titles(entries))
+	s.Equal("Show 1200361736", entries[0].Show)
+	s.Equal("1200361736/1200361736-2", entries[0].Key)
+	s.Equal([]string{"Two", "One"}, s.titles(s.inbox.Entries("user", []string{"811377230"})))
+}
+
+func (s *InboxSuite) TestSetPlayed() {
+	s.publish("811377230", "One", "Two")
+	s.NoError(s.poller().Poll(context.Background()))
+	at := s.clock.Now()
+
+	s.NoError(s.inbox.SetPlayed("grey", []string{"811377230/811377230-0", "811377230/gone"}, true, at))
+
+	entries := s.inbox.Entries("grey", s.subs)
+	s.False(entries[0].Played)
+	s.True(entries[1].Played)
+	s.False(s.inbox.Entries("brady", s.subs)[1].Played)
+	var played map[string]map[string]time.Time
+	s.inbox.played.View(func(p *map[string]map[string]time.Time) { played = *p })
+	s.Equal(map[string]map[string]time.Time{"grey": {"811377230/811377230-0": at}}, played)
+
+	s.NoError(s.inbox.SetPlayed("grey", []string{"811377230/811377230-0"}, false, at))
+	s.False(s.inbox.Entries("grey", s.subs)[1].Played)
+}
+
+func (s *InboxSuite) TestReopen() {
+	s.publish("811377230", "One")
+	s.NoError(s.poller().Poll(context.Background()))
+	s.NoError(s.inbox.SetPlayed("grey", []string{"811377230/811377230-0"}, true, s.clock.Now()))
+
+	reopened, err := Open(&Config{Dir: s.dir})
+	s.NoError(err)
+
+	entries := reopened.Entries("grey", s.subs)
+	s.Len(entries, 1)
+	s.True(entries[0].Played)
+}
+
+func (s *InboxSuite) TestFeedFiles() {
+	poller := s.poller()
+	poller.subscriptions = subscriptions{"811377230", "1200361736"}
+	s.publish("811377230", "One")
+	s.publish("1200361736", "First")
+	s.NoError(poller.Poll(context.Background()))
+
+	s.FileExists(filepath.Join(s.dir, "feeds", "811377230.json"))
+	s.FileExists(filepath.Join(s.dir, "feeds", "1200361736.json"))
+
+	poller.subscriptions = subscriptions{"1200361736"}
+	s.NoError(poller.Poll(context.Background()))
+
+	s.NoFileExists(filepath.Join(s.dir, "feeds", "811377230.json"))
+	reopened, err := Open(&Config{Dir: s.dir})
+	s.NoError(err)
+	_, ok := reopened.Feed("811377230")
+	s.False(ok)
+	f, ok := reopened.Feed("1200361736")
+	s.True(ok)
+	s.Equal("1200361736", f.Podcast)
+	s.Equal([]string{"First"}, s.titles(reopened.Entries("grey", []string{"1200361736"})))
+}
@@ -0,0 +1,207 @@
+package inbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/feed"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"golang.org/x/sync/errgroup"
+	"log"
+	"sync"
+	"time"
+)
+
+const defaultTick = time.Minute
+
+// Store finds feed urls of podcasts.
+type Store interface {
+	Lookup(id string) (*itunes.PodcastDetail, error)
+}
+
+// Feeds fetches feeds with conditional requests.
+type Feeds interface {
+	FetchIfModified(ctx context.Context, url string, v feed.Validators) (*feed.Feed, feed.Validators, error)
+}
+
+// Subscriptions tells which podcasts users subscribe to, it may change between polls.
+type Subscriptions interface {
+	Subscribed() []string
+}
+
+// Clock lets tests control when feeds are polled.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type PollerConfig struct {
+	Store         Store
+	Feeds         Feeds
+	Inbox         *Inbox
+	Subscriptions Subscriptions
+	// Every is how often a feed is polled
+	Every time.Duration
+	// MaxBackoff caps how long polling a failing feed is put off, the delay doubles on every failure
+	MaxBackoff time.Duration
+	// Concurrency limits how many feeds are polled at once, one by default
+	Concurrency int
+	// Tick is how often due feeds are looked for, a minute by default
+	Tick  time.Duration
+	Clock Clock
+}
+
+// Poller polls feeds of subscribed podcasts and records their new episodes in the Inbox.
+type Poller struct {
+	store         Store
+	feeds         Feeds
+	inbox         *Inbox
+	subscriptions Subscriptions
+	every         time.Duration
+	maxBackoff    time.Duration
+	concurrency   int
+	tick          time.Duration
+	clock         Clock
+}
+
+func NewPoller(config *PollerConfig) *Poller {
+	p := &Poller{
+		store:         config.Store,
+		feeds:         config.Feeds,
+		inbox:         config.Inbox,
+		subscriptions: config.Subscriptions,
+		every:         config.Every,
+		maxBackoff:    config.MaxBackoff,
+		concurrency:   config.Concurrency,
+		tick:          config.Tick,
+		clock:         config.Clock,
+	}
+	if p.maxBackoff < p.every {
+		p.maxBackoff = p.every
+	}
+	if p.concurrency <= 0 {
+		p.concurrency = 1
+	}
+	if p.tick <= 0 {
+		p.tick = defaultTick
+	}
+	if p.clock == nil {
+		p.clock = realClock{}
+	}
+
+	return p
+}
+
+// Run polls due feeds every tick until the context is done, polls in flight are cancelled and waited for.
+func (p *Poller) Run(ctx context.Context) error {
+	for {
+		if err := p.Poll(ctx); err != nil {
+			log.Printf("%v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-p.clock.After(p.tick):
+		}
+	}
+}
+
+// Poll polls every subscribed feed that is due, one failing doesn't stop the others.
+func (p *Poller) Poll(ctx context.Context) error {
+	podcasts := p.subscriptions.Subscribed()
+	if err := p.inbox.retain(podcasts); err != nil {
+		return err
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	g := errgroup.Group{}
+	g.SetLimit(p.concurrency)
+	for _, id := range podcasts {
+		if ctx.Err() != nil {
+			break
+		}
+		state, _ := p.inbox.Feed(id)
+		if state.Next.After(p.clock.Now()) {
+			continue
+		}
+
+		g.Go(func() error {
+			if err := p.poll(ctx, id, state); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("can't poll feed of %s: %w", id, err))
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (p *Poller) poll(ctx context.Context, id string, state Feed) error {
+	url := state.Url
+	// Feeds move, so the url is looked up again once polling it fails
+	if url == "" || state.Failures > 0 {
+		pod, err := p.store.Lookup(id)
+		if err != nil {
+			return p.fail(id, err)
+		}
+		url = pod.FeedUrl
+	}
+	if url != state.Url {
+		state.ETag, state.LastModified = "", ""
+	}
+
+	fd, v, err := p.feeds.FetchIfModified(ctx, url, feed.Validators{ETag: state.ETag, LastModified: state.LastModified})
+	if ctx.Err() != nil {
+		// Shutting down isn't the feed's fault
+		return nil
+	}
+	if err != nil && !errors.Is(err, feed.ErrNotModified) {
+		return p.fail(id, err)
+	}
+
+	now := p.clock.Now()
+	added := 0
+	updateErr := p.inbox.update(id, func(f *Feed) {
+		f.Url, f.ETag, f.LastModified = url, v.ETag, v.LastModified
+		if fd != nil {
+			added = f.record(fd, now)
+		}
+		f.Checked, f.Next = now, now.Add(p.every)
+		f.Failures, f.Error = 0, ""
+	})
+	if added > 0 {
+		log.Printf("%d new episodes of %s", added, id)
+	}
+	return updateErr
+}
+
+// fail puts off polling a feed, longer after every failure in a row.
+func (p *Poller) fail(id string, err error) error {
+	now := p.clock.Now()
+	updateErr := p.inbox.update(id, func(f *Feed) {
+		f.Failures++
+		f.Error = err.Error()
+		f.Next = now.Add(p.backoff(f.Failures))
+	})
+	return errors.Join(err, updateErr)
+}
+
+func (p *Poller) backoff(failures int) time.Duration {
+	d := p.every
+	for i := 0; i < failures && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.maxBackoff)
+}
@@ -0,0 +1,126 @@
+package inbox
+
+import (
+	"context"
+	"github.com/timiskhakov/podfinder/app/feed"
+	"net/http"
+	"time"
+)
+
+func (s *InboxSuite) poller() *Poller {
+	return NewPoller(&PollerConfig{
+		Store:         s.store,
+		Feeds:         feed.NewClient(s.server.Client()),
+		Inbox:         s.inbox,
+		Subscriptions: s.subs,
+		Every:         time.Hour,
+		MaxBackoff:    6 * time.Hour,
+		Concurrency:   2,
+		Clock:         s.clock,
+	})
+}
+
+func (s *InboxSuite) TestPoll() {
+	p := s.poller()
+	s.publish("811377230", "One", "Two", "Three", "Four", "Five")
+
+	s.NoError(p.Poll(context.Background()))
+
+	// A new subscription starts with the latest few episodes
+	f, ok := s.inbox.Feed("811377230")
+	s.True(ok)
+	s.Equal([]string{"Five", "Four", "Three"}, s.titles(s.inbox.Entries("grey", s.subs)))
+	s.Equal(s.server.URL+"/feed/811377230", f.Url)
+	s.Equal("Show 811377230", f.Title)
+	s.Equal(`"5"`, f.ETag)
+	s.Equal(s.clock.Now().Add(time.Hour), f.Next)
+
+	// Feeds aren't polled before they are due
+	s.NoError(p.Poll(context.Background()))
+	s.Equal(1, s.requestCount("811377230"))
+
+	s.clock.Advance(time.Hour)
+	s.publish("811377230", "Six")
+	s.NoError(p.Poll(context.Background()))
+
+	entries := s.inbox.Entries("grey", s.subs)
+	s.Equal([]string{"Six", "Five", "Four", "Three"}, s.titles(entries))
+	s.Equal(s.clock.Now(), entries[0].Found)
+	s.Equal(`"5"`, s.lastRequest("811377230").Header.Get("If-None-Match"))
+	s.Equal(int32(1), s.store.lookups.Load())
+}
+
+func (s *InboxSuite) TestPollNotModified() {
+	p := s.poller()
+	s.publish("811377230", "One")
+	s.NoError(p.Poll(context.Background()))
+	s.clock.Advance(time.Hour)
+
+	s.NoError(p.Poll(context.Background()))
+
+	f, _ := s.inbox.Feed("811377230")
+	s.Equal(2, s.requestCount("811377230"))
+	s.Equal(`"1"`, s.lastRequest("811377230").Header.Get("If-None-Match"))
+	s.Equal(`"1"`, f.ETag)
+	s.Equal(s.clock.Now(), f.Checked)
+	s.Equal(s.clock.Now().Add(time.Hour), f.Next)
+	s.Len(f.Episodes, 1)
+}
+
+func (s *InboxSuite) TestPollBackoff() {
+	p := s.poller()
+	s.publish("811377230", "One")
+	s.fail("811377230", http.StatusServiceUnavailable)
+
+	var delays []time.Duration
+	for i := 0; i < 4; i++ {
+		err := p.Poll(context.Background())
+
+		s.ErrorContains(err, "can't poll feed of 811377230: feed error")
+		f, _ := s.inbox.Feed("811377230")
+		s.Equal(i+1, f.Failures)
+		delays = append(delays, f.Next.Sub(s.clock.Now()))
+		s.clock.Advance(f.Next.Sub(s.clock.Now()))
+	}
+	s.Equal([]time.Duration{2 * time.Hour, 4 * time.Hour, 6 * time.Hour, 6 * time.Hour}, delays)
+
+	s.fail("811377230", 0)
+	s.NoError(p.Poll(context.Background()))
+
+	f, _ := s.inbox.Feed("811377230")
+	s.Zero(f.Failures)
+	s.Empty(f.Error)
+	s.Len(f.Episodes, 1)
+	// The url is looked up again after failures
+	s.Equal(int32(5), s.store.lookups.Load())
+}
+
+func (s *InboxSuite) TestPollForgetsUnsubscribed() {
+	p := s.poller()
+	s.publish("811377230", "One")
+	s.NoError(p.Poll(context.Background()))
+
+	p.subscriptions = subscriptions{}
+	s.NoError(p.Poll(context.Background()))
+
+	_, ok := s.inbox.Feed("811377230")
+	s.False(ok)
+}
+
+func (s *InboxSuite) TestRunStops() {
+	p := s.poller()
+	s.publish("811377230", "One")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() { done <- p.Run(ctx) }()
+	s.Eventually(func() bool { return s.requestCount("811377230") == 1 }, time.Second, 10*time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		s.NoError(err)
+	case <-time.After(time.Second):
+		s.Fail("poller didn't stop")
+	}
+}
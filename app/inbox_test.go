@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"github.com/timiskhakov/podfinder/app/accounts"
+	"github.com/timiskhakov/podfinder/app/feed"
+	"github.com/timiskhakov/podfinder/app/inbox"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// enableInbox polls the feed stand-in for podcasts signed in users subscribe to.
+func (s *AppSuite) enableInbox() *inbox.Poller {
+	in, err := inbox.Open(&inbox.Config{})
+	s.NoError(err)
+	itunesUrl, err := url.Parse(s.itunesServer.URL)
+	s.NoError(err)
+
+	s.app.inbox = in
+	return inbox.NewPoller(&inbox.PollerConfig{
+		Store:         s.app.store,
+		Feeds:         feed.NewClient(&http.Client{Transport: &standInTransport{itunesUrl, s.itunesServer.Client().Transport}}),
+		Inbox:         in,
+		Subscriptions: s.app.accounts.(*accounts.Accounts),
+	})
+}
+
+func (s *AppSuite) TestInbox() {
+	server := s.enableAccounts()
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	s.serveCounted("/podcast", "./testdata/feed.xml")
+	session := s.signIn(server, "grey@example.com")
+	resp, _ := s.do(http.MethodPost, "/account/podcasts", url.Values{"action": {"subscribe"}, "podcast": {"811377230"}}, session)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	s.NoError(s.enableInbox().Poll(context.Background()))
+
+	resp, body := s.do(http.MethodGet, "/inbox", nil, session)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(body, "3 unplayed episodes of your subscriptions")
+	s.Less(strings.Index(body, "H.I. #136: Dog Bingo"), strings.Index(body, "H.I. #134: Boaty McBoatface"))
+	s.Contains(body, `<a class="header" href="/podcast/811377230/episode/http:%2F%2Fwww.hellointernet.fm%2Fpodcast%2F134">H.I. #134: Boaty McBoatface</a>`)
+
+	resp, _ = s.do(http.MethodPost, "/inbox", url.Values{"action": {"played"}, "episode": {"811377230/http://www.hellointernet.fm/podcast/134"}}, session)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	s.Equal("/inbox", resp.Header.Get("Location"))
+	_, body = s.do(http.MethodGet, "/inbox?show=unplayed", nil, session)
+	s.Contains(body, "2 unplayed episodes of your subscriptions")
+	s.Contains(body, "H.I. #136: Dog Bingo")
+	s.NotContains(body, "H.I. #134: Boaty McBoatface")
+
+	resp, _ = s.do(http.MethodPost, "/inbox", url.Values{"action": {"all"}}, session)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	_, body = s.do(http.MethodGet, "/inbox", nil, session)
+	s.Contains(body, "0 unplayed episodes of your subscriptions")
+	s.Equal(3, strings.Count(body, "Mark unplayed"))
+}
+
+func (s *AppSuite) TestInboxDisabled() {
+	server := s.enableAccounts()
+	session := s.signIn(server, "grey@example.com")
+
+	resp, _ := s.do(http.MethodGet, "/inbox", nil, session)
+
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,85 @@
+package itunes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+func (s *Store) SearchEpisodes(region, query string) ([]*Episode, error) {
+	return share(&s.flight, "episodes:"+region+":"+query, func() ([]*Episode, error) {
+		return s.searchEpisodes(region, query)
+	})
+}
+
+func (s *Store) searchEpisodes(region, query string) ([]*Episode, error) {
+	if !isSupportedRegion(region) {
+		region = DefaultRegion
+	}
+
+	resp, err := s.hc.Get(fmt.Sprintf("%s/search?media=podcast&entity=podcastEpisode&country=%s&term=%s&limit=%d", s.url, region, url.QueryEscape(query), MaxSearchLimit))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		bytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("itunes search api error: %s", string(bytes))
+	}
+
+	r := episodesResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, err
+	}
+
+	episodes := make([]*Episode, 0, len(r.Results))
+	for _, r := range r.Results {
+		if r.Kind != "podcast-episode" {
+			continue
+		}
+		audio := r.EpisodeUrl
+		if audio == "" {
+			audio = r.PreviewUrl
+		}
+		episodes = append(episodes, &Episode{
+			Id:       strconv.Itoa(r.Id),
+			Title:    r.Title,
+			ShowId:   strconv.Itoa(r.ShowId),
+			Show:     r.Show,
+			Image:    r.Image,
+			Released: r.Released,
+			Duration: time.Duration(r.Millis) * time.Millisecond,
+			Url:      r.Url,
+			AudioUrl: audio,
+		})
+	}
+
+	return episodes, nil
+}
+
+type episodesResponse struct {
+	Count   int             `json:"resultCount"`
+	Results []episodeResult `json:"results"`
+}
+
+type episodeResult struct {
+	Kind       string    `json:"kind"`
+	Id         int       `json:"trackId"`
+	Title      string    `json:"trackName"`
+	ShowId     int       `json:"collectionId"`
+	Show       string    `json:"collectionName"`
+	Image      string    `json:"artworkUrl600"`
+	Released   time.Time `json:"releaseDate"`
+	Millis     int64     `json:"trackTimeMillis"`
+	Url        string    `json:"trackViewUrl"`
+	EpisodeUrl string    `json:"episodeUrl"`
+	PreviewUrl string    `json:"previewUrl"`
+}
@@ -0,0 +1,53 @@
+package itunes
+
+import (
+	"github.com/golang/mock/gomock"
+	"github.com/timiskhakov/podfinder/app/itunes/mock"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+func (s *StoreSuite) TestSearchEpisodes() {
+	fh, err := os.Open("../testdata/episodes.json")
+	s.NoError(err)
+	defer func() { _ = fh.Close() }()
+	g := mock.NewMockHttpClient(s.ctrl)
+	g.EXPECT().Get("/search?media=podcast&entity=podcastEpisode&country=gb&term=dog+bingo&limit=200").Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       fh,
+	}, nil)
+	store := Store{hc: g}
+
+	episodes, err := store.SearchEpisodes("gb", "dog bingo")
+
+	s.NoError(err)
+	s.Equal(2, len(episodes))
+	s.Equal(&Episode{
+		Id:       "1000467521390",
+		Title:    "H.I. #136: Dog Bingo",
+		ShowId:   "811377230",
+		Show:     "Hello Internet",
+		Image:    "https://is1-ssl.mzstatic.com/image/thumb/Podcasts115/v4/a1/2b/3c/a12b3c4d-0000-0000-0000-000000000001/mza_1.jpg/600x600bb.jpg",
+		Released: time.Date(2020, 2, 28, 10, 13, 0, 0, time.UTC),
+		Duration: 2*time.Hour + 10*time.Minute + 15*time.Second,
+		Url:      "https://podcasts.apple.com/us/podcast/h-i-136-dog-bingo/id811377230?i=1000467521390&uo=4",
+		AudioUrl: "https://traffic.libsyn.com/hellointernet/136.mp3",
+	}, episodes[0])
+	s.Equal("https://traffic.libsyn.com/hellointernet/preview/135.mp3", episodes[1].AudioUrl)
+}
+
+func (s *StoreSuite) TestSearchEpisodesError() {
+	g := mock.NewMockHttpClient(s.ctrl)
+	g.EXPECT().Get(gomock.Any()).Return(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       io.NopCloser(strings.NewReader("unavailable")),
+	}, nil)
+	store := Store{hc: g}
+
+	_, err := store.SearchEpisodes("us", "dog bingo")
+
+	s.EqualError(err, "itunes search api error: unavailable")
+}
@@ -0,0 +1,51 @@
+package itunes
+
+import (
+	"fmt"
+	"sync"
+)
+
+// flight collapses concurrent calls with the same key into one, so that identical
+// requests made at the same time share a single round trip to iTunes.
+type flight struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	wg    sync.WaitGroup
+	value any
+	err   error
+}
+
+// share runs fn unless a call with the same key is already in flight, in which case
+// it waits for that call and returns its result. If fn panics, the panic goes on in the
+// caller that ran it and the ones waiting get an error.
+func share[T any](f *flight, key string, fn func() (T, error)) (T, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]*call)
+	}
+	if c, ok := f.calls[key]; ok {
+		f.mu.Unlock()
+		c.wg.Wait()
+		return c.value.(T), c.err
+	}
+
+	var zero T
+	c := &call{value: zero, err: fmt.Errorf("%s panicked", key)}
+	c.wg.Add(1)
+	f.calls[key] = c
+	f.mu.Unlock()
+
+	defer func() {
+		c.wg.Done()
+		f.mu.Lock()
+		delete(f.calls, key)
+		f.mu.Unlock()
+	}()
+
+	v, err := fn()
+	c.value, c.err = v, err
+	return v, err
+}
@@ -0,0 +1,196 @@
+package itunes
+
+import (
+	"errors"
+	"github.com/golang/mock/gomock"
+	"github.com/timiskhakov/podfinder/app/itunes/mock"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// waitForCall waits until a call with the key is in flight.
+func (s *StoreSuite) waitForCall(f *flight, key string) {
+	s.Eventually(func() bool {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		_, ok := f.calls[key]
+		return ok
+	}, time.Second, time.Millisecond)
+}
+
+// joinCall waits until a call with the key is in flight and gives other callers time to join it.
+func (s *StoreSuite) joinCall(f *flight, key string) {
+	s.waitForCall(f, key)
+	time.Sleep(50 * time.Millisecond)
+}
+
+func (s *StoreSuite) TestShare() {
+	f := &flight{}
+	release := make(chan struct{})
+	calls := 0
+
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := share(f, "key", func() (string, error) {
+				calls++
+				<-release
+				return "value", nil
+			})
+			s.NoError(err)
+			results[i] = v
+		}(i)
+	}
+	s.joinCall(f, "key")
+	close(release)
+	wg.Wait()
+
+	s.Equal(1, calls)
+	s.Equal([]string{"value", "value", "value", "value", "value"}, results)
+	s.Empty(f.calls)
+}
+
+func (s *StoreSuite) TestShareError() {
+	f := &flight{}
+	release := make(chan struct{})
+	expected := errors.New("boom")
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = share(f, "key", func() (*Podcast, error) {
+				<-release
+				return nil, expected
+			})
+		}(i)
+	}
+	s.joinCall(f, "key")
+	close(release)
+	wg.Wait()
+
+	for _, err := range errs {
+		s.ErrorIs(err, expected)
+	}
+}
+
+func (s *StoreSuite) TestSharePanic() {
+	f := &flight{}
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer func() { s.Equal("boom", recover()) }()
+		_, _ = share(f, "key", func() (string, error) {
+			<-release
+			panic("boom")
+		})
+	}()
+	s.waitForCall(f, "key")
+	go func() {
+		defer wg.Done()
+		v, err := share(f, "key", func() (string, error) { return "value", nil })
+		s.Empty(v)
+		s.ErrorContains(err, "key panicked")
+	}()
+	s.joinCall(f, "key")
+	close(release)
+	wg.Wait()
+
+	s.Empty(f.calls)
+	v, err := share(f, "key", func() (string, error) { return "value", nil })
+	s.NoError(err)
+	s.Equal("value", v)
+}
+
+func (s *StoreSuite) TestShareSequential() {
+	f := &flight{}
+	calls := 0
+
+	for i := 0; i < 3; i++ {
+		_, err := share(f, "key", func() (int, error) {
+			calls++
+			return calls, nil
+		})
+		s.NoError(err)
+	}
+
+	s.Equal(3, calls)
+}
+
+func (s *StoreSuite) TestLookupShared() {
+	fh, err := os.Open("../testdata/lookup.json")
+	s.NoError(err)
+	defer func() { _ = fh.Close() }()
+	release := make(chan struct{})
+	g := mock.NewMockHttpClient(s.ctrl)
+	g.EXPECT().Get(gomock.Any()).DoAndReturn(func(string) (*http.Response, error) {
+		<-release
+		return &http.Response{StatusCode: http.StatusOK, Body: fh}, nil
+	}).Times(1)
+	store := Store{hc: g}
+
+	var wg sync.WaitGroup
+	details := make([]*PodcastDetail, 4)
+	for i := range details {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			pd, err := store.Lookup("811377230")
+			s.NoError(err)
+			details[i] = pd
+		}(i)
+	}
+	s.joinCall(&store.flight, "lookup:811377230")
+	close(release)
+	wg.Wait()
+
+	for _, pd := range details {
+		s.Equal("Hello Internet", pd.Name)
+	}
+}
+
+func (s *StoreSuite) TestLookupSharedWithLookupMany() {
+	release := make(chan struct{})
+	g := mock.NewMockHttpClient(s.ctrl)
+	g.EXPECT().Get(gomock.Any()).DoAndReturn(func(string) (*http.Response, error) {
+		<-release
+		fh, err := os.Open("../testdata/lookup.json")
+		s.NoError(err)
+		return &http.Response{StatusCode: http.StatusOK, Body: fh}, nil
+	}).Times(2)
+	store := Store{hc: g}
+
+	var wg sync.WaitGroup
+	var detail *PodcastDetail
+	var details map[string]*PodcastDetail
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var err error
+		detail, err = store.Lookup("811377230")
+		s.NoError(err)
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		details, err = store.LookupMany([]string{"811377230"})
+		s.NoError(err)
+	}()
+	s.waitForCall(&store.flight, "lookup:811377230")
+	s.waitForCall(&store.flight, "lookupmany:811377230")
+	close(release)
+	wg.Wait()
+
+	s.Equal("Hello Internet", detail.Name)
+	s.Equal(detail, details["811377230"])
+}
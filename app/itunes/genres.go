@@ -0,0 +1,44 @@
+package itunes
+
+// Genre is a podcast category of Apple Podcasts, see: https://podcasts.apple.com/us/genre/podcasts/id26
+type Genre struct {
+	Id   int
+	Name string
+}
+
+// Genres lists top level podcast genres, sorted by name.
+var Genres = []Genre{
+	{1301, "Arts"},
+	{1321, "Business"},
+	{1303, "Comedy"},
+	{1304, "Education"},
+	{1483, "Fiction"},
+	{1325, "Government"},
+	{1307, "Health & Fitness"},
+	{1487, "History"},
+	{1305, "Kids & Family"},
+	{1323, "Leisure"},
+	{1310, "Music"},
+	{1311, "News"},
+	{1314, "Religion & Spirituality"},
+	{1315, "Science"},
+	{1324, "Society & Culture"},
+	{1316, "Sports"},
+	{1318, "Technology"},
+	{1488, "True Crime"},
+	{1309, "TV & Film"},
+}
+
+var genres = make(map[int]Genre, len(Genres))
+
+func init() {
+	for _, g := range Genres {
+		genres[g.Id] = g
+	}
+}
+
+// LookupGenre finds a genre by its id.
+func LookupGenre(id int) (Genre, bool) {
+	g, ok := genres[id]
+	return g, ok
+}
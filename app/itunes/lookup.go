@@ -6,10 +6,72 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
+// maxLookupBatch is how many ids a single lookup request carries.
+const maxLookupBatch = 100
+
+// MissingError lists ids LookupMany found no podcast for, it matches ErrNotFound.
+type MissingError struct {
+	Ids []string
+}
+
+func (e *MissingError) Error() string {
+	return "podcasts not found: " + strings.Join(e.Ids, ", ")
+}
+
+func (e *MissingError) Is(target error) bool {
+	return target == ErrNotFound
+}
+
 func (s *Store) Lookup(id string) (*PodcastDetail, error) {
-	resp, err := s.hc.Get(fmt.Sprintf("%s/lookup?id=%s", s.url, id))
+	return share(&s.flight, "lookup:"+id, func() (*PodcastDetail, error) {
+		return s.lookup(id)
+	})
+}
+
+func (s *Store) lookup(id string) (*PodcastDetail, error) {
+	results, err := s.fetchLookup([]string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+	if len(results) != 1 {
+		return nil, fmt.Errorf("invalid lookup result length: %d, expected 1", len(results))
+	}
+
+	return results[0].detail(), nil
+}
+
+// LookupMany fetches details of many podcasts in batches. Ids without a podcast don't fail
+// the call, they're reported with a MissingError next to the details that were found.
+func (s *Store) LookupMany(ids []string) (map[string]*PodcastDetail, error) {
+	details := make(map[string]*PodcastDetail, len(ids))
+	ids = uniqueIds(ids)
+	for from := 0; from < len(ids); from += maxLookupBatch {
+		batch := ids[from:min(from+maxLookupBatch, len(ids))]
+		// Batches share calls among themselves only, a batch of one id would otherwise get the result of Lookup
+		results, err := share(&s.flight, "lookupmany:"+strings.Join(batch, ","), func() ([]lookupResult, error) {
+			return s.fetchLookup(batch)
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range results {
+			d := r.detail()
+			details[d.Id] = d
+		}
+	}
+
+	return details, missing(ids, details)
+}
+
+func (s *Store) fetchLookup(ids []string) ([]lookupResult, error) {
+	resp, err := s.hc.Get(fmt.Sprintf("%s/lookup?id=%s", s.url, strings.Join(ids, ",")))
 	if err != nil {
 		return nil, err
 	}
@@ -28,20 +90,33 @@ func (s *Store) Lookup(id string) (*PodcastDetail, error) {
 		return nil, err
 	}
 
-	if len(r.Results) != 1 {
-		return nil, fmt.Errorf("invalid lookup result length: %d, expected 1", len(r.Results))
+	return r.Results, nil
+}
+
+func uniqueIds(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
 	}
+	return result
+}
 
-	return &PodcastDetail{
-		Id:           strconv.Itoa(r.Results[0].Id),
-		Artist:       r.Results[0].Artist,
-		Name:         r.Results[0].Name,
-		Image:        r.Results[0].Image,
-		EpisodeCount: r.Results[0].EpisodeCount,
-		Url:          r.Results[0].Url,
-		FeedUrl:      r.Results[0].FeedUrl,
-		Genres:       r.Results[0].Genres,
-	}, nil
+// missing returns a MissingError for ids without details, or nil when every id was found.
+func missing(ids []string, details map[string]*PodcastDetail) error {
+	err := &MissingError{}
+	for _, id := range ids {
+		if _, ok := details[id]; !ok {
+			err.Ids = append(err.Ids, id)
+		}
+	}
+	if len(err.Ids) == 0 {
+		return nil
+	}
+	return err
 }
 
 type lookupResponse struct {
@@ -58,3 +133,16 @@ type lookupResult struct {
 	FeedUrl      string   `json:"feedUrl"`
 	Genres       []string `json:"genres"`
 }
+
+func (r lookupResult) detail() *PodcastDetail {
+	return &PodcastDetail{
+		Id:           strconv.Itoa(r.Id),
+		Artist:       r.Artist,
+		Name:         r.Name,
+		Image:        r.Image,
+		EpisodeCount: r.EpisodeCount,
+		Url:          r.Url,
+		FeedUrl:      r.FeedUrl,
+		Genres:       r.Genres,
+	}
+}
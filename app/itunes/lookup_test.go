@@ -1,10 +1,14 @@
 package itunes
 
 import (
+	"fmt"
 	"github.com/golang/mock/gomock"
 	"github.com/timiskhakov/podfinder/app/itunes/mock"
+	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 )
 
 func (s *StoreSuite) TestLookup() {
@@ -16,7 +20,7 @@ func (s *StoreSuite) TestLookup() {
 		StatusCode: http.StatusOK,
 		Body:       fh,
 	}, nil)
-	store := Store{"", g}
+	store := Store{hc: g}
 
 	pd, err := store.Lookup("811377230")
 
@@ -32,3 +36,81 @@ func (s *StoreSuite) TestLookup() {
 		Genres:       []string{"Education", "Podcasts"},
 	}, pd)
 }
+
+func (s *StoreSuite) TestLookupNotFound() {
+	g := mock.NewMockHttpClient(s.ctrl)
+	g.EXPECT().Get(gomock.Any()).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(`{"resultCount": 0, "results": []}`)),
+	}, nil)
+	store := Store{hc: g}
+
+	pd, err := store.Lookup("1")
+
+	s.ErrorIs(err, ErrNotFound)
+	s.Nil(pd)
+}
+
+func (s *StoreSuite) TestLookupMany() {
+	g := mock.NewMockHttpClient(s.ctrl)
+	g.EXPECT().Get("/lookup?id=811377230,1,1200361736").Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body: io.NopCloser(strings.NewReader(`{"resultCount": 2, "results": [
+			{"collectionId": 811377230, "collectionName": "Hello Internet"},
+			{"collectionId": 1200361736, "collectionName": "The Daily"}
+		]}`)),
+	}, nil)
+	store := Store{hc: g}
+
+	details, err := store.LookupMany([]string{"811377230", "1", "1200361736", "811377230"})
+
+	s.ErrorIs(err, ErrNotFound)
+	var missing *MissingError
+	s.ErrorAs(err, &missing)
+	s.Equal([]string{"1"}, missing.Ids)
+	s.Len(details, 2)
+	s.Equal("The Daily", details["1200361736"].Name)
+}
+
+func (s *StoreSuite) TestLookupManyBatches() {
+	ids := make([]string, 150)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i + 1)
+	}
+	var urls []string
+	g := mock.NewMockHttpClient(s.ctrl)
+	g.EXPECT().Get(gomock.Any()).Times(2).DoAndReturn(func(url string) (*http.Response, error) {
+		urls = append(urls, url)
+		batch := strings.Split(strings.TrimPrefix(url, "/lookup?id="), ",")
+		results := make([]string, len(batch))
+		for i, id := range batch {
+			results[i] = fmt.Sprintf(`{"collectionId": %s}`, id)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"results": [` + strings.Join(results, ",") + `]}`)),
+		}, nil
+	})
+	store := Store{hc: g}
+
+	details, err := store.LookupMany(ids)
+
+	s.NoError(err)
+	s.Len(details, 150)
+	s.Equal("/lookup?id="+strings.Join(ids[:100], ","), urls[0])
+	s.Equal("/lookup?id="+strings.Join(ids[100:], ","), urls[1])
+}
+
+func (s *StoreSuite) TestLookupManyError() {
+	g := mock.NewMockHttpClient(s.ctrl)
+	g.EXPECT().Get(gomock.Any()).Return(&http.Response{
+		StatusCode: http.StatusServiceUnavailable,
+		Body:       io.NopCloser(strings.NewReader("unavailable")),
+	}, nil)
+	store := Store{hc: g}
+
+	details, err := store.LookupMany([]string{"1", "2"})
+
+	s.EqualError(err, "itunes api error: unavailable")
+	s.Nil(details)
+}
@@ -0,0 +1,258 @@
+package itunes
+
+import (
+	"sort"
+)
+
+const (
+	Africa       = "Africa"
+	Asia         = "Asia"
+	Europe       = "Europe"
+	NorthAmerica = "North America"
+	Oceania      = "Oceania"
+	SouthAmerica = "South America"
+)
+
+// Region is an Apple Podcasts storefront, Value is its ISO 3166-1 alpha-2 code,
+// see: https://en.wikipedia.org/wiki/ISO_3166-1_alpha-2
+type Region struct {
+	Value     string
+	Name      string
+	LocalName string
+	Continent string
+	// Charts and Reviews tell if the storefront publishes top charts and customer reviews
+	Charts  bool
+	Reviews bool
+}
+
+// HasCharts tells if top charts are published for the storefront.
+func (r Region) HasCharts() bool {
+	return r.Charts
+}
+
+// HasReviews tells if customer reviews are published for the storefront.
+func (r Region) HasReviews() bool {
+	return r.Reviews
+}
+
+// Continent groups regions for the region picker.
+type Continent struct {
+	Name    string
+	Regions []Region
+}
+
+// Regions lists storefronts Apple Podcasts serves, grouped by continent, init sorts it by name.
+var Regions = []Region{
+	{"dz", "Algeria", "الجزائر", Africa, true, true},
+	{"ao", "Angola", "Angola", Africa, true, true},
+	{"bj", "Benin", "Bénin", Africa, true, true},
+	{"bw", "Botswana", "Botswana", Africa, true, true},
+	{"bf", "Burkina Faso", "Burkina Faso", Africa, true, true},
+	{"cm", "Cameroon", "Cameroun", Africa, true, true},
+	{"cv", "Cape Verde", "Cabo Verde", Africa, true, true},
+	{"td", "Chad", "Tchad", Africa, true, true},
+	{"cg", "Congo", "Congo", Africa, true, true},
+	{"cd", "Congo, Democratic Republic of the", "RD Congo", Africa, true, true},
+	{"ci", "Côte d'Ivoire", "Côte d'Ivoire", Africa, true, true},
+	{"eg", "Egypt", "مصر", Africa, true, true},
+	{"sz", "Eswatini", "eSwatini", Africa, true, true},
+	{"ga", "Gabon", "Gabon", Africa, true, true},
+	{"gm", "Gambia", "Gambia", Africa, true, true},
+	{"gh", "Ghana", "Ghana", Africa, true, true},
+	{"gw", "Guinea-Bissau", "Guiné-Bissau", Africa, true, true},
+	{"ke", "Kenya", "Kenya", Africa, true, true},
+	{"lr", "Liberia", "Liberia", Africa, true, true},
+	{"ly", "Libya", "ليبيا", Africa, true, true},
+	{"mg", "Madagascar", "Madagasikara", Africa, true, true},
+	{"mw", "Malawi", "Malawi", Africa, true, true},
+	{"ml", "Mali", "Mali", Africa, true, true},
+	{"mr", "Mauritania", "موريتانيا", Africa, true, true},
+	{"mu", "Mauritius", "Maurice", Africa, true, true},
+	{"ma", "Morocco", "المغرب", Africa, true, true},
+	{"mz", "Mozambique", "Moçambique", Africa, true, true},
+	{"na", "Namibia", "Namibia", Africa, true, true},
+	{"ne", "Niger", "Niger", Africa, true, true},
+	{"ng", "Nigeria", "Nigeria", Africa, true, true},
+	{"rw", "Rwanda", "Rwanda", Africa, true, true},
+	{"st", "São Tomé and Príncipe", "São Tomé e Príncipe", Africa, true, true},
+	{"sn", "Senegal", "Sénégal", Africa, true, true},
+	{"sc", "Seychelles", "Seychelles", Africa, true, true},
+	{"sl", "Sierra Leone", "Sierra Leone", Africa, true, true},
+	{"za", "South Africa", "South Africa", Africa, true, true},
+	{"tz", "Tanzania", "Tanzania", Africa, true, true},
+	{"tn", "Tunisia", "تونس", Africa, true, true},
+	{"ug", "Uganda", "Uganda", Africa, true, true},
+	{"zm", "Zambia", "Zambia", Africa, true, true},
+	{"zw", "Zimbabwe", "Zimbabwe", Africa, true, true},
+
+	{"af", "Afghanistan", "افغانستان", Asia, true, true},
+	{"am", "Armenia", "Հայաստան", Asia, true, true},
+	{"az", "Azerbaijan", "Azərbaycan", Asia, true, true},
+	{"bh", "Bahrain", "البحرين", Asia, true, true},
+	{"bt", "Bhutan", "འབྲུག", Asia, true, true},
+	{"bn", "Brunei", "Brunei", Asia, true, true},
+	{"kh", "Cambodia", "កម្ពុជា", Asia, true, true},
+	{"cn", "China", "中国", Asia, false, false},
+	{"ge", "Georgia", "საქართველო", Asia, true, true},
+	{"hk", "Hong Kong", "香港", Asia, true, true},
+	{"in", "India", "भारत", Asia, true, true},
+	{"id", "Indonesia", "Indonesia", Asia, true, true},
+	{"iq", "Iraq", "العراق", Asia, true, true},
+	{"il", "Israel", "ישראל", Asia, true, true},
+	{"jp", "Japan", "日本", Asia, true, true},
+	{"jo", "Jordan", "الأردن", Asia, true, true},
+	{"kz", "Kazakhstan", "Қазақстан", Asia, true, true},
+	{"kw", "Kuwait", "الكويت", Asia, true, true},
+	{"kg", "Kyrgyzstan", "Кыргызстан", Asia, true, true},
+	{"la", "Laos", "ລາວ", Asia, true, true},
+	{"lb", "Lebanon", "لبنان", Asia, true, true},
+	{"mo", "Macao", "澳門", Asia, true, true},
+	{"my", "Malaysia", "Malaysia", Asia, true, true},
+	{"mv", "Maldives", "ދިވެހިރާއްޖެ", Asia, true, true},
+	{"mn", "Mongolia", "Монгол", Asia, true, true},
+	{"mm", "Myanmar", "မြန်မာ", Asia, true, true},
+	{"np", "Nepal", "नेपाल", Asia, true, true},
+	{"om", "Oman", "عُمان", Asia, true, true},
+	{"pk", "Pakistan", "پاکستان", Asia, true, true},
+	{"ph", "Philippines", "Pilipinas", Asia, true, true},
+	{"qa", "Qatar", "قطر", Asia, true, true},
+	{"sa", "Saudi Arabia", "السعودية", Asia, true, true},
+	{"sg", "Singapore", "Singapore", Asia, true, true},
+	{"kr", "South Korea", "대한민국", Asia, true, true},
+	{"lk", "Sri Lanka", "ශ්‍රී ලංකාව", Asia, true, true},
+	{"tw", "Taiwan", "臺灣", Asia, true, true},
+	{"tj", "Tajikistan", "Тоҷикистон", Asia, true, true},
+	{"th", "Thailand", "ประเทศไทย", Asia, true, true},
+	{"tr", "Türkiye", "Türkiye", Asia, true, true},
+	{"tm", "Turkmenistan", "Türkmenistan", Asia, true, true},
+	{"ae", "United Arab Emirates", "الإمارات", Asia, true, true},
+	{"uz", "Uzbekistan", "Oʻzbekiston", Asia, true, true},
+	{"vn", "Vietnam", "Việt Nam", Asia, true, true},
+	{"ye", "Yemen", "اليمن", Asia, true, true},
+
+	{"al", "Albania", "Shqipëri", Europe, true, true},
+	{"at", "Austria", "Österreich", Europe, true, true},
+	{"by", "Belarus", "Беларусь", Europe, true, true},
+	{"be", "Belgium", "België", Europe, true, true},
+	{"ba", "Bosnia and Herzegovina", "Bosna i Hercegovina", Europe, true, true},
+	{"bg", "Bulgaria", "България", Europe, true, true},
+	{"hr", "Croatia", "Hrvatska", Europe, true, true},
+	{"cy", "Cyprus", "Κύπρος", Europe, true, true},
+	{"cz", "Czechia", "Česko", Europe, true, true},
+	{"dk", "Denmark", "Danmark", Europe, true, true},
+	{"ee", "Estonia", "Eesti", Europe, true, true},
+	{"fi", "Finland", "Suomi", Europe, true, true},
+	{"fr", "France", "France", Europe, true, true},
+	{"de", "Germany", "Deutschland", Europe, true, true},
+	{"gr", "Greece", "Ελλάδα", Europe, true, true},
+	{"hu", "Hungary", "Magyarország", Europe, true, true},
+	{"is", "Iceland", "Ísland", Europe, true, true},
+	{"ie", "Ireland", "Ireland", Europe, true, true},
+	{"it", "Italy", "Italia", Europe, true, true},
+	{"xk", "Kosovo", "Kosova", Europe, true, true},
+	{"lv", "Latvia", "Latvija", Europe, true, true},
+	{"lt", "Lithuania", "Lietuva", Europe, true, true},
+	{"lu", "Luxembourg", "Lëtzebuerg", Europe, true, true},
+	{"mt", "Malta", "Malta", Europe, true, true},
+	{"md", "Moldova", "Moldova", Europe, true, true},
+	{"me", "Montenegro", "Crna Gora", Europe, true, true},
+	{"nl", "Netherlands", "Nederland", Europe, true, true},
+	{"mk", "North Macedonia", "Северна Македонија", Europe, true, true},
+	{"no", "Norway", "Norge", Europe, true, true},
+	{"pl", "Poland", "Polska", Europe, true, true},
+	{"pt", "Portugal", "Portugal", Europe, true, true},
+	{"ro", "Romania", "România", Europe, true, true},
+	{"ru", "Russia", "Россия", Europe, true, true},
+	{"rs", "Serbia", "Србија", Europe, true, true},
+	{"sk", "Slovakia", "Slovensko", Europe, true, true},
+	{"si", "Slovenia", "Slovenija", Europe, true, true},
+	{"es", "Spain", "España", Europe, true, true},
+	{"se", "Sweden", "Sverige", Europe, true, true},
+	{"ch", "Switzerland", "Schweiz", Europe, true, true},
+	{"ua", "Ukraine", "Україна", Europe, true, true},
+	{"gb", "United Kingdom", "United Kingdom", Europe, true, true},
+
+	{"ai", "Anguilla", "Anguilla", NorthAmerica, true, true},
+	{"ag", "Antigua and Barbuda", "Antigua and Barbuda", NorthAmerica, true, true},
+	{"bs", "Bahamas", "Bahamas", NorthAmerica, true, true},
+	{"bb", "Barbados", "Barbados", NorthAmerica, true, true},
+	{"bz", "Belize", "Belize", NorthAmerica, true, true},
+	{"bm", "Bermuda", "Bermuda", NorthAmerica, true, true},
+	{"vg", "British Virgin Islands", "British Virgin Islands", NorthAmerica, true, true},
+	{"ca", "Canada", "Canada", NorthAmerica, true, true},
+	{"ky", "Cayman Islands", "Cayman Islands", NorthAmerica, true, true},
+	{"cr", "Costa Rica", "Costa Rica", NorthAmerica, true, true},
+	{"dm", "Dominica", "Dominica", NorthAmerica, true, true},
+	{"do", "Dominican Republic", "República Dominicana", NorthAmerica, true, true},
+	{"sv", "El Salvador", "El Salvador", NorthAmerica, true, true},
+	{"gd", "Grenada", "Grenada", NorthAmerica, true, true},
+	{"gt", "Guatemala", "Guatemala", NorthAmerica, true, true},
+	{"hn", "Honduras", "Honduras", NorthAmerica, true, true},
+	{"jm", "Jamaica", "Jamaica", NorthAmerica, true, true},
+	{"mx", "Mexico", "México", NorthAmerica, true, true},
+	{"ms", "Montserrat", "Montserrat", NorthAmerica, true, true},
+	{"ni", "Nicaragua", "Nicaragua", NorthAmerica, true, true},
+	{"pa", "Panama", "Panamá", NorthAmerica, true, true},
+	{"kn", "Saint Kitts and Nevis", "Saint Kitts and Nevis", NorthAmerica, true, true},
+	{"lc", "Saint Lucia", "Saint Lucia", NorthAmerica, true, true},
+	{"vc", "Saint Vincent and the Grenadines", "Saint Vincent and the Grenadines", NorthAmerica, true, true},
+	{"tt", "Trinidad and Tobago", "Trinidad and Tobago", NorthAmerica, true, true},
+	{"tc", "Turks and Caicos Islands", "Turks and Caicos Islands", NorthAmerica, true, true},
+	{"us", "United States", "United States", NorthAmerica, true, true},
+
+	{"au", "Australia", "Australia", Oceania, true, true},
+	{"fj", "Fiji", "Fiji", Oceania, true, true},
+	{"fm", "Micronesia", "Micronesia", Oceania, true, true},
+	{"nr", "Nauru", "Naoero", Oceania, true, true},
+	{"nz", "New Zealand", "New Zealand", Oceania, true, true},
+	{"pw", "Palau", "Belau", Oceania, true, true},
+	{"pg", "Papua New Guinea", "Papua Niugini", Oceania, true, true},
+	{"sb", "Solomon Islands", "Solomon Islands", Oceania, true, true},
+	{"to", "Tonga", "Tonga", Oceania, true, true},
+	{"vu", "Vanuatu", "Vanuatu", Oceania, true, true},
+
+	{"ar", "Argentina", "Argentina", SouthAmerica, true, true},
+	{"bo", "Bolivia", "Bolivia", SouthAmerica, true, true},
+	{"br", "Brazil", "Brasil", SouthAmerica, true, true},
+	{"cl", "Chile", "Chile", SouthAmerica, true, true},
+	{"co", "Colombia", "Colombia", SouthAmerica, true, true},
+	{"ec", "Ecuador", "Ecuador", SouthAmerica, true, true},
+	{"gy", "Guyana", "Guyana", SouthAmerica, true, true},
+	{"py", "Paraguay", "Paraguay", SouthAmerica, true, true},
+	{"pe", "Peru", "Perú", SouthAmerica, true, true},
+	{"sr", "Suriname", "Suriname", SouthAmerica, true, true},
+	{"uy", "Uruguay", "Uruguay", SouthAmerica, true, true},
+	{"ve", "Venezuela", "Venezuela", SouthAmerica, true, true},
+}
+
+// Continents holds Regions grouped by continent, continents and regions within them are sorted by name.
+var Continents []Continent
+
+var regions = make(map[string]Region, len(Regions))
+
+func init() {
+	sort.SliceStable(Regions, func(i, j int) bool { return Regions[i].Name < Regions[j].Name })
+
+	for _, r := range Regions {
+		regions[r.Value] = r
+
+		i := sort.Search(len(Continents), func(i int) bool { return Continents[i].Name >= r.Continent })
+		if i == len(Continents) || Continents[i].Name != r.Continent {
+			Continents = append(Continents, Continent{})
+			copy(Continents[i+1:], Continents[i:])
+			Continents[i] = Continent{Name: r.Continent}
+		}
+		Continents[i].Regions = append(Continents[i].Regions, r)
+	}
+}
+
+// LookupRegion finds a storefront by its ISO code.
+func LookupRegion(v string) (Region, bool) {
+	r, ok := regions[v]
+	return r, ok
+}
+
+func isSupportedRegion(v string) bool {
+	_, ok := regions[v]
+	return ok
+}
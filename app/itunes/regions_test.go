@@ -0,0 +1,58 @@
+package itunes
+
+import (
+	"github.com/timiskhakov/podfinder/app/itunes/mock"
+	"regexp"
+)
+
+func (s *StoreSuite) TestRegions() {
+	code := regexp.MustCompile(`^[a-z]{2}$`)
+	count := 0
+	for _, c := range Continents {
+		for i, r := range c.Regions {
+			s.Regexp(code, r.Value)
+			s.Equal(c.Name, r.Continent)
+			s.NotEmpty(r.LocalName)
+			if i > 0 {
+				s.Less(c.Regions[i-1].Name, r.Name)
+			}
+			count++
+		}
+	}
+
+	s.Equal(len(Regions), count)
+	s.Len(regions, len(Regions))
+	s.Equal([]string{Africa, Asia, Europe, NorthAmerica, Oceania, SouthAmerica}, []string{
+		Continents[0].Name, Continents[1].Name, Continents[2].Name,
+		Continents[3].Name, Continents[4].Name, Continents[5].Name,
+	})
+}
+
+func (s *StoreSuite) TestLookupRegion() {
+	r, ok := LookupRegion("fi")
+	s.True(ok)
+	s.Equal(Region{"fi", "Finland", "Suomi", Europe, true, true}, r)
+	s.True(r.HasCharts())
+	s.True(r.HasReviews())
+
+	r, ok = LookupRegion("cn")
+	s.True(ok)
+	s.False(r.HasCharts())
+	s.False(r.HasReviews())
+
+	_, ok = LookupRegion("uk")
+	s.False(ok)
+}
+
+func (s *StoreSuite) TestWithoutCharts() {
+	store := Store{hc: mock.NewMockHttpClient(s.ctrl)}
+
+	podcasts, err := store.Top("cn", Chart{})
+	s.NoError(err)
+	s.Empty(podcasts)
+
+	reviews, err := store.Reviews("1", "cn", ReviewsQuery{})
+	s.NoError(err)
+	s.Empty(reviews.Reviews)
+	s.False(reviews.HasMore())
+}
@@ -1,20 +1,83 @@
 package itunes
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"regexp"
 	"strconv"
 	"time"
 )
 
-func (s *Store) Reviews(id, region string) ([]*Review, error) {
+const (
+	ReviewsMostRecent  ReviewSort = "mostrecent"
+	ReviewsMostHelpful ReviewSort = "mosthelpful"
+
+	// MaxReviewPages is how many pages of 50 reviews storefronts serve
+	MaxReviewPages = 10
+)
+
+var pageRegexp = regexp.MustCompile(`/page=(\d+)/`)
+
+// ReviewSort orders reviews, the storefront sorts them before paging.
+type ReviewSort string
+
+// ReviewsQuery selects a page of reviews. The zero value is the first page of the most recent reviews.
+type ReviewsQuery struct {
+	// Page is clamped to MaxReviewPages, zero means the first page
+	Page int
+	Sort ReviewSort
+}
+
+// ReviewPage is a page of reviews.
+type ReviewPage struct {
+	Reviews []*Review
+	Page    int
+	// Last is the number of the last page, zero when there are no reviews
+	Last int
+}
+
+// Normalize fills in defaults and clamps the page.
+func (q ReviewsQuery) Normalize() ReviewsQuery {
+	if q.Sort != ReviewsMostHelpful {
+		q.Sort = ReviewsMostRecent
+	}
+	q.Page = max(1, min(q.Page, MaxReviewPages))
+	return q
+}
+
+// Key identifies a query in cache keys.
+func (q ReviewsQuery) Key() string {
+	q = q.Normalize()
+	return fmt.Sprintf("%s:%d", q.Sort, q.Page)
+}
+
+func (p *ReviewPage) HasMore() bool {
+	return p.Page < p.Last
+}
+
+func (p *ReviewPage) Next() int {
+	return p.Page + 1
+}
+
+func (s *Store) Reviews(id, region string, q ReviewsQuery) (*ReviewPage, error) {
+	return share(&s.flight, "reviews:"+id+":"+region+":"+q.Key(), func() (*ReviewPage, error) {
+		return s.reviews(id, region, q)
+	})
+}
+
+func (s *Store) reviews(id, region string, q ReviewsQuery) (*ReviewPage, error) {
+	q = q.Normalize()
 	if !isSupportedRegion(region) {
 		region = DefaultRegion
 	}
+	if reg, _ := LookupRegion(region); !reg.HasReviews() {
+		return &ReviewPage{Page: q.Page}, nil
+	}
 
-	resp, err := s.hc.Get(fmt.Sprintf("%s/%s/rss/customerreviews/id=%s/json", s.url, region, id))
+	resp, err := s.hc.Get(fmt.Sprintf("%s/%s/rss/customerreviews/page=%d/id=%s/sortby=%s/json", s.url, region, q.Page, id, q.Sort))
 	if err != nil {
 		return nil, err
 	}
@@ -33,19 +96,38 @@ func (s *Store) Reviews(id, region string) ([]*Review, error) {
 		return nil, err
 	}
 
-	reviews := make([]*Review, 0, len(r.Feed.Reviews))
+	page := &ReviewPage{Reviews: make([]*Review, 0, len(r.Feed.Reviews)), Page: q.Page}
 	for _, r := range r.Feed.Reviews {
-		reviews = append(reviews, &Review{
-			Id:      r.Id.Label,
-			Author:  r.Author.Name.Label,
-			Title:   r.Title.Label,
-			Content: r.Content.Label,
-			Rating:  rating(r.Rating.Label),
-			Date:    date(r.Updated.Label),
+		page.Reviews = append(page.Reviews, &Review{
+			Id:        r.Id.Label,
+			Author:    r.Author.Name.Label,
+			Title:     r.Title.Label,
+			Content:   r.Content.Label,
+			Rating:    rating(r.Rating.Label),
+			Date:      date(r.Updated.Label),
+			VoteCount: number(r.VoteCount.Label),
+			VoteSum:   number(r.VoteSum.Label),
+			Version:   r.Version.Label,
 		})
 	}
+	if len(page.Reviews) > 0 {
+		page.Last = max(q.Page, lastPage(r.Feed.Links))
+	}
+
+	return page, nil
+}
 
-	return reviews, nil
+// lastPage finds the number of the last page in links of a reviews feed.
+func lastPage(links []link) int {
+	for _, l := range links {
+		if l.Attributes.Rel != "last" {
+			continue
+		}
+		if m := pageRegexp.FindStringSubmatch(l.Attributes.Href); m != nil {
+			return min(number(m[1]), MaxReviewPages)
+		}
+	}
+	return 0
 }
 
 func rating(s string) []struct{} {
@@ -53,6 +135,11 @@ func rating(s string) []struct{} {
 	return make([]struct{}, v)
 }
 
+func number(s string) int {
+	v, _ := strconv.Atoi(s)
+	return v
+}
+
 func date(s string) time.Time {
 	v, _ := time.Parse(time.RFC3339, s)
 	return v
@@ -63,17 +150,42 @@ type reviewsResponse struct {
 }
 
 type reviewsFeed struct {
-	Update  text     `json:"updated"`
-	Reviews []review `json:"entry"`
+	Update  text              `json:"updated"`
+	Links   oneOrMany[link]   `json:"link"`
+	Reviews oneOrMany[review] `json:"entry"`
 }
 
 type review struct {
-	Id      text   `json:"id"`
-	Title   text   `json:"title"`
-	Author  author `json:"author"`
-	Content text   `json:"content"`
-	Rating  text   `json:"im:rating"`
-	Updated text   `json:"updated"`
+	Id        text   `json:"id"`
+	Title     text   `json:"title"`
+	Author    author `json:"author"`
+	Content   text   `json:"content"`
+	Rating    text   `json:"im:rating"`
+	VoteCount text   `json:"im:voteCount"`
+	VoteSum   text   `json:"im:voteSum"`
+	Version   text   `json:"im:version"`
+	Updated   text   `json:"updated"`
+}
+
+// oneOrMany decodes a list that feeds turn into a single object when it has one element.
+type oneOrMany[T any] []T
+
+func (l *oneOrMany[T]) UnmarshalJSON(b []byte) error {
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '{' {
+		var v T
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		*l = oneOrMany[T]{v}
+		return nil
+	}
+
+	var v []T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*l = v
+	return nil
 }
 
 type author struct {
@@ -3,8 +3,10 @@ package itunes
 import (
 	"github.com/golang/mock/gomock"
 	"github.com/timiskhakov/podfinder/app/itunes/mock"
+	"io"
 	"net/http"
 	"os"
+	"strings"
 )
 
 func (s *StoreSuite) TestReviews() {
@@ -12,16 +14,64 @@ func (s *StoreSuite) TestReviews() {
 	s.NoError(err)
 	defer func() { _ = fh.Close() }()
 	g := mock.NewMockHttpClient(s.ctrl)
-	g.EXPECT().Get(gomock.Any()).Return(&http.Response{
+	g.EXPECT().Get("/us/rss/customerreviews/page=1/id=811377230/sortby=mostrecent/json").Return(&http.Response{
 		StatusCode: http.StatusOK,
 		Body:       fh,
 	}, nil)
-	store := Store{"", g}
+	store := Store{hc: g}
+
+	reviews, err := store.Reviews("811377230", "us", ReviewsQuery{})
+
+	s.NoError(err)
+	s.Equal(50, len(reviews.Reviews))
+	s.Equal(1, reviews.Page)
+	s.Equal(10, reviews.Last)
+	s.True(reviews.HasMore())
+}
+
+func (s *StoreSuite) TestReviewsPage() {
+	g := mock.NewMockHttpClient(s.ctrl)
+	g.EXPECT().Get("/gb/rss/customerreviews/page=10/id=1/sortby=mosthelpful/json").Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body: io.NopCloser(strings.NewReader(`{"feed": {
+			"link": [{"attributes": {"rel": "last", "href": "https://itunes.apple.com/gb/rss/customerreviews/page=10/id=1/sortby=mosthelpful/xml"}}],
+			"entry": {
+				"id": {"label": "42"},
+				"im:rating": {"label": "4"},
+				"im:version": {"label": "1.2"},
+				"im:voteCount": {"label": "7"},
+				"im:voteSum": {"label": "5"},
+				"updated": {"label": "2022-03-02T19:05:50-07:00"}
+			}
+		}}`)),
+	}, nil)
+	store := Store{hc: g}
+
+	reviews, err := store.Reviews("1", "gb", ReviewsQuery{Page: 12, Sort: ReviewsMostHelpful})
+
+	s.NoError(err)
+	s.Equal(10, reviews.Page)
+	s.False(reviews.HasMore())
+	s.Len(reviews.Reviews, 1)
+	s.Equal("42", reviews.Reviews[0].Id)
+	s.Equal(7, reviews.Reviews[0].VoteCount)
+	s.Equal(5, reviews.Reviews[0].VoteSum)
+	s.Equal("1.2", reviews.Reviews[0].Version)
+}
+
+func (s *StoreSuite) TestReviewsEmpty() {
+	g := mock.NewMockHttpClient(s.ctrl)
+	g.EXPECT().Get(gomock.Any()).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(`{"feed": {"link": [{"attributes": {"rel": "self", "href": "https://itunes.apple.com/us/rss/customerreviews/page=3/id=1/json"}}]}}`)),
+	}, nil)
+	store := Store{hc: g}
 
-	reviews, err := store.Reviews("811377230", "us")
+	reviews, err := store.Reviews("1", "us", ReviewsQuery{Page: 3})
 
 	s.NoError(err)
-	s.Equal(50, len(reviews))
+	s.Empty(reviews.Reviews)
+	s.Equal(0, reviews.Last)
 }
 
 func (s *StoreSuite) TestRating() {
@@ -6,15 +6,134 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
-func (s *Store) Search(region, query string) ([]*Podcast, error) {
+const (
+	defaultSearchLimit = 20
+	// MaxSearchLimit is the largest number of results the search API returns for a term
+	MaxSearchLimit = 200
+)
+
+// SearchAttribute restricts a search term to a single field of podcasts.
+type SearchAttribute string
+
+const (
+	AttributeTitle    SearchAttribute = "titleTerm"
+	AttributeAuthor   SearchAttribute = "authorTerm"
+	AttributeKeywords SearchAttribute = "keywordsTerm"
+)
+
+// SearchSort orders search results, relevance keeps the order of iTunes.
+type SearchSort string
+
+const (
+	SortRelevance SearchSort = "relevance"
+	SortName      SearchSort = "name"
+	SortArtist    SearchSort = "artist"
+	SortEpisodes  SearchSort = "episodes"
+	SortReleased  SearchSort = "released"
+)
+
+// Query is a podcast search. iTunes is asked for every match of the term at once, filtering by genre,
+// sorting and paging happen locally, so other pages and orders of cached matches need no requests.
+type Query struct {
+	Term string
+	// Attribute is empty to match the term against every field
+	Attribute SearchAttribute
+	// Genre is a genre id, zero means all genres
+	Genre int
+	// Clean leaves out explicit podcasts
+	Clean bool
+	Sort  SearchSort
+	// Limit is clamped to MaxSearchLimit, zero means 20
+	Limit  int
+	Offset int
+}
+
+// SearchResults is a page of search results.
+type SearchResults struct {
+	Podcasts []*Podcast
+	// Total is the number of matches across all pages
+	Total int
+}
+
+// Normalize fills in defaults and clamps the limit and offset.
+func (q Query) Normalize() Query {
+	switch q.Attribute {
+	case AttributeTitle, AttributeAuthor, AttributeKeywords:
+	default:
+		q.Attribute = ""
+	}
+	if _, ok := LookupGenre(q.Genre); !ok {
+		q.Genre = 0
+	}
+	switch q.Sort {
+	case SortName, SortArtist, SortEpisodes, SortReleased:
+	default:
+		q.Sort = SortRelevance
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultSearchLimit
+	}
+	if q.Limit > MaxSearchLimit {
+		q.Limit = MaxSearchLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	return q
+}
+
+// Key identifies matches of a query in cache keys, it leaves out the order and the page.
+func (q Query) Key() string {
+	q = q.Normalize()
+	return fmt.Sprintf("%s:%d", q.fetchKey(), q.Genre)
+}
+
+// All returns the query for every match in the order of iTunes, which Page sorts and pages later.
+func (q Query) All() Query {
+	q.Sort = SortRelevance
+	q.Limit = MaxSearchLimit
+	q.Offset = 0
+	return q.Normalize()
+}
+
+// fetchKey identifies the request made to iTunes, which doesn't depend on the local options.
+func (q Query) fetchKey() string {
+	return fmt.Sprintf("%s:%t:%s", q.Attribute, q.Clean, q.Term)
+}
+
+func (s *Store) Search(region string, q Query) (*SearchResults, error) {
+	q = q.Normalize()
+	results, err := share(&s.flight, "search:"+region+":"+q.fetchKey(), func() ([]searchResult, error) {
+		return s.search(region, q)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return q.apply(results), nil
+}
+
+func (s *Store) search(region string, q Query) ([]searchResult, error) {
 	if !isSupportedRegion(region) {
 		region = DefaultRegion
 	}
 
-	resp, err := s.hc.Get(fmt.Sprintf("%s/search?media=podcast&entity=podcast&country=%s&term=%s", s.url, region, url.QueryEscape(query)))
+	u := fmt.Sprintf("%s/search?media=podcast&entity=podcast&country=%s&term=%s&limit=%d", s.url, region, url.QueryEscape(q.Term), MaxSearchLimit)
+	if q.Attribute != "" {
+		u += "&attribute=" + string(q.Attribute)
+	}
+	if q.Clean {
+		u += "&explicit=No"
+	}
+
+	resp, err := s.hc.Get(u)
 	if err != nil {
 		return nil, err
 	}
@@ -33,17 +152,53 @@ func (s *Store) Search(region, query string) ([]*Podcast, error) {
 		return nil, err
 	}
 
-	podcasts := make([]*Podcast, len(r.Results))
-	for i, r := range r.Results {
-		podcasts[i] = &Podcast{
-			Id:     strconv.Itoa(r.Id),
-			Artist: r.Artist,
-			Name:   r.Name,
-			Image:  r.Image,
+	return r.Results, nil
+}
+
+// apply filters, sorts and pages results fetched for the query.
+func (q Query) apply(results []searchResult) *SearchResults {
+	genre := strconv.Itoa(q.Genre)
+	podcasts := make([]*Podcast, 0, len(results))
+	for _, r := range results {
+		if q.Genre != 0 && !slices.Contains(r.GenreIds, genre) {
+			continue
+		}
+		if q.Clean && r.Explicitness == "explicit" {
+			continue
 		}
+		podcasts = append(podcasts, r.podcast())
 	}
 
-	return podcasts, nil
+	return q.Page(podcasts)
+}
+
+// Page sorts matches of the query and returns the requested page, matches are left as they are.
+func (q Query) Page(podcasts []*Podcast) *SearchResults {
+	q = q.Normalize()
+	podcasts = slices.Clone(podcasts)
+
+	switch q.Sort {
+	case SortName:
+		sort.SliceStable(podcasts, func(i, j int) bool {
+			return strings.ToLower(podcasts[i].Name) < strings.ToLower(podcasts[j].Name)
+		})
+	case SortArtist:
+		sort.SliceStable(podcasts, func(i, j int) bool {
+			return strings.ToLower(podcasts[i].Artist) < strings.ToLower(podcasts[j].Artist)
+		})
+	case SortEpisodes:
+		sort.SliceStable(podcasts, func(i, j int) bool {
+			return podcasts[i].EpisodeCount > podcasts[j].EpisodeCount
+		})
+	case SortReleased:
+		sort.SliceStable(podcasts, func(i, j int) bool {
+			return podcasts[i].Released.After(podcasts[j].Released)
+		})
+	}
+
+	from := min(q.Offset, len(podcasts))
+	to := min(from+q.Limit, len(podcasts))
+	return &SearchResults{Podcasts: podcasts[from:to], Total: len(podcasts)}
 }
 
 type searchResponse struct {
@@ -52,8 +207,29 @@ type searchResponse struct {
 }
 
 type searchResult struct {
-	Id     int    `json:"collectionId"`
-	Artist string `json:"artistName"`
-	Name   string `json:"collectionName"`
-	Image  string `json:"artworkUrl600"`
+	Id           int       `json:"collectionId"`
+	Artist       string    `json:"artistName"`
+	Name         string    `json:"collectionName"`
+	Image        string    `json:"artworkUrl600"`
+	Genre        string    `json:"primaryGenreName"`
+	Genres       []string  `json:"genres"`
+	GenreIds     []string  `json:"genreIds"`
+	Released     time.Time `json:"releaseDate"`
+	TrackCount   int       `json:"trackCount"`
+	Explicitness string    `json:"collectionExplicitness"`
+	FeedUrl      string    `json:"feedUrl"`
+}
+
+func (r searchResult) podcast() *Podcast {
+	return &Podcast{
+		Id:           strconv.Itoa(r.Id),
+		Artist:       r.Artist,
+		Name:         r.Name,
+		Image:        r.Image,
+		Genre:        r.Genre,
+		EpisodeCount: r.TrackCount,
+		Released:     r.Released,
+		Explicit:     r.Explicitness == "explicit",
+		FeedUrl:      r.FeedUrl,
+	}
 }
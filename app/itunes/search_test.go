@@ -1,31 +1,93 @@
 package itunes
 
 import (
-	"github.com/golang/mock/gomock"
 	"github.com/timiskhakov/podfinder/app/itunes/mock"
 	"net/http"
 	"os"
+	"time"
 )
 
-func (s *StoreSuite) TestSearch() {
-	fh, err := os.Open("../testdata/search.json")
-	s.NoError(err)
-	defer func() { _ = fh.Close() }()
+func (s *StoreSuite) searchStore(url string) *Store {
 	g := mock.NewMockHttpClient(s.ctrl)
-	g.EXPECT().Get(gomock.Any()).Return(&http.Response{
-		StatusCode: http.StatusOK,
-		Body:       fh,
-	}, nil)
-	store := Store{"", g}
+	g.EXPECT().Get(url).DoAndReturn(func(string) (*http.Response, error) {
+		fh, err := os.Open("../testdata/search.json")
+		s.NoError(err)
+		return &http.Response{StatusCode: http.StatusOK, Body: fh}, nil
+	})
+	return &Store{hc: g}
+}
 
-	podcasts, err := store.Search("", "Hello Internet")
+func (s *StoreSuite) TestSearch() {
+	store := s.searchStore("/search?media=podcast&entity=podcast&country=us&term=Hello+Internet&limit=200")
+
+	results, err := store.Search("", Query{Term: "Hello Internet"})
 
 	s.NoError(err)
-	s.Equal(5, len(podcasts))
+	s.Equal(5, results.Total)
+	s.Equal(5, len(results.Podcasts))
 	s.Equal(&Podcast{
-		Id:     "811377230",
-		Artist: "CGP Grey & Brady Haran",
-		Name:   "Hello Internet",
-		Image:  "https://is5-ssl.mzstatic.com/image/thumb/Podcasts6/v4/19/33/fe/1933fe85-cd86-2191-8187-d725ca7359bf/mza_8038397602264410223.png/600x600bb.jpg",
-	}, podcasts[0])
+		Id:           "811377230",
+		Artist:       "CGP Grey & Brady Haran",
+		Name:         "Hello Internet",
+		Image:        "https://is5-ssl.mzstatic.com/image/thumb/Podcasts6/v4/19/33/fe/1933fe85-cd86-2191-8187-d725ca7359bf/mza_8038397602264410223.png/600x600bb.jpg",
+		Genre:        "Education",
+		EpisodeCount: 100,
+		Released:     time.Date(2020, 2, 28, 10, 13, 0, 0, time.UTC),
+		FeedUrl:      "http://www.hellointernet.fm/podcast?format=rss",
+	}, results.Podcasts[0])
+	s.True(results.Podcasts[4].Explicit)
+}
+
+func (s *StoreSuite) TestSearchFilters() {
+	store := s.searchStore("/search?media=podcast&entity=podcast&country=fi&term=hello&limit=200&attribute=titleTerm&explicit=No")
+
+	results, err := store.Search("fi", Query{Term: "hello", Attribute: AttributeTitle, Genre: 1303, Clean: true})
+
+	s.NoError(err)
+	s.Equal(2, results.Total)
+	s.Equal("1567190167", results.Podcasts[0].Id)
+	s.Equal("151622312", results.Podcasts[1].Id)
+}
+
+func (s *StoreSuite) TestSearchSort() {
+	ids := func(podcasts []*Podcast) []string {
+		result := make([]string, len(podcasts))
+		for i, p := range podcasts {
+			result[i] = p.Id
+		}
+		return result
+	}
+	tests := map[SearchSort][]string{
+		SortRelevance: {"811377230", "1567190167", "151622312", "209227182", "519611048"},
+		SortName:      {"811377230", "519611048", "1567190167", "209227182", "151622312"},
+		SortEpisodes:  {"151622312", "811377230", "209227182", "1567190167", "519611048"},
+		SortReleased:  {"1567190167", "811377230", "151622312", "519611048", "209227182"},
+	}
+
+	for sort, expected := range tests {
+		store := s.searchStore("/search?media=podcast&entity=podcast&country=us&term=hello&limit=200")
+
+		results, err := store.Search("us", Query{Term: "hello", Sort: sort})
+
+		s.NoError(err)
+		s.Equal(expected, ids(results.Podcasts), sort)
+	}
+}
+
+func (s *StoreSuite) TestSearchPaging() {
+	store := s.searchStore("/search?media=podcast&entity=podcast&country=us&term=hello&limit=200")
+
+	results, err := store.Search("us", Query{Term: "hello", Sort: SortEpisodes, Limit: 2, Offset: 2})
+
+	s.NoError(err)
+	s.Equal(5, results.Total)
+	s.Equal(2, len(results.Podcasts))
+	s.Equal("209227182", results.Podcasts[0].Id)
+}
+
+func (s *StoreSuite) TestQueryNormalize() {
+	q := Query{Attribute: "languageTerm", Genre: 42, Sort: "rating", Limit: 500, Offset: -1}.Normalize()
+
+	s.Equal(Query{Sort: SortRelevance, Limit: MaxSearchLimit}, q)
+	s.Equal(defaultSearchLimit, Query{}.Normalize().Limit)
 }
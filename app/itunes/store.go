@@ -1,35 +1,21 @@
 package itunes
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 const (
 	defaultUrl    = "https://itunes.apple.com"
 	DefaultRegion = "us"
 )
 
-// Regions lists ISO country codes, see: https://en.wikipedia.org/wiki/ISO_3166-1_alpha-2
-var Regions = []Region{
-	{
-		Value: "fi",
-		Name:  "Finland",
-	},
-	{
-		Value: "ru",
-		Name:  "Russia",
-	},
-	{
-		Value: "gb",
-		Name:  "United Kingdom",
-	},
-	{
-		Value: "us",
-		Name:  "United States",
-	},
-}
+var ErrNotFound = errors.New("podcast not found")
 
 type Store struct {
-	url string
-	hc  HttpClient
+	url    string
+	hc     HttpClient
+	flight flight
 }
 
 func NewStore(url string, g HttpClient) *Store {
@@ -37,16 +23,7 @@ func NewStore(url string, g HttpClient) *Store {
 		url = defaultUrl
 	}
 
-	return &Store{url, g}
-}
-
-func isSupportedRegion(v string) bool {
-	for _, r := range Regions {
-		if r.Value == v {
-			return true
-		}
-	}
-	return false
+	return &Store{url: url, hc: g}
 }
 
 type Podcast struct {
@@ -54,6 +31,29 @@ type Podcast struct {
 	Artist string
 	Name   string
 	Image  string
+	Genre  string
+	// Episode is the title of the episode for entries of episode charts
+	Episode string
+	// EpisodeCount, Released, Explicit and FeedUrl are only known for search results
+	EpisodeCount int
+	Released     time.Time
+	Explicit     bool
+	FeedUrl      string
+}
+
+// Episode is an episode found by SearchEpisodes.
+type Episode struct {
+	Id       string
+	Title    string
+	ShowId   string
+	Show     string
+	Image    string
+	Released time.Time
+	Duration time.Duration
+	// Url is the episode page on Apple Podcasts
+	Url string
+	// AudioUrl is the episode file, or its preview when iTunes has no file
+	AudioUrl string
 }
 
 type PodcastDetail struct {
@@ -74,9 +74,9 @@ type Review struct {
 	Content string
 	Rating  []struct{}
 	Date    time.Time
-}
-
-type Region struct {
-	Value string
-	Name  string
+	// VoteSum of VoteCount readers found the review helpful
+	VoteCount int
+	VoteSum   int
+	// Version is the app version the review was written in, often empty for podcasts
+	Version string
 }
@@ -5,15 +5,75 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"regexp"
 	"strconv"
 )
 
-func (s *Store) Top(region string) ([]*Podcast, error) {
+const (
+	ChartPodcasts ChartType = "toppodcasts"
+	ChartEpisodes ChartType = "topepisodes"
+
+	defaultChartLimit = 10
+	// MaxChartLimit is the largest chart storefronts serve
+	MaxChartLimit = 200
+)
+
+// ChartType tells top podcasts and top episodes apart.
+type ChartType string
+
+// Chart selects a top chart. The zero value is the top 10 podcasts of all genres.
+type Chart struct {
+	Type ChartType
+	// Genre is a genre id, zero means all genres
+	Genre int
+	// Limit is clamped to MaxChartLimit, zero means 10
+	Limit int
+}
+
+// Normalize fills in defaults and clamps the limit.
+func (c Chart) Normalize() Chart {
+	if c.Type != ChartEpisodes {
+		c.Type = ChartPodcasts
+	}
+	if _, ok := LookupGenre(c.Genre); !ok {
+		c.Genre = 0
+	}
+	if c.Limit <= 0 {
+		c.Limit = defaultChartLimit
+	}
+	if c.Limit > MaxChartLimit {
+		c.Limit = MaxChartLimit
+	}
+	return c
+}
+
+// Key identifies a chart in cache keys.
+func (c Chart) Key() string {
+	c = c.Normalize()
+	return fmt.Sprintf("%s:%d:%d", c.Type, c.Genre, c.Limit)
+}
+
+func (s *Store) Top(region string, chart Chart) ([]*Podcast, error) {
+	return share(&s.flight, "top:"+region+":"+chart.Key(), func() ([]*Podcast, error) {
+		return s.top(region, chart)
+	})
+}
+
+func (s *Store) top(region string, chart Chart) ([]*Podcast, error) {
 	if !isSupportedRegion(region) {
 		region = DefaultRegion
 	}
+	if reg, _ := LookupRegion(region); !reg.HasCharts() {
+		return nil, nil
+	}
+
+	chart = chart.Normalize()
+	u := fmt.Sprintf("%s/%s/rss/%s/limit=%d", s.url, region, chart.Type, chart.Limit)
+	if chart.Genre != 0 {
+		u += fmt.Sprintf("/genre=%d", chart.Genre)
+	}
 
-	resp, err := s.hc.Get(fmt.Sprintf("%s/%s/rss/toppodcasts/limit=10/json", s.url, region))
+	resp, err := s.hc.Get(u + "/json")
 	if err != nil {
 		return nil, err
 	}
@@ -39,6 +99,14 @@ func (s *Store) Top(region string) ([]*Podcast, error) {
 			Artist: p.Artist.Label,
 			Name:   p.Name.Label,
 			Image:  selectBiggestImage(p.Images),
+			Genre:  p.Category.Attributes.Label,
+		}
+
+		// Episode charts refer to podcasts as collections
+		if id := IdFromUrl(p.Collection.Link.Attributes.Href); id != "" {
+			podcasts[i].Id = id
+			podcasts[i].Name = p.Collection.Name.Label
+			podcasts[i].Episode = p.Name.Label
 		}
 	}
 
@@ -55,6 +123,7 @@ func selectBiggestImage(images []image) string {
 	for _, image := range images {
 		value, _ := strconv.Atoi(image.Attributes.Height)
 		if value > biggest {
+			biggest = value
 			result = image.Label
 		}
 	}
@@ -62,6 +131,17 @@ func selectBiggestImage(images []image) string {
 	return result
 }
 
+var collectionIdPattern = regexp.MustCompile(`/id(\d+)`)
+
+// IdFromUrl takes a podcast id from its url, such as https://podcasts.apple.com/us/podcast/name/id1612875889
+func IdFromUrl(href string) string {
+	m := collectionIdPattern.FindStringSubmatch(href)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
 type topResponse struct {
 	Feed topFeed `json:"feed"`
 }
@@ -72,10 +152,35 @@ type topFeed struct {
 }
 
 type podcast struct {
-	Id     id      `json:"id"`
-	Artist text    `json:"im:artist"`
-	Name   text    `json:"im:name"`
-	Images []image `json:"im:image"`
+	Id         id         `json:"id"`
+	Artist     text       `json:"im:artist"`
+	Name       text       `json:"im:name"`
+	Images     []image    `json:"im:image"`
+	Category   category   `json:"category"`
+	Collection collection `json:"im:collection"`
+}
+
+type category struct {
+	Attributes categoryAttributes `json:"attributes"`
+}
+
+type categoryAttributes struct {
+	Id    string `json:"im:id"`
+	Label string `json:"label"`
+}
+
+type collection struct {
+	Name text `json:"im:name"`
+	Link link `json:"link"`
+}
+
+type link struct {
+	Attributes linkAttributes `json:"attributes"`
+}
+
+type linkAttributes struct {
+	Rel  string `json:"rel"`
+	Href string `json:"href"`
 }
 
 type text struct {
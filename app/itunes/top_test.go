@@ -3,8 +3,10 @@ package itunes
 import (
 	"github.com/golang/mock/gomock"
 	"github.com/timiskhakov/podfinder/app/itunes/mock"
+	"io"
 	"net/http"
 	"os"
+	"strings"
 )
 
 func (s *StoreSuite) TestTop() {
@@ -12,13 +14,13 @@ func (s *StoreSuite) TestTop() {
 	s.NoError(err)
 	defer func() { _ = fh.Close() }()
 	g := mock.NewMockHttpClient(s.ctrl)
-	g.EXPECT().Get(gomock.Any()).Return(&http.Response{
+	g.EXPECT().Get("/us/rss/toppodcasts/limit=10/json").Return(&http.Response{
 		StatusCode: http.StatusOK,
 		Body:       fh,
 	}, nil)
-	store := Store{"", g}
+	store := Store{hc: g}
 
-	podcasts, err := store.Top("")
+	podcasts, err := store.Top("", Chart{})
 
 	s.NoError(err)
 	s.Equal(10, len(podcasts))
@@ -27,9 +29,60 @@ func (s *StoreSuite) TestTop() {
 		Artist: "HLN",
 		Name:   "Very Scary People",
 		Image:  "https://is2-ssl.mzstatic.com/image/thumb/Podcasts116/v4/18/69/79/18697926-b149-c6e0-d33c-ce6fb250efec/mza_17914905586066761253.jpg/170x170bb.png",
+		Genre:  "True Crime",
 	}, podcasts[0])
 }
 
+func (s *StoreSuite) TestTopChart() {
+	cases := []struct {
+		chart Chart
+		url   string
+	}{
+		{Chart{Limit: 50}, "/gb/rss/toppodcasts/limit=50/json"},
+		{Chart{Genre: 1303}, "/gb/rss/toppodcasts/limit=10/genre=1303/json"},
+		{Chart{Type: ChartEpisodes, Genre: 1, Limit: 500}, "/gb/rss/topepisodes/limit=200/json"},
+	}
+
+	for _, c := range cases {
+		s.Run(c.url, func() {
+			fh, err := os.Open("../testdata/top.json")
+			s.NoError(err)
+			defer func() { _ = fh.Close() }()
+			g := mock.NewMockHttpClient(s.ctrl)
+			g.EXPECT().Get(c.url).Return(&http.Response{StatusCode: http.StatusOK, Body: fh}, nil)
+			store := Store{hc: g}
+
+			podcasts, err := store.Top("gb", c.chart)
+
+			s.NoError(err)
+			s.Equal(10, len(podcasts))
+		})
+	}
+}
+
+func (s *StoreSuite) TestTopEpisodes() {
+	body := `{"feed": {"entry": [{
+		"id": {"attributes": {"im:id": "1000612345678"}},
+		"im:name": {"label": "Episode 1"},
+		"im:artist": {"label": "HLN"},
+		"im:collection": {
+			"im:name": {"label": "Very Scary People"},
+			"link": {"attributes": {"href": "https://podcasts.apple.com/us/podcast/very-scary-people/id1612875889?uo=2"}}
+		}
+	}]}}`
+	g := mock.NewMockHttpClient(s.ctrl)
+	g.EXPECT().Get(gomock.Any()).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}, nil)
+	store := Store{hc: g}
+
+	podcasts, err := store.Top("us", Chart{Type: ChartEpisodes})
+
+	s.NoError(err)
+	s.Equal(&Podcast{Id: "1612875889", Artist: "HLN", Name: "Very Scary People", Episode: "Episode 1"}, podcasts[0])
+}
+
 func (s *StoreSuite) TestSelectBiggestImage() {
 	images := []image{
 		{
@@ -53,4 +106,5 @@ func (s *StoreSuite) TestSelectBiggestImage() {
 	}
 
 	s.Equal("500x500.png", selectBiggestImage(images))
+	s.Equal("500x500.png", selectBiggestImage([]image{images[2], images[0], images[1]}))
 }
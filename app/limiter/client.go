@@ -0,0 +1,104 @@
+package limiter
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIP identifies clients by their IP address. Requests coming from trusted proxies are
+// attributed to the address the proxies forwarded them for, taken from the Forwarded header
+// or, if it is absent, X-Forwarded-For. The zero value trusts no proxies.
+type ClientIP struct {
+	trusted []netip.Prefix
+}
+
+// NewClientIP accepts trusted proxies as IP addresses or CIDR ranges.
+func NewClientIP(trusted []string) (*ClientIP, error) {
+	c := &ClientIP{}
+	for _, t := range trusted {
+		if !strings.Contains(t, "/") {
+			addr, err := netip.ParseAddr(t)
+			if err != nil {
+				return nil, err
+			}
+			c.trusted = append(c.trusted, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(t)
+		if err != nil {
+			return nil, err
+		}
+		c.trusted = append(c.trusted, prefix.Masked())
+	}
+
+	return c, nil
+}
+
+func (c *ClientIP) Key(r *http.Request) string {
+	addr, ok := parseAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+
+	// Walk the chain from the closest hop, the first address not belonging to a trusted proxy is the client
+	hops := forwardedFor(r)
+	for i := len(hops) - 1; i >= 0 && c.isTrusted(addr); i-- {
+		hop, ok := parseAddr(hops[i])
+		if !ok {
+			break
+		}
+		addr = hop
+	}
+
+	return addr.String()
+}
+
+func (c *ClientIP) isTrusted(addr netip.Addr) bool {
+	for _, p := range c.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the for= values of the Forwarded header or addresses from X-Forwarded-For.
+func forwardedFor(r *http.Request) []string {
+	var hops []string
+	for _, h := range r.Header.Values("Forwarded") {
+		for _, element := range strings.Split(h, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(k, "for") {
+					hops = append(hops, strings.Trim(v, `"`))
+				}
+			}
+		}
+	}
+	if len(hops) > 0 {
+		return hops
+	}
+
+	for _, h := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(h, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	return hops
+}
+
+// parseAddr accepts an IP address with or without a port, IPv6 addresses may be in brackets.
+func parseAddr(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+
+	addr, err := netip.ParseAddr(strings.Trim(s, "[]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -0,0 +1,62 @@
+package limiter
+
+import (
+	"net/http"
+)
+
+func (s *LimiterSuite) TestClientIP() {
+	ip, err := NewClientIP([]string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"})
+	s.NoError(err)
+
+	cases := []struct {
+		name      string
+		remote    string
+		forwarded string
+		xff       string
+		key       string
+	}{
+		{"direct", "203.0.113.7:5123", "", "", "203.0.113.7"},
+		{"untrusted proxy", "203.0.113.7:5123", "", "198.51.100.1", "203.0.113.7"},
+		{"trusted proxy", "10.1.2.3:5123", "", "198.51.100.1", "198.51.100.1"},
+		{"proxy chain", "10.1.2.3:5123", "", "198.51.100.1, 203.0.113.9, 192.168.1.1", "203.0.113.9"},
+		{"spoofed chain", "10.1.2.3:5123", "", "1.1.1.1, 198.51.100.1", "198.51.100.1"},
+		{"all trusted", "10.1.2.3:5123", "", "10.0.0.1", "10.0.0.1"},
+		{"invalid hop", "10.1.2.3:5123", "", "unknown", "10.1.2.3"},
+		{"forwarded", "10.1.2.3:5123", `for=198.51.100.1;proto=https, for="[2001:db8::1]:4711"`, "1.1.1.1", "198.51.100.1"},
+		{"forwarded ipv6", "[2001:db8::2]:443", `For="[2001:db8:cafe::17]:4711"`, "", "2001:db8:cafe::17"},
+		{"mapped ipv4", "[::ffff:10.0.0.1]:443", "", "198.51.100.1", "198.51.100.1"},
+	}
+
+	for _, c := range cases {
+		s.Run(c.name, func() {
+			r, err := http.NewRequest(http.MethodGet, "/", nil)
+			s.NoError(err)
+			r.RemoteAddr = c.remote
+			if c.forwarded != "" {
+				r.Header.Set("Forwarded", c.forwarded)
+			}
+			if c.xff != "" {
+				r.Header.Set("X-Forwarded-For", c.xff)
+			}
+
+			s.Equal(c.key, ip.Key(r))
+		})
+	}
+}
+
+func (s *LimiterSuite) TestClientIPWithoutProxies() {
+	r, err := http.NewRequest(http.MethodGet, "/", nil)
+	s.NoError(err)
+	r.RemoteAddr = "203.0.113.7:5123"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+
+	s.Equal("203.0.113.7", (&ClientIP{}).Key(r))
+}
+
+func (s *LimiterSuite) TestNewClientIPInvalid() {
+	_, err := NewClientIP([]string{"not an ip"})
+	s.Error(err)
+
+	_, err = NewClientIP([]string{"10.0.0.0/33"})
+	s.Error(err)
+}
@@ -0,0 +1,145 @@
+package limiter
+
+import (
+	"golang.org/x/time/rate"
+	"math"
+	"sync"
+	"time"
+)
+
+// Budget is a token bucket: Burst requests at once, refilled with one token every Every.
+// A zero Every never refills the bucket.
+type Budget struct {
+	Every time.Duration
+	Burst int
+}
+
+// Quota is the outcome of a single request along with what is left of the client's budget.
+type Quota struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	RetryAfter time.Duration
+	Reset      time.Duration
+}
+
+type KeyedConfig struct {
+	// Budgets sets a budget per route, requests to routes without a budget are always allowed.
+	Budgets map[string]Budget
+	// Idle is how long a client's bucket is kept after it has refilled completely.
+	Idle time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// Keyed tracks a separate token bucket for every client on every route.
+type Keyed struct {
+	budgets map[string]Budget
+	idle    time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+	swept   time.Time
+}
+
+type bucketKey struct {
+	route  string
+	client string
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	budget  Budget
+	seen    time.Time
+}
+
+func NewKeyed(config *KeyedConfig) *Keyed {
+	now := config.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	return &Keyed{
+		budgets: config.Budgets,
+		idle:    config.Idle,
+		now:     now,
+		buckets: make(map[bucketKey]*bucket),
+	}
+}
+
+func (k *Keyed) Allow(route, client string) Quota {
+	budget, ok := k.budgets[route]
+	if !ok {
+		return Quota{Allowed: true, Limit: -1, Remaining: -1}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	k.sweep(now)
+
+	key := bucketKey{route, client}
+	b, ok := k.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(every(budget.Every), budget.Burst), budget: budget}
+		k.buckets[key] = b
+	}
+	b.seen = now
+
+	allowed := b.limiter.AllowN(now, 1)
+	tokens := b.limiter.TokensAt(now)
+	q := Quota{
+		Allowed:   allowed,
+		Limit:     budget.Burst,
+		Remaining: max(0, int(math.Floor(tokens))),
+		Reset:     refill(budget, float64(budget.Burst)-tokens),
+	}
+	if !allowed {
+		q.RetryAfter = refill(budget, 1-tokens)
+	}
+
+	return q
+}
+
+// Len returns the number of buckets being tracked.
+func (k *Keyed) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return len(k.buckets)
+}
+
+// sweep drops buckets that are full and haven't been used for a while, a full bucket behaves
+// exactly like a new one so no client gains anything from it.
+func (k *Keyed) sweep(now time.Time) {
+	if now.Sub(k.swept) < k.idle {
+		return
+	}
+	k.swept = now
+
+	for key, b := range k.buckets {
+		if now.Sub(b.seen) >= k.idle && b.limiter.TokensAt(now) >= float64(b.budget.Burst) {
+			delete(k.buckets, key)
+		}
+	}
+}
+
+func every(d time.Duration) rate.Limit {
+	if d <= 0 {
+		return 0
+	}
+	return rate.Every(d)
+}
+
+// refill returns how long it takes for the budget to regain the given number of tokens.
+func refill(budget Budget, tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if budget.Every <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens * float64(budget.Every))
+}
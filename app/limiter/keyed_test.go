@@ -0,0 +1,121 @@
+package limiter
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"time"
+)
+
+type LimiterSuite struct {
+	suite.Suite
+	now time.Time
+}
+
+func TestLimiterSuite(t *testing.T) {
+	suite.Run(t, new(LimiterSuite))
+}
+
+func (s *LimiterSuite) SetupTest() {
+	s.now = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (s *LimiterSuite) newKeyed() *Keyed {
+	return NewKeyed(&KeyedConfig{
+		Budgets: map[string]Budget{
+			"search": {Every: 3 * time.Second, Burst: 2},
+			"api":    {Every: time.Second, Burst: 10},
+		},
+		Idle: time.Minute,
+		Now:  func() time.Time { return s.now },
+	})
+}
+
+func (s *LimiterSuite) TestAllow() {
+	k := s.newKeyed()
+
+	s.Equal(Quota{Allowed: true, Limit: 2, Remaining: 1, Reset: 3 * time.Second}, k.Allow("search", "a"))
+	s.Equal(Quota{Allowed: true, Limit: 2, Remaining: 0, Reset: 6 * time.Second}, k.Allow("search", "a"))
+	s.Equal(Quota{Allowed: false, Limit: 2, Remaining: 0, RetryAfter: 3 * time.Second, Reset: 6 * time.Second}, k.Allow("search", "a"))
+
+	s.now = s.now.Add(2 * time.Second)
+	q := k.Allow("search", "a")
+	s.False(q.Allowed)
+	s.Equal(time.Second, q.RetryAfter.Round(time.Millisecond))
+
+	s.now = s.now.Add(time.Second)
+	s.True(k.Allow("search", "a").Allowed)
+}
+
+func (s *LimiterSuite) TestAllowPerClient() {
+	k := s.newKeyed()
+
+	s.True(k.Allow("search", "a").Allowed)
+	s.True(k.Allow("search", "a").Allowed)
+	s.False(k.Allow("search", "a").Allowed)
+
+	s.True(k.Allow("search", "b").Allowed)
+}
+
+func (s *LimiterSuite) TestAllowPerRoute() {
+	k := s.newKeyed()
+
+	s.True(k.Allow("search", "a").Allowed)
+	s.True(k.Allow("search", "a").Allowed)
+	s.False(k.Allow("search", "a").Allowed)
+
+	q := k.Allow("api", "a")
+	s.True(q.Allowed)
+	s.Equal(10, q.Limit)
+	s.Equal(9, q.Remaining)
+}
+
+func (s *LimiterSuite) TestAllowUnlimitedRoute() {
+	k := s.newKeyed()
+
+	for i := 0; i < 100; i++ {
+		s.True(k.Allow("home", "a").Allowed)
+	}
+	s.Equal(0, k.Len())
+}
+
+func (s *LimiterSuite) TestAllowNoRefill() {
+	k := NewKeyed(&KeyedConfig{
+		Budgets: map[string]Budget{"search": {Burst: 0}},
+		Now:     func() time.Time { return s.now },
+	})
+
+	q := k.Allow("search", "a")
+
+	s.False(q.Allowed)
+	s.Equal(time.Duration(1<<63-1), q.RetryAfter)
+}
+
+func (s *LimiterSuite) TestSweep() {
+	k := s.newKeyed()
+
+	k.Allow("search", "a")
+	k.Allow("search", "b")
+	s.now = s.now.Add(30 * time.Second)
+	k.Allow("api", "a")
+	s.Equal(3, k.Len())
+
+	// Buckets of both clients have refilled but only "search" ones have been idle long enough
+	s.now = s.now.Add(40 * time.Second)
+	k.Allow("api", "c")
+	s.Equal(2, k.Len())
+}
+
+func (s *LimiterSuite) TestSweepKeepsDrainedBuckets() {
+	k := NewKeyed(&KeyedConfig{
+		Budgets: map[string]Budget{"search": {Every: time.Hour, Burst: 1}},
+		Idle:    time.Minute,
+		Now:     func() time.Time { return s.now },
+	})
+
+	k.Allow("search", "a")
+	s.now = s.now.Add(2 * time.Minute)
+	k.Allow("search", "b")
+
+	s.Equal(2, k.Len())
+	s.False(k.Allow("search", "a").Allowed)
+}
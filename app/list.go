@@ -0,0 +1,347 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"github.com/timiskhakov/podfinder/app/opml"
+	"golang.org/x/sync/errgroup"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	listCookie  = "list"
+	maxListSize = 100
+	maxOpmlSize = 1 << 20
+	// maxOpmlFeeds limits how many feeds of an uploaded file are resolved
+	maxOpmlFeeds       = 200
+	resolveConcurrency = 4
+	maxCandidates      = 5
+)
+
+const (
+	matchFound     = "matched"
+	matchAmbiguous = "ambiguous"
+	matchMissing   = "missing"
+	matchFailed    = "failed"
+	// matchLimited entries weren't resolved as the client ran out of its search budget
+	matchLimited = "limited"
+)
+
+// importEntry is a feed of an uploaded file resolved to a podcast of the directory.
+type importEntry struct {
+	Outline opml.Outline
+	Status  string
+	Podcast *itunes.Podcast
+	// Candidates are podcasts with the same name but a different feed, for ambiguous entries
+	Candidates []*itunes.Podcast
+}
+
+type apiImportEntry struct {
+	Title      string       `json:"title"`
+	FeedUrl    string       `json:"feedUrl"`
+	Status     string       `json:"status"`
+	Podcast    *apiPodcast  `json:"podcast"`
+	Candidates []apiPodcast `json:"candidates"`
+}
+
+func (a *App) handleList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ids := a.listIds(r)
+		if r.Method == http.MethodGet {
+			a.renderList(w, r, ids, http.StatusOK, "")
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		switch r.Form.Get("action") {
+		case "add":
+			for _, v := range r.Form["podcast"] {
+				id := podcastRef(v)
+				if id == "" {
+					a.renderList(w, r, ids, http.StatusBadRequest, fmt.Sprintf("%s isn't a podcast id or an Apple Podcasts link.", v))
+					return
+				}
+				ids = append(ids, id)
+			}
+			if ids = dedupe(ids); len(ids) > maxListSize {
+				a.renderList(w, r, a.listIds(r), http.StatusBadRequest, fmt.Sprintf("A list holds up to %d podcasts.", maxListSize))
+				return
+			}
+		case "remove":
+			ids = slices.DeleteFunc(ids, func(id string) bool { return id == r.Form.Get("podcast") })
+		case "clear":
+			ids = nil
+		}
+
+		if err := a.setCookieIds(w, listCookie, ids); err != nil {
+			a.renderList(w, r, a.listIds(r), http.StatusBadRequest, "The list is too long to keep, remove some podcasts first.")
+			return
+		}
+		http.Redirect(w, r, "/list", http.StatusSeeOther)
+	}
+}
+
+func (a *App) renderList(w http.ResponseWriter, r *http.Request, ids []string, status int, message string) {
+	type response struct {
+		Podcasts []*itunes.PodcastDetail
+		Error    string
+		Max      int
+	}
+
+	podcasts, err := a.lookupList(ids)
+	if err != nil {
+		log.Println(err)
+		message = "Podcasts of the list couldn't be fetched."
+	}
+	a.renderStatus(w, r, status, response{podcasts, message, maxListSize}, "list.html")
+}
+
+// lookupList fetches podcasts of a list in its order, podcasts that are gone are left out.
+func (a *App) lookupList(ids []string) ([]*itunes.PodcastDetail, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	details, err := a.store.LookupMany(ids)
+	var missing *itunes.MissingError
+	if err != nil && !errors.As(err, &missing) {
+		return nil, err
+	}
+
+	podcasts := make([]*itunes.PodcastDetail, 0, len(details))
+	for _, id := range ids {
+		if pod, ok := details[id]; ok {
+			podcasts = append(podcasts, pod)
+		}
+	}
+	return podcasts, nil
+}
+
+// handleExport downloads the list as OPML, the ids query parameter exports other podcasts than the ones in the list.
+func (a *App) handleExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ids := a.listIds(r)
+		if v := r.URL.Query().Get("ids"); v != "" {
+			ids = parseIds(strings.Split(v, ","))
+			ids = ids[:min(len(ids), maxListSize)]
+		}
+
+		podcasts, err := a.lookupList(ids)
+		if err != nil {
+			log.Println(err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		doc := &opml.Document{Title: "podfinder", Created: time.Now()}
+		for _, pod := range podcasts {
+			if pod.FeedUrl == "" {
+				continue
+			}
+			doc.Outlines = append(doc.Outlines, opml.Outline{
+				Text:    pod.Name,
+				Title:   pod.Name,
+				Type:    "rss",
+				XmlUrl:  pod.FeedUrl,
+				HtmlUrl: pod.Url,
+			})
+		}
+
+		w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="podfinder.opml"`)
+		if err := opml.Write(w, doc); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+}
+
+func (a *App) handleImport() http.HandlerFunc {
+	type response struct {
+		Entries   []*importEntry
+		Matched   []string
+		Ambiguous int
+		Missing   int
+		Limited   int
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, maxOpmlSize)
+		file, _, err := r.FormFile("opml")
+		if err != nil {
+			a.renderList(w, r, a.listIds(r), http.StatusBadRequest, "Choose an OPML file to import.")
+			return
+		}
+		defer func() { _ = file.Close() }()
+
+		doc, err := opml.Parse(file)
+		if err == nil && len(doc.Feeds()) > maxOpmlFeeds {
+			err = fmt.Errorf("the file has more than %d feeds", maxOpmlFeeds)
+		}
+		if err != nil {
+			a.renderList(w, r, a.listIds(r), http.StatusBadRequest, fmt.Sprintf("The file couldn't be imported, %v.", err))
+			return
+		}
+
+		resp := response{Entries: a.resolveFeeds(a.region(r), doc.Feeds(), a.budget(r, "search"))}
+		for _, e := range resp.Entries {
+			switch e.Status {
+			case matchFound:
+				resp.Matched = append(resp.Matched, e.Podcast.Id)
+			case matchAmbiguous:
+				resp.Ambiguous++
+			case matchLimited:
+				resp.Limited++
+			default:
+				resp.Missing++
+			}
+		}
+		resp.Matched = dedupe(resp.Matched)
+		a.render(w, r, resp, "import.html")
+	}
+}
+
+func (a *App) handleApiImport() http.HandlerFunc {
+	type response struct {
+		Entries []apiImportEntry `json:"entries"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc, err := opml.Parse(http.MaxBytesReader(w, r.Body, maxOpmlSize))
+		if err != nil {
+			writeApiError(w, http.StatusBadRequest, "request body must be an opml document")
+			return
+		}
+		feeds := doc.Feeds()
+		if len(feeds) > maxOpmlFeeds {
+			writeApiError(w, http.StatusBadRequest, fmt.Sprintf("opml document must have at most %d feeds", maxOpmlFeeds))
+			return
+		}
+
+		entries := a.resolveFeeds(a.region(r), feeds, a.budget(r, "search"))
+		result := make([]apiImportEntry, len(entries))
+		for i, e := range entries {
+			result[i] = apiImportEntry{
+				Title:      e.Outline.Name(),
+				FeedUrl:    e.Outline.XmlUrl,
+				Status:     e.Status,
+				Candidates: toApiPodcasts(e.Candidates),
+			}
+			if e.Podcast != nil {
+				pod := toApiPodcasts([]*itunes.Podcast{e.Podcast})[0]
+				result[i].Podcast = &pod
+			}
+		}
+		writeJson(w, http.StatusOK, response{result})
+	}
+}
+
+// resolveFeeds finds podcasts of feeds, a feed failing to resolve doesn't stop the others.
+// Every feed takes a request from the budget, feeds past it are left unresolved for a later import.
+func (a *App) resolveFeeds(region string, feeds []opml.Outline, budget func() bool) []*importEntry {
+	entries := make([]*importEntry, len(feeds))
+	g := errgroup.Group{}
+	g.SetLimit(resolveConcurrency)
+	for i, feed := range feeds {
+		if !budget() {
+			entries[i] = &importEntry{Outline: feed, Status: matchLimited}
+			continue
+		}
+		g.Go(func() error {
+			entry, err := a.resolveFeed(region, feed)
+			if err != nil {
+				log.Println(err)
+				entry = &importEntry{Outline: feed, Status: matchFailed}
+			}
+			entries[i] = entry
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return entries
+}
+
+// resolveFeed looks up a feed by the Apple Podcasts link of its outline, then searches
+// for its name and matches results by feed url. Results with the same name but another
+// feed make the entry ambiguous.
+func (a *App) resolveFeed(region string, feed opml.Outline) (*importEntry, error) {
+	entry := &importEntry{Outline: feed, Status: matchMissing}
+
+	if id := itunes.IdFromUrl(feed.HtmlUrl); id != "" {
+		pod, err := a.store.Lookup(id)
+		if err == nil {
+			entry.Status = matchFound
+			entry.Podcast = &itunes.Podcast{Id: pod.Id, Artist: pod.Artist, Name: pod.Name, Image: pod.Image, FeedUrl: pod.FeedUrl}
+			return entry, nil
+		}
+		if !errors.Is(err, itunes.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	name := feed.Name()
+	if name == "" {
+		return entry, nil
+	}
+	results, err := a.store.Search(region, itunes.Query{Term: name, Limit: itunes.MaxSearchLimit})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, pod := range results.Podcasts {
+		if sameFeed(pod.FeedUrl, feed.XmlUrl) {
+			entry.Status = matchFound
+			entry.Podcast = pod
+			return entry, nil
+		}
+		if strings.EqualFold(strings.TrimSpace(pod.Name), name) && len(entry.Candidates) < maxCandidates {
+			entry.Candidates = append(entry.Candidates, pod)
+		}
+	}
+	if len(entry.Candidates) > 0 {
+		entry.Status = matchAmbiguous
+	}
+
+	return entry, nil
+}
+
+// sameFeed compares feed urls ignoring the scheme, a www. prefix, case of the host and a trailing slash.
+func sameFeed(a, b string) bool {
+	normalize := func(v string) string {
+		u, err := url.Parse(strings.TrimSpace(v))
+		if err != nil || u.Host == "" {
+			return ""
+		}
+		host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+		return host + strings.TrimSuffix(u.Path, "/") + "?" + u.RawQuery
+	}
+
+	na := normalize(a)
+	return na != "" && na == normalize(b)
+}
+
+// podcastRef takes a podcast id from an id or an Apple Podcasts link.
+func podcastRef(v string) string {
+	v = strings.TrimSpace(v)
+	if idPattern.MatchString(v) {
+		return v
+	}
+	return itunes.IdFromUrl(v)
+}
+
+// listIds reads the list of podcast ids kept in a cookie.
+func (a *App) listIds(r *http.Request) []string {
+	ids := a.cookieIds(r, listCookie)
+	return ids[:min(len(ids), maxListSize)]
+}
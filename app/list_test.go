@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/timiskhakov/podfinder/app/limiter"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+func (s *AppSuite) TestList() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	req, err := http.NewRequest(http.MethodGet, s.appServer.URL+"/list", nil)
+	s.NoError(err)
+	req.AddCookie(s.signedCookie("list", "811377230.bad.1200361736"))
+
+	resp, err := http.DefaultClient.Do(req)
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(string(body), `<a href="/podcast/811377230">Hello Internet</a>`)
+	s.Contains(string(body), "1 of up to 100 podcasts")
+	s.Contains(string(body), `href="/list/opml"`)
+}
+
+func (s *AppSuite) TestListAdd() {
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	form := url.Values{"action": {"add"}, "podcast": {"https://podcasts.apple.com/us/podcast/hello-internet/id811377230", "1200361736"}}
+	req, err := http.NewRequest(http.MethodPost, s.appServer.URL+"/list", strings.NewReader(form.Encode()))
+	s.NoError(err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(s.signedCookie("list", "1200361736.1"))
+
+	resp, err := client.Do(req)
+	s.NoError(err)
+	_ = resp.Body.Close()
+
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	s.Equal("/list", resp.Header.Get("Location"))
+	s.Equal("1200361736.1.811377230", s.cookieValue(resp.Cookies()[0]))
+
+	form = url.Values{"action": {"remove"}, "podcast": {"1"}}
+	req, err = http.NewRequest(http.MethodPost, s.appServer.URL+"/list", strings.NewReader(form.Encode()))
+	s.NoError(err)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(resp.Cookies()[0])
+
+	resp, err = client.Do(req)
+	s.NoError(err)
+	_ = resp.Body.Close()
+
+	s.Equal("1200361736.811377230", s.cookieValue(resp.Cookies()[0]))
+}
+
+func (s *AppSuite) TestListAddInvalid() {
+	resp, err := http.PostForm(s.appServer.URL+"/list", url.Values{"action": {"add"}, "podcast": {"hello"}})
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+	s.Contains(string(body), "hello isn&#39;t a podcast id or an Apple Podcasts link.")
+}
+
+func (s *AppSuite) TestExport() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+
+	resp, err := http.Get(s.appServer.URL + "/list/opml?ids=811377230")
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(`attachment; filename="podfinder.opml"`, resp.Header.Get("Content-Disposition"))
+	s.Contains(string(body), `<outline text="Hello Internet" title="Hello Internet" type="rss" xmlUrl="http://www.hellointernet.fm/podcast?format=rss" htmlUrl="https://podcasts.apple.com/us/podcast/hello-internet/id811377230?uo=4"></outline>`)
+}
+
+func (s *AppSuite) uploadOpml(path string) *http.Response {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("opml", "podcasts.opml")
+	s.NoError(err)
+	f, err := os.Open(path)
+	s.NoError(err)
+	defer func() { _ = f.Close() }()
+	_, err = io.Copy(fw, f)
+	s.NoError(err)
+	s.NoError(mw.Close())
+
+	resp, err := http.Post(s.appServer.URL+"/list/import", mw.FormDataContentType(), &buf)
+	s.NoError(err)
+	return resp
+}
+
+func (s *AppSuite) TestImport() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	searches := s.serveCounted("/search", "./testdata/search.json")
+
+	resp := s.uploadOpml("./testdata/podcasts.opml")
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(int32(4), searches.Load())
+	s.Contains(string(body), "2 matched · 1 ambiguous · 2 not in the directory")
+	s.Contains(string(body), `<input type="hidden" name="podcast" value="811377230"><input type="hidden" name="podcast" value="519611048">`)
+	s.Contains(string(body), `the feed differs from
+          <a href="/podcast/811377230">Hello Internet</a>`)
+}
+
+func (s *AppSuite) TestImportLimit() {
+	s.serveCounted("/lookup", "./testdata/lookup.json")
+	searches := s.serveCounted("/search", "./testdata/search.json")
+	s.app.isLimiterEnabled = true
+	s.app.limiter = limiter.NewKeyed(&limiter.KeyedConfig{
+		Budgets: map[string]limiter.Budget{"search": {Every: time.Hour, Burst: 3}},
+	})
+
+	resp := s.uploadOpml("./testdata/podcasts.opml")
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	// The upload itself takes a request, the first two feeds take the rest
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(int32(1), searches.Load())
+	s.Contains(string(body), "2 matched · 0 ambiguous · 0 not in the directory · 3 left for later")
+	s.Equal(3, strings.Count(string(body), "the search limit was reached"))
+}
+
+func (s *AppSuite) TestImportInvalid() {
+	resp := s.uploadOpml("./testdata/feed.xml")
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusBadRequest, resp.StatusCode)
+	s.Contains(string(body), "The file couldn&#39;t be imported, not an opml document")
+}
+
+func (s *AppSuite) TestApiImport() {
+	s.serveCounted("/search", "./testdata/search.json")
+	var body struct {
+		Entries []apiImportEntry `json:"entries"`
+	}
+	opml := `<opml version="2.0"><body>
+		<outline text="hello, we're from the internet" xmlUrl="http://feed.podbean.com/hwfti/feed.xml/"/>
+		<outline text="Unknown Show" xmlUrl="https://example.com/unknown.xml"/>
+	</body></opml>`
+
+	resp, err := http.Post(s.appServer.URL+"/api/v1/opml/import", "text/x-opml", strings.NewReader(opml))
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	s.NoError(json.NewDecoder(resp.Body).Decode(&body))
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Len(body.Entries, 2)
+	s.Equal("matched", body.Entries[0].Status)
+	s.Equal("519611048", body.Entries[0].Podcast.Id)
+	s.Equal("missing", body.Entries[1].Status)
+	s.Nil(body.Entries[1].Podcast)
+	s.Empty(body.Entries[1].Candidates)
+}
+
+func (s *AppSuite) TestSameFeed() {
+	s.True(sameFeed("http://www.hellointernet.fm/podcast?format=rss", "https://HelloInternet.fm/podcast/?format=rss"))
+	s.False(sameFeed("http://www.hellointernet.fm/podcast?format=rss", "http://www.hellointernet.fm/podcast?format=atom"))
+	s.False(sameFeed("", ""))
+}
@@ -0,0 +1,84 @@
+// Package mail sends plain text emails.
+package mail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+var ErrInvalidHeader = errors.New("header can't contain line breaks")
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type SMTPConfig struct {
+	// Addr is the host:port of the server
+	Addr string
+	From string
+	// Username and Password authenticate with PLAIN auth if set, which needs TLS for servers other than localhost
+	Username string
+	Password string
+}
+
+// SMTP sends messages through an SMTP server.
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTP(config *SMTPConfig) *SMTP {
+	s := &SMTP{addr: config.Addr, from: config.From}
+	if config.Username != "" {
+		host, _, _ := net.SplitHostPort(config.Addr)
+		s.auth = smtp.PlainAuth("", config.Username, config.Password, host)
+	}
+	return s
+}
+
+func (s *SMTP) Send(m Message) error {
+	data, err := s.format(m, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{m.To}, data)
+}
+
+// format builds an RFC 5322 message with CRLF line endings.
+func (s *SMTP) format(m Message, date time.Time) ([]byte, error) {
+	for _, v := range []string{s.from, m.To, m.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "From: %s\r\n", s.from)
+	_, _ = fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	_, _ = fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	_, _ = fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes(), nil
+}
+
+// Log writes messages to the log instead of sending them, for development without a mail server.
+type Log struct{}
+
+func (Log) Send(m Message) error {
+	log.Printf("mail to %s: %s\n%s", m.To, m.Subject, m.Body)
+	return nil
+}
@@ -0,0 +1,65 @@
+package mail
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/timiskhakov/podfinder/app/mail/mailtest"
+	"testing"
+	"time"
+)
+
+type MailSuite struct {
+	suite.Suite
+	server *mailtest.Server
+}
+
+func TestMailSuite(t *testing.T) {
+	suite.Run(t, new(MailSuite))
+}
+
+func (s *MailSuite) SetupTest() {
+	s.server = mailtest.NewServer()
+}
+
+func (s *MailSuite) TearDownTest() {
+	s.server.Close()
+}
+
+func (s *MailSuite) TestSend() {
+	m := NewSMTP(&SMTPConfig{Addr: s.server.Addr, From: "podfinder@example.com"})
+
+	err := m.Send(Message{To: "grey@example.com", Subject: "Sign in to podfinder — link", Body: "Hello\n.hidden dot\nBye"})
+
+	s.NoError(err)
+	messages := s.server.Messages()
+	s.Len(messages, 1)
+	s.Equal("podfinder@example.com", messages[0].From)
+	s.Equal([]string{"grey@example.com"}, messages[0].To)
+	s.Contains(messages[0].Data, "To: grey@example.com\r\n")
+	s.Contains(messages[0].Data, "Subject: =?utf-8?q?Sign_in_to_podfinder_=E2=80=94_link?=\r\n")
+	s.Contains(messages[0].Data, "\r\n\r\nHello\r\n.hidden dot\r\nBye")
+}
+
+func (s *MailSuite) TestSendInvalidHeader() {
+	m := NewSMTP(&SMTPConfig{Addr: s.server.Addr, From: "podfinder@example.com"})
+
+	err := m.Send(Message{To: "grey@example.com\r\nBcc: all@example.com", Subject: "Hi"})
+
+	s.ErrorIs(err, ErrInvalidHeader)
+	s.Empty(s.server.Messages())
+}
+
+func (s *MailSuite) TestFormat() {
+	m := NewSMTP(&SMTPConfig{From: "podfinder@example.com"})
+
+	data, err := m.format(Message{To: "grey@example.com", Subject: "Hi", Body: "Line\r\nLine"}, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	s.NoError(err)
+	s.Equal("From: podfinder@example.com\r\n"+
+		"To: grey@example.com\r\n"+
+		"Subject: Hi\r\n"+
+		"Date: Fri, 01 Mar 2024 12:00:00 +0000\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n"+
+		"Content-Transfer-Encoding: 8bit\r\n\r\n"+
+		"Line\r\nLine", string(data))
+}
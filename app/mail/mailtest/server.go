@@ -0,0 +1,150 @@
+// Package mailtest provides an SMTP server for tests, which keeps messages instead of delivering them.
+package mailtest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Received is a message accepted by the server, Data is as the client sent it with dots unstuffed.
+type Received struct {
+	From string
+	To   []string
+	Data string
+}
+
+// Server speaks enough SMTP for net/smtp clients, it doesn't offer TLS or authentication.
+type Server struct {
+	// Addr is the host:port the server listens on
+	Addr string
+
+	ln       net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	messages []Received
+}
+
+// NewServer starts a server on a free port of the loopback interface.
+func NewServer() *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("mailtest: can't listen: " + err.Error())
+	}
+
+	s := &Server{Addr: ln.Addr().String(), ln: ln}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Messages returns messages received so far.
+func (s *Server) Messages() []Received {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Received(nil), s.messages...)
+}
+
+// Close stops the server and waits for open connections to end.
+func (s *Server) Close() {
+	_ = s.ln.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	reply := func(line string) bool {
+		_, err := conn.Write([]byte(line + "\r\n"))
+		return err == nil
+	}
+
+	if !reply("220 mailtest ESMTP") {
+		return
+	}
+	var msg Received
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb, arg, _ := strings.Cut(line, " ")
+
+		var ok bool
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			ok = reply("250-mailtest") && reply("250 8BITMIME")
+		case "HELO", "NOOP":
+			ok = reply("250 OK")
+		case "RSET":
+			msg = Received{}
+			ok = reply("250 OK")
+		case "MAIL":
+			msg = Received{From: address(arg)}
+			ok = reply("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, address(arg))
+			ok = reply("250 OK")
+		case "DATA":
+			if !reply("354 End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := readData(r)
+			if err != nil {
+				return
+			}
+			msg.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = Received{}
+			ok = reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			ok = reply("502 Command not implemented")
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// address takes the address of FROM:<a@b> or TO:<a@b>, ignoring parameters.
+func address(arg string) string {
+	_, v, _ := strings.Cut(arg, ":")
+	v, _, _ = strings.Cut(strings.TrimSpace(v), " ")
+	return strings.Trim(v, "<>")
+}
+
+func readData(r *bufio.Reader) (string, error) {
+	var b strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if line == ".\r\n" || line == ".\n" {
+			return b.String(), nil
+		}
+		b.WriteString(strings.TrimPrefix(line, "."))
+	}
+}
@@ -2,64 +2,95 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"github.com/timiskhakov/podfinder/app/accounts"
+	"github.com/timiskhakov/podfinder/app/charts"
+	"github.com/timiskhakov/podfinder/app/config"
+	"github.com/timiskhakov/podfinder/app/feed"
+	"github.com/timiskhakov/podfinder/app/geoip"
+	"github.com/timiskhakov/podfinder/app/inbox"
 	"github.com/timiskhakov/podfinder/app/itunes"
+	"github.com/timiskhakov/podfinder/app/limiter"
+	"github.com/timiskhakov/podfinder/app/mail"
+	"github.com/timiskhakov/podfinder/app/reviews"
+	"github.com/timiskhakov/podfinder/app/webhooks"
 	"golang.org/x/sync/errgroup"
-	"golang.org/x/time/rate"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 )
 
-const port = 3000
-
 func main() {
+	cfg, print, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if print {
+		if err := cfg.Write(os.Stdout); err != nil {
+			_, _ = fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	ctx := context.Background()
-	if err := run(ctx); err != nil {
+	if err := run(ctx, cfg); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
-func run(ctx context.Context) error {
-	t := http.DefaultTransport.(*http.Transport).Clone()
-	t.MaxIdleConns = 100
-	t.MaxConnsPerHost = 100
-	t.MaxIdleConnsPerHost = 100
+// worker runs in the background until the context is done.
+type worker func(ctx context.Context) error
 
-	app, err := NewApp(&AppConfig{
-		Store:            itunes.NewStore("", &http.Client{Timeout: 2 * time.Second, Transport: t}),
-		IsLimiterEnabled: true,
-		Limiter:          rate.NewLimiter(rate.Every(time.Minute), 20),
-	})
+func run(ctx context.Context, cfg *config.Config) error {
+	app, workers, err := newApp(cfg)
 	if err != nil {
 		return err
 	}
 
+	port := cfg.Server.Port
 	srv := http.Server{
 		Addr:              fmt.Sprintf(":%d", port),
 		Handler:           app,
-		IdleTimeout:       30 * time.Second,
-		ReadTimeout:       1 * time.Second,
-		ReadHeaderTimeout: 2 * time.Second,
-		WriteTimeout:      5 * time.Second,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
 	}
 
+	// A signal or any part failing stops the server and every worker
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	errs, ctx := errgroup.WithContext(ctx)
 	errs.Go(func() error {
 		log.Printf("starting server: %d\n", port)
-		return srv.ListenAndServe()
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
 	})
+	for _, w := range workers {
+		errs.Go(func() error {
+			return w(ctx)
+		})
+	}
 	errs.Go(func() error {
-		sigs := make(chan os.Signal, 1)
-		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
-		<-sigs
+		<-ctx.Done()
 
 		log.Printf("shutting down server: %d\n", port)
-		tc, cancel := context.WithTimeout(ctx, 5*time.Second)
+		tc, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 		defer cancel()
 
 		return srv.Shutdown(tc)
@@ -67,3 +98,202 @@ func run(ctx context.Context) error {
 
 	return errs.Wait()
 }
+
+func newApp(cfg *config.Config) (*App, []worker, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConns = cfg.Http.MaxIdleConns
+	t.MaxConnsPerHost = cfg.Http.MaxConnsPerHost
+	t.MaxIdleConnsPerHost = cfg.Http.MaxIdleConnsPerHost
+
+	feeds := feed.NewClient(&http.Client{Timeout: cfg.Http.FeedTimeout, Transport: t})
+	// Collectors stamp what they fetch with the time of the collection, so they skip the cache
+	client := itunes.NewStore(cfg.Itunes.Url, &http.Client{Timeout: cfg.Http.Timeout, Transport: t})
+	var store Store = client
+	if cfg.Cache.Enabled {
+		store = NewCachedStore(store, &CacheConfig{
+			TopTTL:     cfg.Cache.TopTTL,
+			SearchTTL:  cfg.Cache.SearchTTL,
+			LookupTTL:  cfg.Cache.LookupTTL,
+			ReviewsTTL: cfg.Cache.ReviewsTTL,
+			Stale:      cfg.Cache.Stale,
+			Size:       cfg.Cache.Size,
+		})
+	}
+
+	budgets := make(map[string]limiter.Budget, len(cfg.Limiter.Routes))
+	for name, route := range cfg.Limiter.Routes {
+		budgets[name] = limiter.Budget{Every: route.Every, Burst: route.Burst}
+	}
+	clients, err := limiter.NewClientIP(cfg.Limiter.TrustedProxies)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.Cookies.Key == "" {
+		log.Println("cookies.key isn't set, cookies signed with a random key won't survive a restart")
+	}
+	cookies, err := NewSignedCookies([]byte(cfg.Cookies.Key))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		accs   *accounts.Accounts
+		mailer Mailer
+	)
+	if cfg.Accounts.Enabled {
+		accs, err = accounts.Open(&accounts.Config{
+			File:       cfg.Accounts.File,
+			LinkTTL:    cfg.Accounts.LinkTTL,
+			SessionTTL: cfg.Accounts.SessionTTL,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if cfg.Accounts.Smtp.Addr == "" {
+			log.Println("accounts.smtp.addr isn't set, sign in links are logged instead of sent")
+			mailer = mail.Log{}
+		} else {
+			mailer = mail.NewSMTP(&mail.SMTPConfig{
+				Addr:     cfg.Accounts.Smtp.Addr,
+				From:     cfg.Accounts.Smtp.From,
+				Username: cfg.Accounts.Smtp.Username,
+				Password: cfg.Accounts.Smtp.Password,
+			})
+		}
+	}
+
+	regions := []RegionSource{QueryRegion{}}
+	if accs != nil {
+		regions = append(regions, AccountRegion{accs, cookies})
+	}
+	regions = append(regions, CookieRegion{cookies})
+	if cfg.Geoip.Database != "" {
+		db, err := geoip.Open(cfg.Geoip.Database)
+		if err != nil {
+			return nil, nil, err
+		}
+		regions = append(regions, &GeoRegion{Countries: db, Clients: clients})
+	}
+	regions = append(regions, LanguageRegion{})
+
+	var assets fs.FS
+	if cfg.Assets.Dev {
+		assets = os.DirFS(cfg.Assets.Dir)
+	}
+
+	var (
+		history ChartHistory
+		workers []worker
+	)
+	var (
+		hooks          Webhooks
+		chartListener  charts.Listener
+		reviewListener reviews.Listener
+	)
+	if accs != nil && cfg.Webhooks.Enabled {
+		w, err := webhooks.Open(&webhooks.Config{File: cfg.Webhooks.File, Users: accs})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		dialer := &net.Dialer{Timeout: cfg.Webhooks.Timeout}
+		if !cfg.Webhooks.AllowPrivate {
+			dialer.Control = webhooks.PublicOnly
+		}
+		wt := http.DefaultTransport.(*http.Transport).Clone()
+		// A proxy would connect on behalf of hooks, past the check of their addresses
+		wt.Proxy = nil
+		wt.DialContext = dialer.DialContext
+		sender := webhooks.NewSender(&webhooks.SenderConfig{
+			Webhooks: w,
+			Client: &http.Client{
+				Timeout:   cfg.Webhooks.Timeout,
+				Transport: wt,
+				CheckRedirect: func(*http.Request, []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			},
+			Attempts:    cfg.Webhooks.Attempts,
+			Backoff:     cfg.Webhooks.Backoff,
+			Concurrency: cfg.Webhooks.Concurrency,
+		})
+		hooks, chartListener, reviewListener = w, w, w
+		workers = append(workers, sender.Run)
+	}
+	if cfg.Charts.Enabled {
+		h := charts.NewHistory(&charts.HistoryConfig{Dir: cfg.Charts.Dir, Retention: cfg.Charts.Retention})
+		collector := charts.NewCollector(&charts.CollectorConfig{
+			Store:       client,
+			History:     h,
+			Every:       cfg.Charts.Every,
+			Concurrency: cfg.Charts.Concurrency,
+			Listener:    chartListener,
+		})
+		history, workers = h, append(workers, collector.Run)
+	}
+	var archive ReviewArchive
+	if cfg.Archive.Enabled {
+		a := reviews.NewArchive(&reviews.ArchiveConfig{Dir: cfg.Archive.Dir})
+		var watchlist reviews.Watchlist
+		if accs != nil {
+			watchlist = accs
+		}
+		collector := reviews.NewCollector(&reviews.CollectorConfig{
+			Store:       client,
+			Archive:     a,
+			Podcasts:    cfg.Archive.Podcasts,
+			Watchlist:   watchlist,
+			Regions:     cfg.Archive.Regions,
+			Every:       cfg.Archive.Every,
+			Concurrency: cfg.Archive.Concurrency,
+			Listener:    reviewListener,
+		})
+		archive, workers = a, append(workers, collector.Run)
+	}
+
+	var users Accounts
+	var episodes Inbox
+	if accs != nil {
+		users = accs
+		if cfg.Inbox.Enabled {
+			in, err := inbox.Open(&inbox.Config{Dir: cfg.Inbox.Dir})
+			if err != nil {
+				return nil, nil, err
+			}
+			poller := inbox.NewPoller(&inbox.PollerConfig{
+				Store:         store,
+				Feeds:         feeds,
+				Inbox:         in,
+				Subscriptions: accs,
+				Every:         cfg.Inbox.Every,
+				MaxBackoff:    cfg.Inbox.MaxBackoff,
+				Concurrency:   cfg.Inbox.Concurrency,
+			})
+			episodes, workers = in, append(workers, poller.Run)
+		}
+	}
+	app, err := NewApp(&AppConfig{
+		Store:            store,
+		Feeds:            feeds,
+		IsLimiterEnabled: cfg.Limiter.Enabled,
+		Limiter:          limiter.NewKeyed(&limiter.KeyedConfig{Budgets: budgets, Idle: cfg.Limiter.Idle}),
+		Clients:          clients,
+		Regions:          regions,
+		Accounts:         users,
+		Inbox:            episodes,
+		Webhooks:         hooks,
+		Cookies:          cookies,
+		Assets:           assets,
+		Dev:              cfg.Assets.Dev,
+		History:          history,
+		Archive:          archive,
+		Mailer:           mailer,
+		Url:              cfg.Accounts.Url,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return app, workers, nil
+}
@@ -0,0 +1,159 @@
+// Package opml reads and writes OPML 2.0 subscription lists, see: http://opml.org/spec2.opml
+package opml
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrNotOpml = errors.New("not an opml document")
+
+// Document is a subscription list.
+type Document struct {
+	Title    string
+	Created  time.Time
+	Outlines []Outline
+}
+
+// Outline is a feed, or a folder of outlines when it has no XmlUrl.
+type Outline struct {
+	Text    string
+	Title   string
+	Type    string
+	XmlUrl  string
+	HtmlUrl string
+	// Outlines are nested in a folder
+	Outlines []Outline
+}
+
+// Name returns the title of the outline, or its text when it has none.
+func (o Outline) Name() string {
+	if o.Title != "" {
+		return o.Title
+	}
+	return o.Text
+}
+
+// Feeds returns every outline with a feed url, folders are flattened.
+func (d *Document) Feeds() []Outline {
+	var feeds []Outline
+	var walk func(outlines []Outline)
+	walk = func(outlines []Outline) {
+		for _, o := range outlines {
+			if o.XmlUrl != "" {
+				feeds = append(feeds, o)
+			}
+			walk(o.Outlines)
+		}
+	}
+	walk(d.Outlines)
+	return feeds
+}
+
+type opml struct {
+	XMLName xml.Name     `xml:"opml"`
+	Version string       `xml:"version,attr"`
+	Head    head         `xml:"head"`
+	Body    []xmlOutline `xml:"body>outline"`
+}
+
+type head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+// xmlOutline keeps attributes as they are, players disagree on their case, such as xmlUrl and xmlurl.
+type xmlOutline struct {
+	Attrs    []xml.Attr   `xml:",any,attr"`
+	Outlines []xmlOutline `xml:"outline"`
+}
+
+func (o xmlOutline) attr(name string) string {
+	for _, a := range o.Attrs {
+		if strings.EqualFold(a.Name.Local, name) {
+			return strings.TrimSpace(a.Value)
+		}
+	}
+	return ""
+}
+
+func (o xmlOutline) outline() Outline {
+	result := Outline{
+		Text:    o.attr("text"),
+		Title:   o.attr("title"),
+		Type:    o.attr("type"),
+		XmlUrl:  o.attr("xmlUrl"),
+		HtmlUrl: o.attr("htmlUrl"),
+	}
+	for _, child := range o.Outlines {
+		result.Outlines = append(result.Outlines, child.outline())
+	}
+	return result
+}
+
+// Parse reads an OPML document of any version.
+func Parse(r io.Reader) (*Document, error) {
+	var o opml
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	if err := d.Decode(&o); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotOpml, err)
+	}
+
+	doc := &Document{Title: strings.TrimSpace(o.Head.Title)}
+	if created, err := time.Parse(time.RFC1123Z, strings.TrimSpace(o.Head.DateCreated)); err == nil {
+		doc.Created = created
+	} else if created, err := time.Parse(time.RFC1123, strings.TrimSpace(o.Head.DateCreated)); err == nil {
+		doc.Created = created
+	}
+	for _, outline := range o.Body {
+		doc.Outlines = append(doc.Outlines, outline.outline())
+	}
+
+	return doc, nil
+}
+
+// Write writes the document as OPML 2.0.
+func Write(w io.Writer, doc *Document) error {
+	o := opml{Version: "2.0", Head: head{Title: doc.Title}}
+	if !doc.Created.IsZero() {
+		o.Head.DateCreated = doc.Created.UTC().Format(time.RFC1123Z)
+	}
+	for _, outline := range doc.Outlines {
+		o.Body = append(o.Body, toXml(outline))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(o); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func toXml(o Outline) xmlOutline {
+	var result xmlOutline
+	for _, a := range [][2]string{
+		{"text", o.Name()},
+		{"title", o.Title},
+		{"type", o.Type},
+		{"xmlUrl", o.XmlUrl},
+		{"htmlUrl", o.HtmlUrl},
+	} {
+		if a[1] != "" {
+			result.Attrs = append(result.Attrs, xml.Attr{Name: xml.Name{Local: a[0]}, Value: a[1]})
+		}
+	}
+	for _, child := range o.Outlines {
+		result.Outlines = append(result.Outlines, toXml(child))
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package opml
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type OpmlSuite struct {
+	suite.Suite
+}
+
+func TestOpmlSuite(t *testing.T) {
+	suite.Run(t, new(OpmlSuite))
+}
+
+func (s *OpmlSuite) TestParse() {
+	f, err := os.Open("../testdata/podcasts.opml")
+	s.NoError(err)
+	defer func() { _ = f.Close() }()
+
+	doc, err := Parse(f)
+
+	s.NoError(err)
+	s.Equal("Podcasts", doc.Title)
+	s.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), doc.Created.UTC())
+	feeds := doc.Feeds()
+	s.Len(feeds, 5)
+	s.Equal("Hello Internet", feeds[0].Name())
+	s.Equal("http://www.hellointernet.fm/podcast?format=rss", feeds[0].XmlUrl)
+	s.Equal("https://podcasts.apple.com/us/podcast/hello-internet/id811377230", feeds[0].HtmlUrl)
+	// Attribute names are matched regardless of case
+	s.Equal("https://feed.podbean.com/hwfti/feed.xml", feeds[1].XmlUrl)
+	s.Equal("Nested", feeds[4].Name())
+}
+
+func (s *OpmlSuite) TestParseInvalid() {
+	for _, input := range []string{"", "not xml", `<rss version="2.0"><channel></channel></rss>`} {
+		_, err := Parse(strings.NewReader(input))
+		s.ErrorIs(err, ErrNotOpml, input)
+	}
+}
+
+func (s *OpmlSuite) TestWrite() {
+	doc := &Document{
+		Title:   "podfinder",
+		Created: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Outlines: []Outline{
+			{Title: "Hello Internet", Type: "rss", XmlUrl: "http://www.hellointernet.fm/podcast?format=rss", HtmlUrl: "https://podcasts.apple.com/us/podcast/hello-internet/id811377230"},
+			{Text: "Folder", Outlines: []Outline{{Text: "Q&A", XmlUrl: "https://example.com/feed"}}},
+		},
+	}
+
+	var buf bytes.Buffer
+	s.NoError(Write(&buf, doc))
+
+	s.Contains(buf.String(), `<opml version="2.0">`)
+	s.Contains(buf.String(), `<dateCreated>Fri, 01 Mar 2024 12:00:00 +0000</dateCreated>`)
+	s.Contains(buf.String(), `<outline text="Hello Internet" title="Hello Internet" type="rss" xmlUrl="http://www.hellointernet.fm/podcast?format=rss" htmlUrl="https://podcasts.apple.com/us/podcast/hello-internet/id811377230"></outline>`)
+	parsed, err := Parse(&buf)
+	s.NoError(err)
+	s.Equal(doc.Title, parsed.Title)
+	s.Equal(doc.Created, parsed.Created.UTC())
+	s.Equal([]Outline{{Text: "Q&A", Title: "", XmlUrl: "https://example.com/feed"}}, parsed.Outlines[1].Outlines)
+	s.Len(parsed.Feeds(), 2)
+}
@@ -0,0 +1,37 @@
+package main
+
+// Page describes a single page of a longer list. Pages are numbered from 1.
+type Page struct {
+	Number int
+	Total  int
+}
+
+func (p Page) HasPrev() bool {
+	return p.Number > 1
+}
+
+func (p Page) HasNext() bool {
+	return p.Number < p.Total
+}
+
+func (p Page) Prev() int {
+	return p.Number - 1
+}
+
+func (p Page) Next() int {
+	return p.Number + 1
+}
+
+// paginate returns items on the page with the given number, which is clamped to the existing pages.
+func paginate[T any](items []T, number, size int) ([]T, Page) {
+	total := (len(items) + size - 1) / size
+	if total == 0 {
+		total = 1
+	}
+	number = max(1, min(number, total))
+
+	from := min((number-1)*size, len(items))
+	to := min(from+size, len(items))
+
+	return items[from:to], Page{Number: number, Total: total}
+}
@@ -0,0 +1,30 @@
+package main
+
+import "fmt"
+
+func (s *AppSuite) TestPaginate() {
+	items := []int{1, 2, 3, 4, 5}
+	cases := []struct {
+		number int
+		items  []int
+		page   Page
+	}{
+		{0, []int{1, 2}, Page{1, 3}},
+		{1, []int{1, 2}, Page{1, 3}},
+		{2, []int{3, 4}, Page{2, 3}},
+		{3, []int{5}, Page{3, 3}},
+		{4, []int{5}, Page{3, 3}},
+	}
+
+	for _, c := range cases {
+		s.Run(fmt.Sprint(c.number), func() {
+			items, page := paginate(items, c.number, 2)
+			s.Equal(c.items, items)
+			s.Equal(c.page, page)
+		})
+	}
+
+	empty, page := paginate([]int{}, 1, 2)
+	s.Empty(empty)
+	s.Equal(Page{1, 1}, page)
+}
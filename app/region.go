@@ -0,0 +1,183 @@
+package main
+
+import (
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RegionSource is a step of the region resolution chain, returning false if it can't tell the region of a request.
+type RegionSource interface {
+	Name() string
+	Region(r *http.Request) (string, bool)
+}
+
+// Countries locates IP addresses.
+type Countries interface {
+	Country(addr netip.Addr) (string, error)
+}
+
+// QueryRegion reads the region query parameter.
+type QueryRegion struct{}
+
+func (QueryRegion) Name() string { return "query" }
+
+func (QueryRegion) Region(r *http.Request) (string, bool) {
+	v := r.URL.Query().Get("region")
+	return v, v != ""
+}
+
+// CookieRegion reads the region a visitor picked.
+type CookieRegion struct {
+	Cookies *SignedCookies
+}
+
+func (c CookieRegion) Name() string { return "cookie" }
+
+func (c CookieRegion) Region(r *http.Request) (string, bool) {
+	v, ok := c.Cookies.Get(r, "region")
+	return v, ok && v != ""
+}
+
+// AccountRegion reads the region a signed in user prefers.
+type AccountRegion struct {
+	Accounts Accounts
+	Cookies  *SignedCookies
+}
+
+func (AccountRegion) Name() string { return "account" }
+
+func (s AccountRegion) Region(r *http.Request) (string, bool) {
+	user := sessionUser(r, s.Cookies, s.Accounts)
+	if user == nil {
+		return "", false
+	}
+	return user.Region, user.Region != ""
+}
+
+// GeoRegion locates the client address in a GeoIP database.
+type GeoRegion struct {
+	Countries Countries
+	Clients   Clients
+}
+
+func (g *GeoRegion) Name() string { return "location" }
+
+func (g *GeoRegion) Region(r *http.Request) (string, bool) {
+	addr, err := netip.ParseAddr(g.Clients.Key(r))
+	if err != nil {
+		return "", false
+	}
+	country, err := g.Countries.Country(addr)
+	if err != nil {
+		return "", false
+	}
+	return country, true
+}
+
+// LanguageRegion takes the region from the most preferred Accept-Language tag that has one, such as fi-FI.
+// Tags without a region are matched to the country the language is mostly spoken in.
+type LanguageRegion struct{}
+
+func (LanguageRegion) Name() string { return "language" }
+
+func (LanguageRegion) Region(r *http.Request) (string, bool) {
+	for _, tag := range languages(r.Header.Get("Accept-Language")) {
+		lang, rest, _ := strings.Cut(strings.ToLower(tag), "-")
+		for _, sub := range strings.Split(rest, "-") {
+			// Region subtags are two letters, four letter ones are scripts and longer ones are variants
+			if len(sub) == 2 {
+				return sub, true
+			}
+		}
+		if reg, ok := languageRegions[lang]; ok {
+			return reg, true
+		}
+	}
+	return "", false
+}
+
+// languageRegions maps languages to their primary region where the codes differ or the language is national.
+var languageRegions = map[string]string{
+	"cs": "cz",
+	"da": "dk",
+	"de": "de",
+	"el": "gr",
+	"es": "es",
+	"et": "ee",
+	"fi": "fi",
+	"fr": "fr",
+	"he": "il",
+	"hu": "hu",
+	"id": "id",
+	"it": "it",
+	"ja": "jp",
+	"ko": "kr",
+	"nb": "no",
+	"nl": "nl",
+	"nn": "no",
+	"no": "no",
+	"pl": "pl",
+	"pt": "pt",
+	"ro": "ro",
+	"ru": "ru",
+	"sk": "sk",
+	"sv": "se",
+	"th": "th",
+	"tr": "tr",
+	"uk": "ua",
+	"vi": "vn",
+}
+
+// languages returns tags of an Accept-Language header ordered by their quality.
+func languages(header string) []string {
+	type language struct {
+		tag string
+		q   float64
+	}
+
+	var langs []language
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		if tag = strings.TrimSpace(tag); tag != "" && tag != "*" && q > 0 {
+			langs = append(langs, language{tag, q})
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.tag
+	}
+	return tags
+}
+
+// resolveRegion walks the region sources and returns the first supported region with the name of its source.
+func (a *App) resolveRegion(r *http.Request) (string, string) {
+	for _, s := range a.regions {
+		v, ok := s.Region(r)
+		if !ok {
+			continue
+		}
+		if _, ok := itunes.LookupRegion(strings.ToLower(v)); ok {
+			return strings.ToLower(v), s.Name()
+		}
+	}
+	return itunes.DefaultRegion, "default"
+}
+
+func (a *App) region(r *http.Request) string {
+	reg, _ := a.resolveRegion(r)
+	return reg
+}
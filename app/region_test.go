@@ -0,0 +1,105 @@
+package main
+
+import (
+	"errors"
+	"github.com/timiskhakov/podfinder/app/limiter"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+)
+
+type fakeCountries map[string]string
+
+func (f fakeCountries) Country(addr netip.Addr) (string, error) {
+	if c, ok := f[addr.String()]; ok {
+		return c, nil
+	}
+	return "", errors.New("not found")
+}
+
+func (s *AppSuite) TestResolveRegion() {
+	a := &App{regions: []RegionSource{
+		QueryRegion{},
+		CookieRegion{s.app.cookies},
+		&GeoRegion{Countries: fakeCountries{"85.76.0.1": "fi", "81.2.69.1": "xx"}, Clients: &limiter.ClientIP{}},
+		LanguageRegion{},
+	}}
+
+	cases := []struct {
+		name     string
+		query    string
+		cookie   string
+		addr     string
+		language string
+		region   string
+		source   string
+	}{
+		{"query", "?region=de", "se", "85.76.0.1", "fr-FR", "de", "query"},
+		{"unsupported query", "?region=zz", "se", "85.76.0.1", "", "se", "cookie"},
+		{"cookie", "", "se", "85.76.0.1", "fr-FR", "se", "cookie"},
+		{"location", "", "", "85.76.0.1", "fr-FR", "fi", "location"},
+		{"unsupported location", "", "", "81.2.69.1", "fr-FR", "fr", "language"},
+		{"language", "", "", "10.0.0.1", "en-GB,en;q=0.9", "gb", "language"},
+		{"default", "", "", "10.0.0.1", "en", "us", "default"},
+	}
+
+	for _, c := range cases {
+		s.Run(c.name, func() {
+			r := httptest.NewRequest(http.MethodGet, "/"+c.query, nil)
+			r.RemoteAddr = c.addr + ":1234"
+			if c.cookie != "" {
+				r.AddCookie(s.signedCookie("region", c.cookie))
+			}
+			r.Header.Set("Accept-Language", c.language)
+
+			region, source := a.resolveRegion(r)
+
+			s.Equal(c.region, region)
+			s.Equal(c.source, source)
+		})
+	}
+}
+
+func (s *AppSuite) TestLanguageRegion() {
+	cases := map[string]string{
+		"fi-FI,fi;q=0.9,en-US;q=0.8": "fi",
+		"en;q=0.5, sv-SE":            "se",
+		"zh-Hant-TW":                 "tw",
+		"ja":                         "jp",
+		"en-US;q=0, de;q=0.1":        "de",
+		"en, *":                      "",
+		"":                           "",
+	}
+
+	for header, region := range cases {
+		s.Run(header, func() {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Language", header)
+
+			v, ok := LanguageRegion{}.Region(r)
+
+			s.Equal(region, v)
+			s.Equal(region != "", ok)
+		})
+	}
+}
+
+func (s *AppSuite) TestRegionSourceShown() {
+	s.itunesMux.HandleFunc("/se/rss/toppodcasts/limit=10/json", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./testdata/top.json")
+	})
+	req, err := http.NewRequest(http.MethodGet, s.appServer.URL, nil)
+	s.NoError(err)
+	req.Header.Set("Accept-Language", "sv")
+
+	resp, err := http.DefaultClient.Do(req)
+	s.NoError(err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	s.NoError(err)
+
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(string(body), `<option value="se" selected>`)
+	s.Contains(string(body), "Detected from your browser language")
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"errors"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"github.com/timiskhakov/podfinder/app/reviews"
+	"github.com/timiskhakov/podfinder/app/sentiment"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	// reviewPagesConcurrency limits how many review pages are fetched at once for a summary
+	reviewPagesConcurrency = 4
+	summaryMonths          = 12
+	complaintKeywords      = 8
+	// complaintRating is the highest rating of reviews counted as complaints whatever their sentiment
+	complaintRating = 2
+)
+
+type apiSentiment struct {
+	Value float64 `json:"value"`
+	Label string  `json:"label"`
+}
+
+type apiKeyword struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+type apiMonth struct {
+	Month   string  `json:"month"`
+	Count   int     `json:"count"`
+	Average float64 `json:"average"`
+}
+
+func (a *App) handleApiReviewSummary() http.HandlerFunc {
+	type response struct {
+		Region     string       `json:"region"`
+		Count      int          `json:"count"`
+		Average    float64      `json:"average"`
+		Histogram  [5]int       `json:"histogram"`
+		Months     []apiMonth   `json:"months"`
+		Complaints []apiKeyword `json:"complaints"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		reg := a.region(r)
+		rews, err := a.allReviews(r.PathValue("id"), reg)
+		if err != nil {
+			log.Println(err)
+			writeApiError(w, http.StatusBadGateway, "can't fetch reviews")
+			return
+		}
+
+		summary := reviews.Summarize(rews)
+		months := make([]apiMonth, len(summary.Months))
+		for i, m := range summary.Months {
+			months[i] = apiMonth{m.Start.Format("2006-01"), m.Count, m.Average}
+		}
+		complaints := a.complaints(rews)
+		keywords := make([]apiKeyword, len(complaints))
+		for i, k := range complaints {
+			keywords[i] = apiKeyword{k.Term, k.Count}
+		}
+		writeJson(w, http.StatusOK, response{reg, summary.Count, summary.Average, summary.Histogram, months, keywords})
+	}
+}
+
+// allReviews returns every review the storefront serves for a podcast, up to ten pages fetched at
+// once, along with reviews of the region kept by the archive. Its route has a budget of its own.
+func (a *App) allReviews(id, region string) ([]*itunes.Review, error) {
+	rews, err := reviews.Fetch(a.store, id, region, reviewPagesConcurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	archived, err := a.archivedReviews(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return withArchived(rews, archived, region), nil
+}
+
+// withArchived adds reviews of the region kept by the archive to reviews the storefront serves.
+func withArchived(rews []*itunes.Review, archived []*reviews.Archived, region string) []*itunes.Review {
+	result := slices.Clone(rews)
+	for _, ar := range archived {
+		if ar.Region == region {
+			result = append(result, ar.Review())
+		}
+	}
+	return result
+}
+
+// scoredReview is a review with the sentiment of its title and content.
+type scoredReview struct {
+	*itunes.Review
+	Sentiment sentiment.Score
+}
+
+func (a *App) scoreReviews(rews []*itunes.Review) []scoredReview {
+	result := make([]scoredReview, len(rews))
+	for i, rew := range rews {
+		result[i] = scoredReview{rew, a.sentiment.Score(reviewText(rew))}
+	}
+	return result
+}
+
+// complaints finds what negative and low rated reviews mention most, each review is counted once.
+func (a *App) complaints(rews []*itunes.Review) []sentiment.Keyword {
+	seen := make(map[string]bool, len(rews))
+	var texts []string
+	for _, rew := range rews {
+		if seen[rew.Id] {
+			continue
+		}
+		seen[rew.Id] = true
+
+		text := reviewText(rew)
+		if len(rew.Rating) <= complaintRating || a.sentiment.Score(text).Value <= -sentiment.Threshold {
+			texts = append(texts, text)
+		}
+	}
+	return a.sentiment.Keywords(texts, complaintKeywords)
+}
+
+func reviewText(rew *itunes.Review) string {
+	return rew.Title + ". " + rew.Content
+}
+
+// archivedReviews returns reviews of a podcast kept by the archive, if there is one.
+func (a *App) archivedReviews(id string) ([]*reviews.Archived, error) {
+	if a.archive == nil {
+		return nil, nil
+	}
+	archived, err := a.archive.Reviews(id)
+	if errors.Is(err, reviews.ErrInvalidId) {
+		return nil, nil
+	}
+	return archived, err
+}
+
+type apiArchivedReview struct {
+	Id        string    `json:"id"`
+	Region    string    `json:"region"`
+	Author    string    `json:"author"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Rating    int       `json:"rating"`
+	Date      time.Time `json:"date"`
+	VoteCount int       `json:"voteCount"`
+	VoteSum   int       `json:"voteSum"`
+	Version   string    `json:"version"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Edits     []apiEdit `json:"edits"`
+}
+
+type apiEdit struct {
+	Title    string    `json:"title"`
+	Content  string    `json:"content"`
+	Rating   int       `json:"rating"`
+	Date     time.Time `json:"date"`
+	Detected time.Time `json:"detected"`
+}
+
+func (a *App) handleReviewArchive() http.HandlerFunc {
+	type response struct {
+		Podcast *itunes.PodcastDetail
+		Reviews []*reviews.Archived
+		Edited  int
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.archive == nil {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+
+		id := r.PathValue("id")
+		pod, err := a.store.Lookup(id)
+		if err != nil {
+			log.Println(err)
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+
+		archived, err := a.archivedReviews(id)
+		if err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		edited := 0
+		for _, ar := range archived {
+			if len(ar.Edits) > 0 {
+				edited++
+			}
+		}
+		a.render(w, r, response{pod, archived, edited}, "archive.html")
+	}
+}
+
+func (a *App) handleApiReviewArchive() http.HandlerFunc {
+	type response struct {
+		Id      string              `json:"id"`
+		Reviews []apiArchivedReview `json:"reviews"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.archive == nil {
+			writeApiError(w, http.StatusNotFound, "review archive not found")
+			return
+		}
+
+		id := r.PathValue("id")
+		archived, err := a.archive.Reviews(id)
+		if errors.Is(err, reviews.ErrInvalidId) {
+			writeApiError(w, http.StatusBadRequest, "invalid podcast id")
+			return
+		}
+		if err != nil {
+			log.Printf("%v", err)
+			writeApiError(w, http.StatusInternalServerError, "can't read review archive")
+			return
+		}
+
+		result := make([]apiArchivedReview, len(archived))
+		for i, ar := range archived {
+			edits := make([]apiEdit, len(ar.Edits))
+			for j, e := range ar.Edits {
+				edits[j] = apiEdit{e.Title, e.Content, e.Rating, e.Date, e.Detected}
+			}
+			result[i] = apiArchivedReview{
+				Id:        ar.Id,
+				Region:    ar.Region,
+				Author:    ar.Author,
+				Title:     ar.Title,
+				Content:   ar.Content,
+				Rating:    ar.Rating,
+				Date:      ar.Date,
+				VoteCount: ar.VoteCount,
+				VoteSum:   ar.VoteSum,
+				Version:   ar.Version,
+				FirstSeen: ar.FirstSeen,
+				LastSeen:  ar.LastSeen,
+				Edits:     edits,
+			}
+		}
+		writeJson(w, http.StatusOK, response{id, result})
+	}
+}
@@ -0,0 +1,214 @@
+package reviews
+
+import (
+	"errors"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"github.com/timiskhakov/podfinder/app/storage"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidId = errors.New("invalid podcast id")
+
+	idRegexp = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// Archived is a review kept by the archive, with the versions it had before it was edited.
+type Archived struct {
+	Id        string    `json:"id"`
+	Region    string    `json:"region"`
+	Author    string    `json:"author"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Rating    int       `json:"rating"`
+	Date      time.Time `json:"date"`
+	VoteCount int       `json:"voteCount"`
+	VoteSum   int       `json:"voteSum"`
+	Version   string    `json:"version"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	// Edits are earlier versions of the review, oldest first
+	Edits []Edit `json:"edits,omitempty"`
+}
+
+// Edit is a version of a review that was replaced, Detected is when the new version was first seen.
+type Edit struct {
+	Title    string    `json:"title"`
+	Content  string    `json:"content"`
+	Rating   int       `json:"rating"`
+	Date     time.Time `json:"date"`
+	Detected time.Time `json:"detected"`
+}
+
+// Changes counts what an Add did to the archive.
+type Changes struct {
+	New    int
+	Edited int
+	// First is set if reviews of the podcast were never collected in the region before
+	First bool
+}
+
+// podcast is the stored archive of a podcast, reviews are keyed by id.
+type podcast struct {
+	Collected time.Time `json:"collected"`
+	// Regions are when reviews were last collected in each region, archives saved before they were
+	// tracked have none and count every region as collected
+	Regions map[string]time.Time `json:"regions,omitempty"`
+	Reviews map[string]*Archived `json:"reviews"`
+}
+
+type ArchiveConfig struct {
+	// Dir holds a file per podcast, the archive is kept in memory only if it is empty.
+	Dir string
+}
+
+// Archive stores reviews of podcasts, deduplicated by review id.
+type Archive struct {
+	dir   string
+	mu    sync.Mutex
+	files map[string]*storage.File[podcast]
+}
+
+func NewArchive(config *ArchiveConfig) *Archive {
+	return &Archive{
+		dir:   config.Dir,
+		files: make(map[string]*storage.File[podcast]),
+	}
+}
+
+func (a *Archive) file(id string) (*storage.File[podcast], error) {
+	if !idRegexp.MatchString(id) {
+		return nil, ErrInvalidId
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if f, ok := a.files[id]; ok {
+		return f, nil
+	}
+
+	path := ""
+	if a.dir != "" {
+		path = filepath.Join(a.dir, id+".json")
+	}
+	f, err := storage.Open[podcast](path)
+	if err != nil {
+		return nil, err
+	}
+	a.files[id] = f
+
+	return f, nil
+}
+
+// Add saves reviews of a podcast seen in a region at a time. Reviews already in the archive
+// are updated, and their previous versions kept as edits if the title, content or rating changed.
+func (a *Archive) Add(id, region string, at time.Time, reviews []*itunes.Review) (Changes, error) {
+	f, err := a.file(id)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	var changes Changes
+	err = f.Update(func(p *podcast) error {
+		if p.Reviews == nil {
+			p.Reviews = make(map[string]*Archived)
+		}
+		if p.Regions == nil {
+			changes.First = p.Collected.IsZero()
+			p.Regions = make(map[string]time.Time)
+		} else {
+			_, collected := p.Regions[region]
+			changes.First = !collected
+		}
+		if at.After(p.Collected) {
+			p.Collected = at
+		}
+		if at.After(p.Regions[region]) {
+			p.Regions[region] = at
+		}
+
+		for _, r := range reviews {
+			rating := len(r.Rating)
+			ar, ok := p.Reviews[r.Id]
+			if !ok {
+				ar = &Archived{Id: r.Id, Region: region, FirstSeen: at}
+				p.Reviews[r.Id] = ar
+				changes.New++
+			} else if ar.Title != r.Title || ar.Content != r.Content || ar.Rating != rating {
+				ar.Edits = append(ar.Edits, Edit{Title: ar.Title, Content: ar.Content, Rating: ar.Rating, Date: ar.Date, Detected: at})
+				changes.Edited++
+			}
+
+			ar.Author, ar.Title, ar.Content, ar.Rating, ar.Date = r.Author, r.Title, r.Content, rating, r.Date
+			ar.VoteCount, ar.VoteSum, ar.Version = r.VoteCount, r.VoteSum, r.Version
+			if at.After(ar.LastSeen) {
+				ar.LastSeen = at
+			}
+		}
+
+		return nil
+	})
+
+	return changes, err
+}
+
+// Collected returns when reviews of a podcast were last added.
+func (a *Archive) Collected(id string) (time.Time, error) {
+	f, err := a.file(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var collected time.Time
+	f.View(func(p *podcast) {
+		collected = p.Collected
+	})
+
+	return collected, nil
+}
+
+// Reviews returns archived reviews of a podcast, newest first.
+func (a *Archive) Reviews(id string) ([]*Archived, error) {
+	f, err := a.file(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Archived
+	f.View(func(p *podcast) {
+		result = make([]*Archived, 0, len(p.Reviews))
+		for _, r := range p.Reviews {
+			c := *r
+			c.Edits = append([]Edit(nil), r.Edits...)
+			result = append(result, &c)
+		}
+	})
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Date.Equal(result[j].Date) {
+			return result[i].Date.After(result[j].Date)
+		}
+		return result[i].Id < result[j].Id
+	})
+
+	return result, nil
+}
+
+// Review converts an archived review back to the latest version of the review.
+func (r *Archived) Review() *itunes.Review {
+	return &itunes.Review{
+		Id:        r.Id,
+		Author:    r.Author,
+		Title:     r.Title,
+		Content:   r.Content,
+		Rating:    make([]struct{}, r.Rating),
+		Date:      r.Date,
+		VoteCount: r.VoteCount,
+		VoteSum:   r.VoteSum,
+		Version:   r.Version,
+	}
+}
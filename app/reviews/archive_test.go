@@ -0,0 +1,73 @@
+package reviews
+
+import (
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"time"
+)
+
+func (s *ReviewsSuite) TestArchiveAdd() {
+	a := NewArchive(&ArchiveConfig{})
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+
+	changes, err := a.Add("811377230", "us", first, []*itunes.Review{
+		review("1", 5, "2024-01-05"),
+		review("2", 2, "2024-02-10"),
+		review("1", 5, "2024-01-05"),
+	})
+	s.NoError(err)
+	s.Equal(Changes{New: 2, First: true}, changes)
+
+	edited := review("2", 4, "2024-03-01")
+	edited.Content = "Better now"
+	changes, err = a.Add("811377230", "us", second, []*itunes.Review{
+		review("1", 5, "2024-01-05"),
+		edited,
+		review("3", 3, "2024-02-20"),
+	})
+	s.NoError(err)
+	s.Equal(Changes{New: 1, Edited: 1}, changes)
+
+	rews, err := a.Reviews("811377230")
+	s.NoError(err)
+	s.Len(rews, 3)
+	s.Equal("2", rews[0].Id)
+	s.Equal(4, rews[0].Rating)
+	s.Equal("Better now", rews[0].Content)
+	s.Equal(first, rews[0].FirstSeen)
+	s.Equal(second, rews[0].LastSeen)
+	s.Equal([]Edit{{Rating: 2, Date: time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC), Detected: second}}, rews[0].Edits)
+	s.Equal([]string{"3", "1"}, []string{rews[1].Id, rews[2].Id})
+	s.Empty(rews[2].Edits)
+
+	collected, err := a.Collected("811377230")
+	s.NoError(err)
+	s.Equal(second, collected)
+
+	changes, err = a.Add("811377230", "gb", second, []*itunes.Review{review("4", 1, "2024-02-25")})
+	s.NoError(err)
+	s.Equal(Changes{New: 1, First: true}, changes)
+}
+
+func (s *ReviewsSuite) TestArchivePersists() {
+	dir := s.T().TempDir()
+	a := NewArchive(&ArchiveConfig{Dir: dir})
+	_, err := a.Add("811377230", "gb", time.Now(), []*itunes.Review{review("1", 5, "2024-01-05")})
+	s.NoError(err)
+
+	rews, err := NewArchive(&ArchiveConfig{Dir: dir}).Reviews("811377230")
+
+	s.NoError(err)
+	s.Len(rews, 1)
+	s.Equal("gb", rews[0].Region)
+	s.Equal(5, len(rews[0].Review().Rating))
+}
+
+func (s *ReviewsSuite) TestArchiveInvalidId() {
+	a := NewArchive(&ArchiveConfig{Dir: s.T().TempDir()})
+
+	_, err := a.Add("../811377230", "us", time.Now(), nil)
+	s.ErrorIs(err, ErrInvalidId)
+	_, err = a.Reviews("")
+	s.ErrorIs(err, ErrInvalidId)
+}
@@ -0,0 +1,237 @@
+package reviews
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"golang.org/x/sync/errgroup"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Store interface {
+	Reviews(id, region string, q itunes.ReviewsQuery) (*itunes.ReviewPage, error)
+}
+
+// Clock lets tests control when reviews are collected.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Fetch fetches every page of the most recent reviews of a podcast, at most concurrency pages at once.
+func Fetch(store Store, id, region string, concurrency int) ([]*itunes.Review, error) {
+	first, err := store.Reviews(id, region, itunes.ReviewsQuery{})
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make([]*itunes.ReviewPage, max(first.Last, 1))
+	pages[0] = first
+	g := errgroup.Group{}
+	g.SetLimit(max(concurrency, 1))
+	for i := 1; i < len(pages); i++ {
+		g.Go(func() error {
+			page, err := store.Reviews(id, region, itunes.ReviewsQuery{Page: i + 1})
+			pages[i] = page
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var result []*itunes.Review
+	for _, page := range pages {
+		result = append(result, page.Reviews...)
+	}
+	return result, nil
+}
+
+// Watchlist tells which podcasts users watch, it may change between collections.
+type Watchlist interface {
+	Watched() []string
+}
+
+// Listener is told about reviews archived for the first time. Reviews found when a podcast is collected
+// in a region for the first time aren't reported, they were published before anyone watched it there.
+type Listener interface {
+	Reviews(podcast, region string, added []*Archived)
+}
+
+type CollectorConfig struct {
+	Store   Store
+	Archive *Archive
+	// Podcasts are ids of the podcasts to archive reviews of
+	Podcasts []string
+	// Watchlist adds podcasts users watch to Podcasts, if it is set
+	Watchlist Watchlist
+	// Regions to fetch reviews from, the default region by default
+	Regions []string
+	Every   time.Duration
+	// Concurrency limits how many podcasts are fetched at once, one by default
+	Concurrency int
+	// Listener is told about new reviews, if it is set
+	Listener Listener
+	Clock    Clock
+}
+
+// Collector periodically adds reviews of watched podcasts to the Archive.
+type Collector struct {
+	store       Store
+	archive     *Archive
+	podcasts    []string
+	watchlist   Watchlist
+	regions     []string
+	every       time.Duration
+	concurrency int
+	listener    Listener
+	clock       Clock
+}
+
+func NewCollector(config *CollectorConfig) *Collector {
+	c := &Collector{
+		store:       config.Store,
+		archive:     config.Archive,
+		podcasts:    config.Podcasts,
+		watchlist:   config.Watchlist,
+		regions:     config.Regions,
+		every:       config.Every,
+		concurrency: config.Concurrency,
+		listener:    config.Listener,
+		clock:       config.Clock,
+	}
+	if len(c.regions) == 0 {
+		c.regions = []string{itunes.DefaultRegion}
+	}
+	if c.concurrency <= 0 {
+		c.concurrency = 1
+	}
+	if c.clock == nil {
+		c.clock = realClock{}
+	}
+
+	return c
+}
+
+// Run collects reviews until the context is done. The first collection happens right away,
+// unless the archive already has reviews collected more recently than the interval.
+func (c *Collector) Run(ctx context.Context) error {
+	last, err := c.latest()
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.clock.After(c.every - c.clock.Now().Sub(last)):
+		}
+
+		last = c.clock.Now()
+		if err := c.Collect(ctx); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+}
+
+// Collect archives reviews of every podcast in every region, one failing doesn't stop the others.
+func (c *Collector) Collect(ctx context.Context) error {
+	now := c.clock.Now()
+	ids := c.podcastIds()
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	g := errgroup.Group{}
+	g.SetLimit(c.concurrency)
+	for _, id := range ids {
+		for _, region := range c.regions {
+			if ctx.Err() != nil {
+				break
+			}
+
+			g.Go(func() error {
+				if err := c.collect(id, region, now); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("can't archive %s reviews of %s: %w", region, id, err))
+					mu.Unlock()
+				}
+				return nil
+			})
+		}
+	}
+	_ = g.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (c *Collector) collect(id, region string, now time.Time) error {
+	rews, err := Fetch(c.store, id, region, 1)
+	if err != nil {
+		return err
+	}
+	changes, err := c.archive.Add(id, region, now, rews)
+	if err != nil {
+		return err
+	}
+	if changes.Edited > 0 {
+		log.Printf("%d reviews of %s edited in %s", changes.Edited, id, region)
+	}
+	if c.listener == nil || changes.First || changes.New == 0 {
+		return nil
+	}
+
+	archived, err := c.archive.Reviews(id)
+	if err != nil {
+		return err
+	}
+	var added []*Archived
+	for _, r := range archived {
+		if r.Region == region && r.FirstSeen.Equal(now) {
+			added = append(added, r)
+		}
+	}
+	c.listener.Reviews(id, region, added)
+	return nil
+}
+
+// latest returns when reviews of any podcast were last collected.
+func (c *Collector) latest() (time.Time, error) {
+	var last time.Time
+	for _, id := range c.podcastIds() {
+		t, err := c.archive.Collected(id)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	return last, nil
+}
+
+// podcastIds returns the configured podcasts followed by watched ones that aren't configured.
+func (c *Collector) podcastIds() []string {
+	if c.watchlist == nil {
+		return c.podcasts
+	}
+
+	ids := slices.Clone(c.podcasts)
+	for _, id := range c.watchlist.Watched() {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,151 @@
+package reviews
+
+import (
+	"context"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"time"
+)
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time                         { return c.now }
+func (c fixedClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (s *ReviewsSuite) TestCollect() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/gb/") {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeFile(w, r, "../testdata/reviews.json")
+	}))
+	defer server.Close()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := NewArchive(&ArchiveConfig{})
+	c := NewCollector(&CollectorConfig{
+		Store:       itunes.NewStore(server.URL, server.Client()),
+		Archive:     a,
+		Podcasts:    []string{"811377230"},
+		Regions:     []string{"us", "gb"},
+		Concurrency: 2,
+		Clock:       fixedClock{now},
+	})
+
+	err := c.Collect(context.Background())
+
+	s.ErrorContains(err, "can't archive gb reviews of 811377230")
+	rews, err := a.Reviews("811377230")
+	s.NoError(err)
+	s.Len(rews, 50)
+	s.Equal("us", rews[0].Region)
+	s.Equal(now, rews[0].FirstSeen)
+	collected, err := c.latest()
+	s.NoError(err)
+	s.Equal(now, collected)
+}
+
+type watchlist []string
+
+func (w watchlist) Watched() []string { return w }
+
+func (s *ReviewsSuite) TestCollectWatched() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "../testdata/reviews.json")
+	}))
+	defer server.Close()
+	a := NewArchive(&ArchiveConfig{})
+	c := NewCollector(&CollectorConfig{
+		Store:     itunes.NewStore(server.URL, server.Client()),
+		Archive:   a,
+		Podcasts:  []string{"811377230"},
+		Watchlist: watchlist{"811377230", "1200361736"},
+		Clock:     fixedClock{time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+	})
+
+	err := c.Collect(context.Background())
+
+	s.NoError(err)
+	s.Equal([]string{"811377230", "1200361736"}, c.podcastIds())
+	rews, err := a.Reviews("1200361736")
+	s.NoError(err)
+	s.Len(rews, 50)
+}
+
+type added map[string][]*Archived
+
+func (a added) Reviews(podcast, region string, rews []*Archived) {
+	a[podcast+"/"+region] = rews
+}
+
+func (s *ReviewsSuite) TestCollectListener() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "../testdata/reviews.json")
+	}))
+	defer server.Close()
+	store := itunes.NewStore(server.URL, server.Client())
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	rews, err := Fetch(store, "811377230", "us", 1)
+	s.NoError(err)
+	a := NewArchive(&ArchiveConfig{})
+	// Every page the stand-in serves is the same, so the two newest reviews are left out by id
+	older := slices.DeleteFunc(slices.Clone(rews), func(r *itunes.Review) bool { return r.Id == rews[0].Id || r.Id == rews[1].Id })
+	_, err = a.Add("811377230", "us", now.Add(-24*time.Hour), older)
+	s.NoError(err)
+	l := added{}
+	c := NewCollector(&CollectorConfig{
+		Store:    store,
+		Archive:  a,
+		Podcasts: []string{"811377230", "1200361736"},
+		Listener: l,
+		Clock:    fixedClock{now},
+	})
+
+	s.NoError(c.Collect(context.Background()))
+
+	// Reviews of a podcast collected for the first time aren't new to anyone
+	s.Len(l, 1)
+	s.Len(l["811377230/us"], 2)
+	s.Equal(rews[0].Id, l["811377230/us"][0].Id)
+	s.Equal(rews[1].Id, l["811377230/us"][1].Id)
+	s.Equal(len(rews[0].Rating), l["811377230/us"][0].Rating)
+}
+
+func (s *ReviewsSuite) TestCollectListenerNewRegion() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "../testdata/reviews.json")
+	}))
+	defer server.Close()
+	store := itunes.NewStore(server.URL, server.Client())
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	rews, err := Fetch(store, "811377230", "us", 1)
+	s.NoError(err)
+	a := NewArchive(&ArchiveConfig{})
+	older := slices.DeleteFunc(slices.Clone(rews), func(r *itunes.Review) bool { return r.Id == rews[0].Id })
+	_, err = a.Add("811377230", "us", now.Add(-24*time.Hour), older)
+	s.NoError(err)
+	l := added{}
+	c := NewCollector(&CollectorConfig{
+		Store:    store,
+		Archive:  a,
+		Podcasts: []string{"811377230"},
+		Regions:  []string{"gb"},
+		Listener: l,
+		Clock:    fixedClock{now},
+	})
+
+	s.NoError(c.Collect(context.Background()))
+
+	// A region added later starts out like a new podcast, its reviews aren't new to anyone
+	s.Empty(l)
+	archived, err := a.Reviews("811377230")
+	s.NoError(err)
+	i := slices.IndexFunc(archived, func(r *Archived) bool { return r.Id == rews[0].Id })
+	s.Equal("gb", archived[i].Region)
+	s.Equal(now, archived[i].FirstSeen)
+}
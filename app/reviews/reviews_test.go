@@ -0,0 +1,21 @@
+package reviews
+
+import (
+	"github.com/stretchr/testify/suite"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"testing"
+	"time"
+)
+
+type ReviewsSuite struct {
+	suite.Suite
+}
+
+func TestReviewsSuite(t *testing.T) {
+	suite.Run(t, new(ReviewsSuite))
+}
+
+func review(id string, rating int, date string) *itunes.Review {
+	t, _ := time.Parse(time.DateOnly, date)
+	return &itunes.Review{Id: id, Rating: make([]struct{}, rating), Date: t}
+}
@@ -0,0 +1,108 @@
+package reviews
+
+import (
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"time"
+)
+
+// Summary describes ratings of a set of reviews.
+type Summary struct {
+	Count   int
+	Average float64
+	// Histogram counts reviews by rating, index 0 is one star
+	Histogram [5]int
+	// Months covers every month from the oldest review to the newest one, oldest first
+	Months []Month
+}
+
+// Month is the review volume and average rating of a calendar month.
+type Month struct {
+	Start   time.Time
+	Count   int
+	Average float64
+}
+
+// Bar is a row of the rating histogram.
+type Bar struct {
+	Stars   int
+	Count   int
+	Percent int
+}
+
+// Summarize computes a summary of reviews, reviews with the same id are counted once
+// and the first of them is used. Reviews without a rating or a date are skipped.
+func Summarize(reviews []*itunes.Review) *Summary {
+	s := &Summary{}
+	seen := make(map[string]bool, len(reviews))
+	months := make(map[time.Time]*Month)
+	var first, last time.Time
+	total := 0
+
+	for _, r := range reviews {
+		rating := len(r.Rating)
+		if seen[r.Id] || rating < 1 || rating > 5 || r.Date.IsZero() {
+			continue
+		}
+		seen[r.Id] = true
+
+		s.Count++
+		s.Histogram[rating-1]++
+		total += rating
+
+		start := monthStart(r.Date)
+		m, ok := months[start]
+		if !ok {
+			m = &Month{Start: start}
+			months[start] = m
+		}
+		m.Count++
+		m.Average += float64(rating)
+
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if start.After(last) {
+			last = start
+		}
+	}
+	if s.Count == 0 {
+		return s
+	}
+
+	s.Average = float64(total) / float64(s.Count)
+	for t := first; !t.After(last); t = t.AddDate(0, 1, 0) {
+		m, ok := months[t]
+		if !ok {
+			m = &Month{Start: t}
+		}
+		if m.Count > 0 {
+			m.Average /= float64(m.Count)
+		}
+		s.Months = append(s.Months, *m)
+	}
+
+	return s
+}
+
+// Bars returns the histogram from five stars down to one.
+func (s *Summary) Bars() []Bar {
+	bars := make([]Bar, 0, len(s.Histogram))
+	for stars := len(s.Histogram); stars >= 1; stars-- {
+		bar := Bar{Stars: stars, Count: s.Histogram[stars-1]}
+		if s.Count > 0 {
+			bar.Percent = bar.Count * 100 / s.Count
+		}
+		bars = append(bars, bar)
+	}
+	return bars
+}
+
+// Recent returns at most n of the latest months.
+func (s *Summary) Recent(n int) []Month {
+	return s.Months[max(0, len(s.Months)-n):]
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,47 @@
+package reviews
+
+import (
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"time"
+)
+
+func (s *ReviewsSuite) TestSummarize() {
+	summary := Summarize([]*itunes.Review{
+		review("1", 5, "2024-01-05"),
+		review("2", 4, "2024-01-20"),
+		review("3", 1, "2024-03-02"),
+		review("1", 1, "2024-03-10"),
+		review("4", 0, "2024-03-10"),
+		review("5", 5, "0001-01-01"),
+	})
+
+	s.Equal(3, summary.Count)
+	s.InDelta(10.0/3, summary.Average, 0.001)
+	s.Equal([5]int{1, 0, 0, 1, 1}, summary.Histogram)
+	s.Equal([]Month{
+		{Start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Count: 2, Average: 4.5},
+		{Start: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{Start: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Count: 1, Average: 1},
+	}, summary.Months)
+}
+
+func (s *ReviewsSuite) TestSummarizeEmpty() {
+	summary := Summarize(nil)
+
+	s.Equal(0, summary.Count)
+	s.Empty(summary.Months)
+	s.Equal(Bar{Stars: 5}, summary.Bars()[0])
+}
+
+func (s *ReviewsSuite) TestBars() {
+	summary := &Summary{Count: 4, Histogram: [5]int{1, 0, 0, 0, 3}}
+
+	s.Equal([]Bar{{5, 3, 75}, {4, 0, 0}, {3, 0, 0}, {2, 0, 0}, {1, 1, 25}}, summary.Bars())
+}
+
+func (s *ReviewsSuite) TestRecent() {
+	summary := Summarize([]*itunes.Review{review("1", 5, "2023-11-05"), review("2", 4, "2024-02-20")})
+
+	s.Len(summary.Recent(12), 4)
+	s.Equal([]Month{{Start: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Count: 1, Average: 4}}, summary.Recent(1))
+}
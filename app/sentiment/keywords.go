@@ -0,0 +1,79 @@
+package sentiment
+
+import (
+	"strings"
+)
+
+// minKeywordCount is how many texts must mention a term for it to be a keyword.
+const minKeywordCount = 2
+
+// Keyword is a word or a two word phrase and the number of texts it occurs in.
+type Keyword struct {
+	Term  string
+	Count int
+}
+
+// Keywords returns up to n of the terms most texts mention, leaving out stop words of each
+// text's language. Phrases of two words come before the single words they contain when both
+// are mentioned by as many texts.
+func (a *Analyzer) Keywords(texts []string, n int) []Keyword {
+	counts := make(map[string]int)
+	for _, text := range texts {
+		words := tokenize(text)
+		code := a.detect(words)
+		lang := a.languages[code]
+
+		seen := make(map[string]bool)
+		prev := ""
+		for _, w := range lang.normalize(code, words) {
+			if lang.stopwords[w] || len([]rune(w)) < 2 || isNumber(w) {
+				prev = ""
+				continue
+			}
+			seen[w] = true
+			if prev != "" {
+				seen[prev+" "+w] = true
+			}
+			prev = w
+		}
+		for term := range seen {
+			counts[term]++
+		}
+	}
+
+	// Words are covered by a phrase mentioned just as often
+	covered := make(map[string]bool)
+	for term, count := range counts {
+		first, second, ok := strings.Cut(term, " ")
+		if !ok || count < minKeywordCount {
+			continue
+		}
+		if counts[first] == count {
+			covered[first] = true
+		}
+		if counts[second] == count {
+			covered[second] = true
+		}
+	}
+
+	var result []Keyword
+	for _, term := range sortedKeys(counts) {
+		if len(result) == n || counts[term] < minKeywordCount {
+			break
+		}
+		if covered[term] {
+			continue
+		}
+		result = append(result, Keyword{term, counts[term]})
+	}
+	return result
+}
+
+func isNumber(w string) bool {
+	for _, r := range w {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,245 @@
+// Package sentiment scores review texts with bundled word lists and finds what reviews talk about.
+package sentiment
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	// normalization keeps scores between -1 and 1, higher values need more words to reach the ends
+	normalization = 15
+	// negationWindow is how many words after a negator have their score flipped
+	negationWindow = 3
+	// Threshold separates positive and negative scores from neutral ones
+	Threshold = 0.05
+)
+
+//go:embed lexicon/*.txt stopwords/*.txt
+var bundled embed.FS
+
+// negators flip the score of words following them.
+var negators = map[string][]string{
+	"en": {"not", "no", "never", "nor", "without", "don't", "doesn't", "didn't", "isn't", "wasn't", "aren't", "can't", "won't", "couldn't", "wouldn't", "hardly"},
+	"de": {"nicht", "kein", "keine", "keinen", "nie", "niemals", "ohne"},
+	"es": {"no", "nunca", "jamás", "sin", "ni"},
+	"fr": {"pas", "jamais", "sans", "ni", "aucun", "aucune"},
+}
+
+// elided languages attach articles and pronouns to words with an apostrophe, such as l'épisode.
+var elided = map[string]bool{"fr": true}
+
+// Languages lists languages with a bundled lexicon, English comes first and wins ties.
+var Languages = []string{"en", "de", "es", "fr"}
+
+// Score is the sentiment of a text.
+type Score struct {
+	// Value goes from -1, most negative, to 1, most positive
+	Value    float64
+	Language string
+	// Positive and Negative count words that moved the score
+	Positive int
+	Negative int
+}
+
+// Label names the score for display.
+func (s Score) Label() string {
+	switch {
+	case s.Value >= Threshold:
+		return "positive"
+	case s.Value <= -Threshold:
+		return "negative"
+	default:
+		return "neutral"
+	}
+}
+
+type language struct {
+	lexicon   map[string]int
+	stopwords map[string]bool
+	negators  map[string]bool
+}
+
+// Analyzer scores texts and extracts keywords, it is safe for concurrent use.
+type Analyzer struct {
+	languages map[string]*language
+}
+
+// New builds an analyzer from the bundled word lists.
+func New() *Analyzer {
+	a, err := load(bundled)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func load(fsys fs.FS) (*Analyzer, error) {
+	a := &Analyzer{languages: make(map[string]*language, len(Languages))}
+	for _, code := range Languages {
+		lang := &language{
+			lexicon:   make(map[string]int),
+			stopwords: make(map[string]bool),
+			negators:  make(map[string]bool),
+		}
+
+		err := readLines(fsys, path.Join("lexicon", code+".txt"), func(line string) error {
+			word, value, ok := strings.Cut(line, " ")
+			score, err := strconv.Atoi(value)
+			if !ok || err != nil || score < -3 || score > 3 {
+				return fmt.Errorf("want a word and a score from -3 to 3, got %q", line)
+			}
+			lang.lexicon[word] = score
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		err = readLines(fsys, path.Join("stopwords", code+".txt"), func(line string) error {
+			lang.stopwords[line] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, n := range negators[code] {
+			lang.negators[n] = true
+			lang.stopwords[n] = true
+		}
+		a.languages[code] = lang
+	}
+	return a, nil
+}
+
+// readLines calls fn with every line of a file that isn't empty or a # comment.
+func readLines(fsys fs.FS, name string, fn func(line string) error) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(strings.ToLower(line)); err != nil {
+			return fmt.Errorf("%s:%d: %w", name, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// Score rates a text with the lexicon of its language. Words following a negator, as in
+// "not funny", count the other way.
+func (a *Analyzer) Score(text string) Score {
+	words := tokenize(text)
+	code := a.detect(words)
+	lang := a.languages[code]
+	words = lang.normalize(code, words)
+
+	s := Score{Language: code}
+	sum, negated := 0.0, 0
+	for _, w := range words {
+		if lang.negators[w] {
+			negated = negationWindow
+			continue
+		}
+
+		v, ok := lang.lexicon[w]
+		if !ok || v == 0 {
+			negated = max(negated-1, 0)
+			continue
+		}
+		if negated > 0 {
+			negated, v = 0, -v
+		}
+
+		sum += float64(v)
+		if v > 0 {
+			s.Positive++
+		} else {
+			s.Negative++
+		}
+	}
+
+	s.Value = sum / math.Sqrt(sum*sum+normalization)
+	return s
+}
+
+// detect picks the language whose stop words occur most often in the words.
+func (a *Analyzer) detect(words []string) string {
+	best, hits := Languages[0], 0
+	for _, code := range Languages {
+		lang := a.languages[code]
+		n := 0
+		for _, w := range lang.normalize(code, words) {
+			if lang.stopwords[w] {
+				n++
+			}
+		}
+		if n > hits {
+			best, hits = code, n
+		}
+	}
+	return best
+}
+
+// normalize strips elisions from words of languages that have them.
+func (l *language) normalize(code string, words []string) []string {
+	if !elided[code] {
+		return words
+	}
+
+	result := make([]string, len(words))
+	for i, w := range words {
+		if prefix, rest, ok := strings.Cut(w, "'"); ok && rest != "" && len([]rune(prefix)) <= 2 {
+			w = rest
+		}
+		result[i] = w
+	}
+	return result
+}
+
+// tokenize splits a text into lowercase words, apostrophes inside words are kept.
+func tokenize(text string) []string {
+	text = strings.ToLower(strings.ReplaceAll(text, "’", "'"))
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+
+	result := words[:0]
+	for _, w := range words {
+		if w = strings.Trim(w, "'"); w != "" {
+			result = append(result, w)
+		}
+	}
+	return result
+}
+
+// sortedKeys returns terms by count, most frequent first, then alphabetically.
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
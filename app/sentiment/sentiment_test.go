@@ -0,0 +1,99 @@
+package sentiment
+
+import (
+	"github.com/stretchr/testify/suite"
+	"testing"
+	"testing/fstest"
+)
+
+type SentimentSuite struct {
+	suite.Suite
+	analyzer *Analyzer
+}
+
+func TestSentimentSuite(t *testing.T) {
+	suite.Run(t, new(SentimentSuite))
+}
+
+func (s *SentimentSuite) SetupSuite() {
+	s.analyzer = New()
+}
+
+func (s *SentimentSuite) TestScore() {
+	for text, label := range map[string]string{
+		"Absolutely love this show, the hosts are hilarious!": "positive",
+		"Boring and repetitive. Unsubscribed.":                "negative",
+		"It's not funny anymore":                              "negative",
+		"Not bad at all":                                      "positive",
+		"Episodes come out on Mondays":                        "neutral",
+		"":                                                    "neutral",
+	} {
+		s.Equal(label, s.analyzer.Score(text).Label(), text)
+	}
+}
+
+func (s *SentimentSuite) TestScoreRange() {
+	score := s.analyzer.Score("amazing amazing amazing amazing amazing amazing amazing")
+
+	s.Equal(7, score.Positive)
+	s.Zero(score.Negative)
+	s.Greater(score.Value, 0.95)
+	s.LessOrEqual(score.Value, 1.0)
+}
+
+func (s *SentimentSuite) TestScoreLanguages() {
+	for text, want := range map[string]Score{
+		"Der Podcast ist nicht gut, sehr langweilig":    {Language: "de", Negative: 2},
+		"Me encanta, es muy divertido y no es aburrido": {Language: "es", Positive: 3},
+		"Ce n'est pas bon, l'animateur est ennuyeux":    {Language: "fr", Negative: 2},
+		"Great show, the best on the subject":           {Language: "en", Positive: 2},
+	} {
+		score := s.analyzer.Score(text)
+		s.Equal(want.Language, score.Language, text)
+		s.Equal(want.Positive, score.Positive, text)
+		s.Equal(want.Negative, score.Negative, text)
+	}
+}
+
+func (s *SentimentSuite) TestKeywords() {
+	keywords := s.analyzer.Keywords([]string{
+		"Way too many ad breaks, and the audio quality is poor",
+		"Ad breaks every five minutes!!",
+		"The ad breaks ruin it. Audio quality got worse in 2023 and 2023",
+		"Great guests",
+		"Les pubs sont trop longues. Les pubs encore.",
+		"Trop de pubs",
+	}, 10)
+
+	s.Equal([]Keyword{
+		{"ad breaks", 3},
+		{"audio quality", 2},
+		{"pubs", 2},
+	}, keywords)
+}
+
+func (s *SentimentSuite) TestKeywordsLimit() {
+	texts := []string{"hosts guests sound", "hosts guests sound"}
+
+	s.Len(s.analyzer.Keywords(texts, 2), 2)
+	s.Empty(s.analyzer.Keywords(texts[:1], 10))
+}
+
+func (s *SentimentSuite) TestLoadErrors() {
+	files := fstest.MapFS{}
+	for _, code := range Languages {
+		files["lexicon/"+code+".txt"] = &fstest.MapFile{Data: []byte("# comment\ngood 2\n")}
+		files["stopwords/"+code+".txt"] = &fstest.MapFile{Data: []byte("the\n")}
+	}
+	_, err := load(files)
+	s.NoError(err)
+
+	files["lexicon/de.txt"] = &fstest.MapFile{Data: []byte("gut 2\nschlecht -5\n")}
+	_, err = load(files)
+	s.ErrorContains(err, "lexicon/de.txt:2")
+
+	delete(files, "stopwords/fr.txt")
+	files["lexicon/de.txt"] = files["lexicon/en.txt"]
+	_, err = load(files)
+	s.Error(err)
+}
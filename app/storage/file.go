@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// File keeps a value in memory and persists it as a JSON file, which is rewritten atomically on every update.
+// A File with an empty path is kept in memory only.
+type File[T any] struct {
+	mu    sync.RWMutex
+	path  string
+	value T
+}
+
+// Open reads a file, a missing file starts out as the zero value of T.
+func Open[T any](path string) (*File[T], error) {
+	f := &File[T]{path: path}
+	if path == "" {
+		return f, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &f.value); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// View calls fn with the current value, which must not be retained or modified.
+func (f *File[T]) View(fn func(v *T)) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	fn(&f.value)
+}
+
+// Update calls fn to modify the value and saves it. If fn or saving fails, the value is left as it was.
+func (f *File[T]) Update(fn func(v *T) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Work on a deep copy, so that a failed update doesn't leave partial changes behind
+	b, err := json.Marshal(&f.value)
+	if err != nil {
+		return err
+	}
+	var v T
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	if err := fn(&v); err != nil {
+		return err
+	}
+	if err := f.save(&v); err != nil {
+		return err
+	}
+
+	f.value = v
+	return nil
+}
+
+func (f *File[T]) save(v *T) error {
+	if f.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
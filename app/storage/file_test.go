@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"errors"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type FileSuite struct {
+	suite.Suite
+	path string
+}
+
+type counters struct {
+	Values map[string]int `json:"values"`
+}
+
+func TestFileSuite(t *testing.T) {
+	suite.Run(t, new(FileSuite))
+}
+
+func (s *FileSuite) SetupTest() {
+	s.path = filepath.Join(s.T().TempDir(), "data", "counters.json")
+}
+
+func (s *FileSuite) increment(f *File[counters], key string) error {
+	return f.Update(func(v *counters) error {
+		if v.Values == nil {
+			v.Values = map[string]int{}
+		}
+		v.Values[key]++
+		return nil
+	})
+}
+
+func (s *FileSuite) TestUpdate() {
+	f, err := Open[counters](s.path)
+	s.NoError(err)
+
+	s.NoError(s.increment(f, "a"))
+	s.NoError(s.increment(f, "a"))
+	s.NoError(s.increment(f, "b"))
+
+	reopened, err := Open[counters](s.path)
+	s.NoError(err)
+	reopened.View(func(v *counters) {
+		s.Equal(map[string]int{"a": 2, "b": 1}, v.Values)
+	})
+
+	entries, err := os.ReadDir(filepath.Dir(s.path))
+	s.NoError(err)
+	s.Len(entries, 1)
+}
+
+func (s *FileSuite) TestUpdateError() {
+	f, err := Open[counters](s.path)
+	s.NoError(err)
+	s.NoError(s.increment(f, "a"))
+
+	err = f.Update(func(v *counters) error {
+		v.Values["a"] = 10
+		return errors.New("failed")
+	})
+
+	s.EqualError(err, "failed")
+	f.View(func(v *counters) {
+		s.Equal(1, v.Values["a"])
+	})
+}
+
+func (s *FileSuite) TestInMemory() {
+	f, err := Open[counters]("")
+	s.NoError(err)
+
+	s.NoError(s.increment(f, "a"))
+
+	f.View(func(v *counters) {
+		s.Equal(1, v.Values["a"])
+	})
+}
+
+func (s *FileSuite) TestOpenInvalid() {
+	s.NoError(os.MkdirAll(filepath.Dir(s.path), 0o755))
+	s.NoError(os.WriteFile(s.path, []byte("{"), 0o644))
+
+	_, err := Open[counters](s.path)
+
+	s.Error(err)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/accounts"
+	"github.com/timiskhakov/podfinder/app/webhooks"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// deliveriesShown is how many of the latest deliveries of a hook the webhooks page shows.
+const deliveriesShown = 20
+
+// Webhooks sends events about podcasts users watch to urls they register, and keeps a log of deliveries.
+type Webhooks interface {
+	Hooks(user string) []*webhooks.Hook
+	Register(h webhooks.Hook) (*webhooks.Hook, error)
+	Remove(user, id string) error
+	Deliveries(user, hook string) ([]*webhooks.Delivery, error)
+	Replay(user, hook, delivery string) (*webhooks.Delivery, error)
+}
+
+// webhookErrors are shown to users for hooks that can't be registered.
+var webhookErrors = []struct {
+	err     error
+	message string
+}{
+	{webhooks.ErrInvalidUrl, "Enter an http or https url, such as https://example.com/hook."},
+	{webhooks.ErrInvalidEvent, "Pick events from the list."},
+	{webhooks.ErrInvalidRegion, "Enter regions as comma separated codes, such as us, gb."},
+	{webhooks.ErrInvalidThreshold, "Places can't be negative and the rating must be between 1 and 5 stars."},
+	{webhooks.ErrTooManyHooks, fmt.Sprintf("You can register up to %d webhooks, remove some to add more.", webhooks.MaxHooks)},
+}
+
+func (a *App) handleWebhooks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.webhooks == nil {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+
+		a.renderWebhooks(w, r, a.user(r), http.StatusOK, "")
+	}
+}
+
+func (a *App) renderWebhooks(w http.ResponseWriter, r *http.Request, user *accounts.User, status int, message string) {
+	type hook struct {
+		*webhooks.Hook
+		Deliveries []*webhooks.Delivery
+	}
+	type response struct {
+		Hooks  []hook
+		Events []string
+		Error  string
+	}
+
+	resp := response{Events: webhooks.Events, Error: message}
+	for _, h := range a.webhooks.Hooks(user.Id) {
+		deliveries, err := a.webhooks.Deliveries(user.Id, h.Id)
+		if err != nil {
+			log.Printf("%v", err)
+		}
+		resp.Hooks = append(resp.Hooks, hook{h, deliveries[:min(len(deliveries), deliveriesShown)]})
+	}
+	a.renderStatus(w, r, status, resp, "webhooks.html")
+}
+
+// handleWebhooksUpdate registers a hook or removes one.
+func (a *App) handleWebhooksUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.webhooks == nil {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		user := a.user(r)
+		var err error
+		switch r.Form.Get("action") {
+		case "add":
+			h := webhooks.Hook{
+				User:    user.Id,
+				Url:     strings.TrimSpace(r.Form.Get("url")),
+				Events:  r.Form["event"],
+				Regions: strings.FieldsFunc(strings.ToLower(r.Form.Get("regions")), func(c rune) bool { return c == ',' || c == ' ' }),
+			}
+			var movesErr, ratingErr error
+			h.Moves, movesErr = strconv.Atoi(r.Form.Get("moves"))
+			h.Rating, ratingErr = strconv.Atoi(r.Form.Get("rating"))
+			if movesErr != nil || ratingErr != nil {
+				err = webhooks.ErrInvalidThreshold
+			} else {
+				_, err = a.webhooks.Register(h)
+			}
+		case "remove":
+			err = a.webhooks.Remove(user.Id, r.Form.Get("hook"))
+			if errors.Is(err, webhooks.ErrHookNotFound) {
+				err = nil
+			}
+		}
+		for _, e := range webhookErrors {
+			if errors.Is(err, e.err) {
+				a.renderWebhooks(w, r, user, http.StatusBadRequest, e.message)
+				return
+			}
+		}
+		if err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		http.Redirect(w, r, "/account/webhooks", http.StatusSeeOther)
+	}
+}
+
+// handleWebhookReplay sends the event of a delivery to its hook again.
+func (a *App) handleWebhookReplay() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.webhooks == nil {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+
+		_, err := a.webhooks.Replay(a.user(r).Id, r.PathValue("hook"), r.PathValue("delivery"))
+		if errors.Is(err, webhooks.ErrHookNotFound) || errors.Is(err, webhooks.ErrDeliveryNotFound) {
+			a.renderStatus(w, r, http.StatusNotFound, nil, "404.html")
+			return
+		}
+		if err != nil {
+			log.Printf("%v", err)
+			a.render(w, r, nil, "error.html")
+			return
+		}
+
+		http.Redirect(w, r, "/account/webhooks", http.StatusSeeOther)
+	}
+}
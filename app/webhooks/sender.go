@@ -0,0 +1,222 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/sync/errgroup"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultAttempts = 6
+	defaultBackoff  = time.Minute
+	defaultTick     = 10 * time.Second
+)
+
+// ErrPrivateAddress is returned when a hook resolves to an address that isn't public.
+var ErrPrivateAddress = errors.New("webhook address isn't public")
+
+type SenderConfig struct {
+	Webhooks *Webhooks
+	// Client sends deliveries, redirects should be turned off as they are considered failures
+	Client *http.Client
+	// Attempts is how many times a delivery is tried before it fails, six by default
+	Attempts int
+	// Backoff is the delay before the second attempt, it doubles after every attempt, a minute by default
+	Backoff time.Duration
+	// Concurrency limits how many deliveries are sent at once, one by default
+	Concurrency int
+	// Tick is how often due deliveries are looked for, ten seconds by default
+	Tick  time.Duration
+	Clock Clock
+}
+
+// Sender delivers pending deliveries of Webhooks and retries failed ones with exponential backoff.
+type Sender struct {
+	webhooks    *Webhooks
+	client      *http.Client
+	attempts    int
+	backoff     time.Duration
+	concurrency int
+	tick        time.Duration
+	clock       Clock
+}
+
+func NewSender(config *SenderConfig) *Sender {
+	s := &Sender{
+		webhooks:    config.Webhooks,
+		client:      config.Client,
+		attempts:    config.Attempts,
+		backoff:     config.Backoff,
+		concurrency: config.Concurrency,
+		tick:        config.Tick,
+		clock:       config.Clock,
+	}
+	if s.client == nil {
+		s.client = http.DefaultClient
+	}
+	if s.attempts <= 0 {
+		s.attempts = defaultAttempts
+	}
+	if s.backoff <= 0 {
+		s.backoff = defaultBackoff
+	}
+	if s.concurrency <= 0 {
+		s.concurrency = 1
+	}
+	if s.tick <= 0 {
+		s.tick = defaultTick
+	}
+	if s.clock == nil {
+		s.clock = realClock{}
+	}
+
+	return s
+}
+
+// Run sends due deliveries every tick until the context is done, deliveries in flight are cancelled and waited for.
+func (s *Sender) Run(ctx context.Context) error {
+	for {
+		if err := s.Send(ctx); err != nil {
+			log.Printf("%v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.clock.After(s.tick):
+		}
+	}
+}
+
+// Send tries every delivery that is due, one failing doesn't stop the others.
+func (s *Sender) Send(ctx context.Context) error {
+	deliveries, hooks := s.webhooks.due(s.clock.Now())
+
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	g := errgroup.Group{}
+	g.SetLimit(s.concurrency)
+	for _, d := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+
+		g.Go(func() error {
+			if err := s.send(ctx, hooks[d.Hook], d); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("can't deliver %s to %s: %w", d.Id, hooks[d.Hook].Url, err))
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (s *Sender) send(ctx context.Context, h *Hook, d *Delivery) error {
+	status, err := s.post(ctx, h, d)
+	if ctx.Err() != nil {
+		// Shutting down isn't the receiver's fault, the delivery is tried again on the next start
+		return nil
+	}
+
+	now := s.clock.Now()
+	a := Attempt{Time: now, Status: status}
+	result, next := Delivered, time.Time{}
+	if err != nil {
+		a.Error, result = err.Error(), Failed
+		if n := len(d.Attempts) + 1; n < s.attempts {
+			result, next = Pending, now.Add(s.delay(n))
+		}
+	}
+
+	return errors.Join(err, s.webhooks.record(d.Id, a, result, next))
+}
+
+// post sends the event of a delivery, anything but a 2xx response is an error.
+func (s *Sender) post(ctx context.Context, h *Hook, d *Delivery) (int, error) {
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(s.clock.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "podfinder-webhooks")
+	req.Header.Set("X-Podfinder-Event", d.Event.Type)
+	req.Header.Set("X-Podfinder-Delivery", d.Id)
+	req.Header.Set("X-Podfinder-Timestamp", timestamp)
+	req.Header.Set("X-Podfinder-Signature", "sha256="+Sign(h.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// delay is how long to wait after a number of failed attempts.
+func (s *Sender) delay(failures int) time.Duration {
+	d := s.backoff
+	for i := 1; i < failures; i++ {
+		d *= 2
+	}
+	return d
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of a timestamp and a body joined with a dot, keyed with a hook's secret.
+// Receivers compute it to check the X-Podfinder-Signature header, and reject old timestamps to stop replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PublicOnly is a net.Dialer Control function that refuses connections to loopback, private, link-local
+// and other addresses that aren't public, so that hooks can't reach services next to the server.
+func PublicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return ErrPrivateAddress
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (s *WebhooksSuite) sender() *Sender {
+	return NewSender(&SenderConfig{
+		Webhooks:    s.webhooks,
+		Client:      s.server.Client(),
+		Attempts:    3,
+		Backoff:     time.Minute,
+		Concurrency: 2,
+		Clock:       s.clock,
+	})
+}
+
+func (s *WebhooksSuite) TestSend() {
+	h := s.register("grey", Hook{})
+	s.snapshot("us", []string{"811377230"}, nil)
+
+	s.NoError(s.sender().Send(context.Background()))
+
+	s.Len(s.requests, 1)
+	r, body := s.requests[0], s.bodies[0]
+	deliveries, _ := s.webhooks.Deliveries("grey", h.Id)
+	d := deliveries[0]
+	s.Equal(http.MethodPost, r.Method)
+	s.Equal("/hook", r.URL.Path)
+	s.Equal("application/json", r.Header.Get("Content-Type"))
+	s.Equal(ChartLeft, r.Header.Get("X-Podfinder-Event"))
+	s.Equal(d.Id, r.Header.Get("X-Podfinder-Delivery"))
+	timestamp := r.Header.Get("X-Podfinder-Timestamp")
+	s.Equal(strconv.FormatInt(s.clock.Now().Unix(), 10), timestamp)
+	s.Equal("sha256="+Sign(h.Secret, timestamp, body), r.Header.Get("X-Podfinder-Signature"))
+	var e Event
+	s.NoError(json.Unmarshal(body, &e))
+	s.Equal(d.Event, e)
+
+	s.Equal(Delivered, d.Status)
+	s.Equal([]Attempt{{Time: s.clock.Now(), Status: http.StatusNoContent}}, d.Attempts)
+
+	// Delivered events aren't sent again
+	s.NoError(s.sender().Send(context.Background()))
+	s.Len(s.requests, 1)
+}
+
+func (s *WebhooksSuite) TestSendRetries() {
+	h := s.register("grey", Hook{})
+	s.snapshot("us", []string{"811377230"}, nil)
+	s.status = http.StatusServiceUnavailable
+	sender := s.sender()
+
+	var delays []time.Duration
+	for i := 0; i < 3; i++ {
+		err := sender.Send(context.Background())
+
+		s.ErrorContains(err, "unexpected status 503")
+		deliveries, _ := s.webhooks.Deliveries("grey", h.Id)
+		d := deliveries[0]
+		s.Len(d.Attempts, i+1)
+		s.Equal(http.StatusServiceUnavailable, d.Last().Status)
+		if d.Status == Pending {
+			delays = append(delays, d.Next.Sub(s.clock.Now()))
+
+			// Nothing is sent before the next attempt is due
+			s.NoError(sender.Send(context.Background()))
+			s.Len(s.requests, i+1)
+			s.clock.Advance(d.Next.Sub(s.clock.Now()))
+		}
+	}
+
+	s.Equal([]time.Duration{time.Minute, 2 * time.Minute}, delays)
+	deliveries, _ := s.webhooks.Deliveries("grey", h.Id)
+	s.Equal(Failed, deliveries[0].Status)
+	s.Len(s.requests, 3)
+
+	// A replay starts over with every attempt
+	s.status = http.StatusOK
+	_, err := s.webhooks.Replay("grey", h.Id, deliveries[0].Id)
+	s.NoError(err)
+	s.NoError(sender.Send(context.Background()))
+	deliveries, _ = s.webhooks.Deliveries("grey", h.Id)
+	s.Equal(Delivered, deliveries[0].Status)
+	s.Equal(deliveries[1].Event.Id, deliveries[0].Event.Id)
+}
+
+func (s *WebhooksSuite) TestSendUnreachable() {
+	h := s.register("grey", Hook{Url: "http://127.0.0.1:1/hook"})
+	s.snapshot("us", []string{"811377230"}, nil)
+
+	err := s.sender().Send(context.Background())
+
+	s.ErrorContains(err, "can't deliver")
+	deliveries, _ := s.webhooks.Deliveries("grey", h.Id)
+	s.Zero(deliveries[0].Last().Status)
+	s.NotEmpty(deliveries[0].Last().Error)
+	s.Equal(Pending, deliveries[0].Status)
+}
+
+func (s *WebhooksSuite) TestPublicOnly() {
+	tests := map[string]bool{
+		"93.184.216.34:443":        true,
+		"[2606:2800:220:1::]:443":  true,
+		"127.0.0.1:80":             false,
+		"10.0.0.1:80":              false,
+		"192.168.1.1:80":           false,
+		"169.254.169.254:80":       false,
+		"0.0.0.0:80":               false,
+		"[::1]:80":                 false,
+		"[::ffff:127.0.0.1]:80":    false,
+		"[fd00::1]:80":             false,
+		"[fe80::1]:80":             false,
+		"[ff02::1]:80":             false,
+		"[2606:2800:220:1::]:8080": true,
+	}
+
+	for address, public := range tests {
+		err := PublicOnly("tcp", address, nil)
+		if public {
+			s.NoError(err, address)
+		} else {
+			s.ErrorIs(err, ErrPrivateAddress, address)
+		}
+	}
+
+	client := &http.Client{Transport: &http.Transport{DialContext: (&net.Dialer{Control: PublicOnly}).DialContext}}
+	_, err := client.Get(s.server.URL)
+	s.ErrorIs(err, ErrPrivateAddress)
+}
@@ -0,0 +1,551 @@
+// Package webhooks sends signed events about chart and review changes of watched podcasts to urls users register.
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/timiskhakov/podfinder/app/accounts"
+	"github.com/timiskhakov/podfinder/app/charts"
+	"github.com/timiskhakov/podfinder/app/itunes"
+	"github.com/timiskhakov/podfinder/app/reviews"
+	"github.com/timiskhakov/podfinder/app/storage"
+	"log"
+	"net/url"
+	"slices"
+	"time"
+)
+
+const (
+	// ChartEntered is sent when a podcast enters the top chart of a region
+	ChartEntered = "chart.entered"
+	// ChartLeft is sent when a podcast drops out of the top chart of a region
+	ChartLeft = "chart.left"
+	// ChartMoved is sent when a podcast moves up or down the top chart by more places than a hook's Moves
+	ChartMoved = "chart.moved"
+	// ReviewLow is sent for a new review rated below a hook's Rating
+	ReviewLow = "review.low"
+)
+
+// Events are the types of events a hook can receive.
+var Events = []string{ChartEntered, ChartLeft, ChartMoved, ReviewLow}
+
+const (
+	Pending   = "pending"
+	Delivered = "delivered"
+	Failed    = "failed"
+)
+
+const (
+	// MaxHooks limits how many hooks a user registers
+	MaxHooks = 10
+	// maxDeliveries is how many of the newest finished deliveries are kept per hook
+	maxDeliveries = 100
+)
+
+var (
+	ErrHookNotFound     = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrInvalidUrl       = errors.New("webhook url must be an absolute http(s) url")
+	ErrInvalidEvent     = errors.New("unknown webhook event")
+	ErrInvalidRegion    = errors.New("unknown region")
+	ErrInvalidThreshold = errors.New("moves can't be negative and rating must be between 1 and 5")
+	ErrTooManyHooks     = fmt.Errorf("a user registers up to %d webhooks", MaxHooks)
+)
+
+// Hook is a url a user registered to receive events about podcasts they watch.
+type Hook struct {
+	Id   string `json:"id"`
+	User string `json:"user"`
+	Url  string `json:"url"`
+	// Secret is the key payloads are signed with
+	Secret string `json:"secret"`
+	// Events the hook receives, every event if it is empty
+	Events []string `json:"events,omitempty"`
+	// Regions the hook receives events of, every region if it is empty
+	Regions []string `json:"regions,omitempty"`
+	// Moves is how many places a podcast has to move by for ChartMoved
+	Moves int `json:"moves"`
+	// Rating is the rating reviews have to be below for ReviewLow
+	Rating  int       `json:"rating"`
+	Created time.Time `json:"created"`
+}
+
+// Event is the payload of a delivery.
+type Event struct {
+	Id      string    `json:"id"`
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	Podcast string    `json:"podcast"`
+	Region  string    `json:"region"`
+	// Rank and Previous are places in the top chart now and before, zero if the podcast isn't in it
+	Rank     int     `json:"rank,omitempty"`
+	Previous int     `json:"previous,omitempty"`
+	Review   *Review `json:"review,omitempty"`
+}
+
+type Review struct {
+	Id      string    `json:"id"`
+	Author  string    `json:"author"`
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	Rating  int       `json:"rating"`
+	Date    time.Time `json:"date"`
+}
+
+// Delivery is an event sent to a hook, tried until it is delivered or runs out of attempts.
+type Delivery struct {
+	Id     string `json:"id"`
+	Hook   string `json:"hook"`
+	Event  Event  `json:"event"`
+	Status string `json:"status"`
+	// Next is when the next attempt of a pending delivery is due
+	Next     time.Time `json:"next"`
+	Attempts []Attempt `json:"attempts,omitempty"`
+	Created  time.Time `json:"created"`
+	// Replay is the id of the delivery this one replays
+	Replay string `json:"replay,omitempty"`
+}
+
+// Attempt is a try to deliver, Status is the status code of the response if there was one.
+type Attempt struct {
+	Time   time.Time `json:"time"`
+	Status int       `json:"status,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Last returns the latest attempt of a delivery, nil if it wasn't tried yet.
+func (d *Delivery) Last() *Attempt {
+	if len(d.Attempts) == 0 {
+		return nil
+	}
+	return &d.Attempts[len(d.Attempts)-1]
+}
+
+// data is what is stored, deliveries are oldest first.
+type data struct {
+	Hooks      []*Hook     `json:"hooks"`
+	Deliveries []*Delivery `json:"deliveries"`
+}
+
+// Users finds owners of hooks, they only receive events of podcasts they watch.
+type Users interface {
+	Find(id string) (*accounts.User, error)
+}
+
+// Clock lets tests control when events happen and deliveries are tried.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+type Config struct {
+	// File keeps hooks and the delivery log as JSON, they are kept in memory only if it is empty.
+	File  string
+	Users Users
+	Clock Clock
+}
+
+// Webhooks keeps registered hooks and the log of deliveries to them. It listens to chart snapshots
+// and new reviews, and queues deliveries of events for a Sender.
+type Webhooks struct {
+	file  *storage.File[data]
+	users Users
+	clock Clock
+}
+
+func Open(config *Config) (*Webhooks, error) {
+	f, err := storage.Open[data](config.File)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Webhooks{file: f, users: config.Users, clock: config.Clock}
+	if w.clock == nil {
+		w.clock = realClock{}
+	}
+
+	return w, nil
+}
+
+// Hooks returns hooks of a user, oldest first.
+func (w *Webhooks) Hooks(user string) []*Hook {
+	var hooks []*Hook
+	w.file.View(func(d *data) {
+		for _, h := range d.Hooks {
+			if h.User == user {
+				hooks = append(hooks, h.copy())
+			}
+		}
+	})
+	return hooks
+}
+
+// Register adds a hook with a new id and secret. Moves and Rating are thresholds of ChartMoved and ReviewLow.
+func (w *Webhooks) Register(h Hook) (*Hook, error) {
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if h.Id, err = newId(); err != nil {
+		return nil, err
+	}
+	if h.Secret, err = newSecret(); err != nil {
+		return nil, err
+	}
+	h.Events, h.Regions = slices.Clone(h.Events), slices.Clone(h.Regions)
+	h.Created = w.clock.Now()
+
+	err = w.file.Update(func(d *data) error {
+		count := 0
+		for _, other := range d.Hooks {
+			if other.User == h.User {
+				count++
+			}
+		}
+		if count >= MaxHooks {
+			return ErrTooManyHooks
+		}
+		d.Hooks = append(d.Hooks, &h)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.copy(), nil
+}
+
+// Remove deletes a hook of a user along with its deliveries.
+func (w *Webhooks) Remove(user, id string) error {
+	return w.file.Update(func(d *data) error {
+		i := slices.IndexFunc(d.Hooks, func(h *Hook) bool { return h.Id == id && h.User == user })
+		if i < 0 {
+			return ErrHookNotFound
+		}
+		d.Hooks = slices.Delete(d.Hooks, i, i+1)
+		d.Deliveries = slices.DeleteFunc(d.Deliveries, func(dl *Delivery) bool { return dl.Hook == id })
+		return nil
+	})
+}
+
+// Deliveries returns the delivery log of a hook of a user, newest first.
+func (w *Webhooks) Deliveries(user, hook string) ([]*Delivery, error) {
+	var (
+		deliveries []*Delivery
+		err        error
+	)
+	w.file.View(func(d *data) {
+		if d.hook(user, hook) == nil {
+			err = ErrHookNotFound
+			return
+		}
+		for i := len(d.Deliveries) - 1; i >= 0; i-- {
+			if dl := d.Deliveries[i]; dl.Hook == hook {
+				deliveries = append(deliveries, dl.copy())
+			}
+		}
+	})
+	return deliveries, err
+}
+
+// Replay queues the event of a delivery to be sent again as a new delivery.
+func (w *Webhooks) Replay(user, hook, delivery string) (*Delivery, error) {
+	id, err := newId()
+	if err != nil {
+		return nil, err
+	}
+
+	var replay *Delivery
+	err = w.file.Update(func(d *data) error {
+		if d.hook(user, hook) == nil {
+			return ErrHookNotFound
+		}
+		i := slices.IndexFunc(d.Deliveries, func(dl *Delivery) bool { return dl.Id == delivery && dl.Hook == hook })
+		if i < 0 {
+			return ErrDeliveryNotFound
+		}
+
+		now := w.clock.Now()
+		replay = &Delivery{Id: id, Hook: hook, Event: d.Deliveries[i].Event, Status: Pending, Next: now, Created: now, Replay: delivery}
+		d.Deliveries = append(d.Deliveries, replay)
+		d.trim()
+		replay = replay.copy()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return replay, nil
+}
+
+// Snapshot queues chart events of a region found by comparing a snapshot with the previous one, which is
+// how Webhooks listens to the chart collector. Nothing happens for the first snapshot of a region.
+func (w *Webhooks) Snapshot(region string, previous, current charts.Snapshot) {
+	if previous.Time.IsZero() {
+		return
+	}
+
+	before, after := ranks(previous.Ids), ranks(current.Ids)
+	var events []Event
+	add := func(typ, podcast string) {
+		events = append(events, Event{Type: typ, Time: current.Time, Podcast: podcast, Region: region, Rank: after[podcast], Previous: before[podcast]})
+	}
+	for _, id := range current.Ids {
+		switch rank := before[id]; {
+		case rank == 0:
+			add(ChartEntered, id)
+		case rank != after[id]:
+			add(ChartMoved, id)
+		}
+	}
+	for _, id := range previous.Ids {
+		if after[id] == 0 {
+			add(ChartLeft, id)
+		}
+	}
+
+	if err := w.publish(events); err != nil {
+		log.Printf("can't publish %s chart events: %v", region, err)
+	}
+}
+
+// Reviews queues review events of new reviews of a podcast, which is how Webhooks listens to the review collector.
+func (w *Webhooks) Reviews(podcast, region string, added []*reviews.Archived) {
+	var events []Event
+	for _, r := range added {
+		events = append(events, Event{
+			Type:    ReviewLow,
+			Time:    r.FirstSeen,
+			Podcast: podcast,
+			Region:  region,
+			Review:  &Review{Id: r.Id, Author: r.Author, Title: r.Title, Content: r.Content, Rating: r.Rating, Date: r.Date},
+		})
+	}
+
+	if err := w.publish(events); err != nil {
+		log.Printf("can't publish review events of %s: %v", podcast, err)
+	}
+}
+
+// publish queues a delivery of every event to every hook that wants it, if its user watches the podcast.
+func (w *Webhooks) publish(events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var hooks []*Hook
+	w.file.View(func(d *data) {
+		for _, h := range d.Hooks {
+			hooks = append(hooks, h.copy())
+		}
+	})
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	for i := range events {
+		id, err := newId()
+		if err != nil {
+			return err
+		}
+		events[i].Id = id
+	}
+
+	now := w.clock.Now()
+	users := make(map[string]*accounts.User)
+	var deliveries []*Delivery
+	for _, h := range hooks {
+		u, ok := users[h.User]
+		if !ok {
+			var err error
+			u, err = w.users.Find(h.User)
+			if err != nil && !errors.Is(err, accounts.ErrUserNotFound) {
+				return err
+			}
+			users[h.User] = u
+		}
+		if u == nil {
+			continue
+		}
+
+		for _, e := range events {
+			if !u.Watches(e.Podcast) || !h.wants(e) {
+				continue
+			}
+			id, err := newId()
+			if err != nil {
+				return err
+			}
+			deliveries = append(deliveries, &Delivery{Id: id, Hook: h.Id, Event: e, Status: Pending, Next: now, Created: now})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return w.file.Update(func(d *data) error {
+		for _, dl := range deliveries {
+			// Hooks removed in the meantime don't get anything
+			if slices.ContainsFunc(d.Hooks, func(h *Hook) bool { return h.Id == dl.Hook }) {
+				d.Deliveries = append(d.Deliveries, dl)
+			}
+		}
+		d.trim()
+		return nil
+	})
+}
+
+// due returns pending deliveries whose next attempt is due, with their hooks.
+func (w *Webhooks) due(now time.Time) ([]*Delivery, map[string]*Hook) {
+	var (
+		deliveries []*Delivery
+		hooks      = make(map[string]*Hook)
+	)
+	w.file.View(func(d *data) {
+		for _, dl := range d.Deliveries {
+			if dl.Status != Pending || dl.Next.After(now) {
+				continue
+			}
+			for _, h := range d.Hooks {
+				if h.Id == dl.Hook {
+					deliveries, hooks[h.Id] = append(deliveries, dl.copy()), h.copy()
+				}
+			}
+		}
+	})
+	return deliveries, hooks
+}
+
+// record adds an attempt to a delivery and moves it on to a status, a delivery that is gone is ignored.
+func (w *Webhooks) record(id string, a Attempt, status string, next time.Time) error {
+	return w.file.Update(func(d *data) error {
+		i := slices.IndexFunc(d.Deliveries, func(dl *Delivery) bool { return dl.Id == id })
+		if i < 0 {
+			return nil
+		}
+		dl := d.Deliveries[i]
+		dl.Attempts = append(dl.Attempts, a)
+		dl.Status, dl.Next = status, next
+		d.trim()
+		return nil
+	})
+}
+
+func (d *data) hook(user, id string) *Hook {
+	for _, h := range d.Hooks {
+		if h.Id == id && h.User == user {
+			return h
+		}
+	}
+	return nil
+}
+
+// trim drops the oldest finished deliveries of hooks with more than maxDeliveries of them, pending ones are kept.
+func (d *data) trim() {
+	finished := make(map[string]int)
+	keep := make([]bool, len(d.Deliveries))
+	for i := len(d.Deliveries) - 1; i >= 0; i-- {
+		dl := d.Deliveries[i]
+		if dl.Status == Pending {
+			keep[i] = true
+			continue
+		}
+		finished[dl.Hook]++
+		keep[i] = finished[dl.Hook] <= maxDeliveries
+	}
+
+	kept := d.Deliveries[:0]
+	for i, dl := range d.Deliveries {
+		if keep[i] {
+			kept = append(kept, dl)
+		}
+	}
+	d.Deliveries = kept
+}
+
+func (h *Hook) validate() error {
+	u, err := url.Parse(h.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return ErrInvalidUrl
+	}
+	for _, e := range h.Events {
+		if !slices.Contains(Events, e) {
+			return ErrInvalidEvent
+		}
+	}
+	for _, region := range h.Regions {
+		if _, ok := itunes.LookupRegion(region); !ok {
+			return ErrInvalidRegion
+		}
+	}
+	if h.Moves < 0 || h.Rating < 1 || h.Rating > 5 {
+		return ErrInvalidThreshold
+	}
+	return nil
+}
+
+// wants tells whether an event is one of the hook's events, in one of its regions and past its thresholds.
+func (h *Hook) wants(e Event) bool {
+	if len(h.Events) > 0 && !slices.Contains(h.Events, e.Type) {
+		return false
+	}
+	if len(h.Regions) > 0 && !slices.Contains(h.Regions, e.Region) {
+		return false
+	}
+
+	switch e.Type {
+	case ChartMoved:
+		return max(e.Rank-e.Previous, e.Previous-e.Rank) > h.Moves
+	case ReviewLow:
+		return e.Review != nil && e.Review.Rating < h.Rating
+	}
+	return true
+}
+
+func (h *Hook) copy() *Hook {
+	c := *h
+	c.Events, c.Regions = slices.Clone(h.Events), slices.Clone(h.Regions)
+	return &c
+}
+
+func (d *Delivery) copy() *Delivery {
+	c := *d
+	c.Attempts = slices.Clone(d.Attempts)
+	if d.Event.Review != nil {
+		r := *d.Event.Review
+		c.Event.Review = &r
+	}
+	return &c
+}
+
+// ranks maps ids of a chart to their places, starting from one.
+func ranks(ids []string) map[string]int {
+	m := make(map[string]int, len(ids))
+	for i, id := range ids {
+		m[id] = i + 1
+	}
+	return m
+}
+
+func newId() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"github.com/timiskhakov/podfinder/app/accounts"
+	"github.com/timiskhakov/podfinder/app/charts"
+	"github.com/timiskhakov/podfinder/app/webhooks"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var replayRegexp = regexp.MustCompile(`/account/webhooks/\w+/deliveries/\w+/replay`)
+
+// enableWebhooks keeps hooks in memory, nothing is sent without a Sender.
+func (s *AppSuite) enableWebhooks() *webhooks.Webhooks {
+	hooks, err := webhooks.Open(&webhooks.Config{Users: s.app.accounts.(*accounts.Accounts)})
+	s.NoError(err)
+	s.app.webhooks = hooks
+	return hooks
+}
+
+func (s *AppSuite) TestWebhooks() {
+	server := s.enableAccounts()
+	hooks := s.enableWebhooks()
+	session := s.signIn(server, "grey@example.com")
+	resp, _ := s.do(http.MethodPost, "/account/podcasts", url.Values{"action": {"watch"}, "podcast": {"811377230"}}, session)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+
+	resp, _ = s.do(http.MethodPost, "/account/webhooks", url.Values{
+		"action":  {"add"},
+		"url":     {"https://hooks.example.com/podfinder"},
+		"event":   {webhooks.ChartEntered, webhooks.ChartLeft},
+		"regions": {"US, gb"},
+		"moves":   {"5"},
+		"rating":  {"3"},
+	}, session)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	s.Equal("/account/webhooks", resp.Header.Get("Location"))
+
+	resp, body := s.do(http.MethodGet, "/account/webhooks", nil, session)
+	s.Equal(http.StatusOK, resp.StatusCode)
+	s.Contains(body, "https://hooks.example.com/podfinder")
+	s.Contains(body, "chart.entered, chart.left")
+	s.Contains(body, "in us, gb,")
+	s.Contains(body, "Nothing was sent yet.")
+	h := hooks.Hooks(s.hookUser(session))[0]
+	s.Contains(body, "<code>"+h.Secret+"</code>")
+
+	at := time.Now()
+	hooks.Snapshot("us", charts.Snapshot{Time: at.Add(-time.Hour), Ids: []string{"811377230"}}, charts.Snapshot{Time: at})
+	_, body = s.do(http.MethodGet, "/account/webhooks", nil, session)
+	s.Contains(body, "chart.left us")
+	s.Contains(body, `<a href="/podcast/811377230">811377230</a>`)
+	replay := replayRegexp.FindString(body)
+	s.NotEmpty(replay)
+
+	resp, _ = s.do(http.MethodPost, replay, nil, session)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	_, body = s.do(http.MethodGet, "/account/webhooks", nil, session)
+	s.Equal(2, strings.Count(body, "chart.left us"))
+	s.Contains(body, "pending (replay)")
+
+	// Hooks of other users can't be replayed
+	other := s.signIn(server, "brady@example.com")
+	resp, _ = s.do(http.MethodPost, replay, nil, other)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = s.do(http.MethodPost, "/account/webhooks", url.Values{"action": {"remove"}, "hook": {h.Id}}, session)
+	s.Equal(http.StatusSeeOther, resp.StatusCode)
+	_, body = s.do(http.MethodGet, "/account/webhooks", nil, session)
+	s.NotContains(body, "https://hooks.example.com/podfinder")
+}
+
+// hookUser returns the id of the user of a session.
+func (s *AppSuite) hookUser(session *http.Cookie) string {
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	s.NoError(err)
+	req.AddCookie(session)
+	user := sessionUser(req, s.app.cookies, s.app.accounts)
+	s.NotNil(user)
+	return user.Id
+}
+
+func (s *AppSuite) TestWebhooksInvalid() {
+	server := s.enableAccounts()
+	s.enableWebhooks()
+	session := s.signIn(server, "grey@example.com")
+
+	tests := map[string]url.Values{
+		"Enter an http or https url":               {"url": {"example.com/hook"}, "moves": {"5"}, "rating": {"3"}},
+		"Enter regions as comma separated codes":   {"url": {"https://example.com/hook"}, "regions": {"atlantis"}, "moves": {"5"}, "rating": {"3"}},
+		"Places can&#39;t be negative":             {"url": {"https://example.com/hook"}, "moves": {"-1"}, "rating": {"3"}},
+		"the rating must be between 1 and 5 stars": {"url": {"https://example.com/hook"}, "moves": {"five"}, "rating": {"3"}},
+		"Pick events from the list.":               {"url": {"https://example.com/hook"}, "event": {"chart.exploded"}, "moves": {"5"}, "rating": {"3"}},
+	}
+
+	for message, form := range tests {
+		form.Set("action", "add")
+		resp, body := s.do(http.MethodPost, "/account/webhooks", form, session)
+
+		s.Equal(http.StatusBadRequest, resp.StatusCode, message)
+		s.Contains(body, message)
+	}
+}
+
+func (s *AppSuite) TestWebhooksDisabled() {
+	server := s.enableAccounts()
+	session := s.signIn(server, "grey@example.com")
+
+	resp, _ := s.do(http.MethodGet, "/account/webhooks", nil, session)
+	s.Equal(http.StatusNotFound, resp.StatusCode)
+	_, body := s.do(http.MethodGet, "/account", nil, session)
+	s.NotContains(body, "manage webhooks")
+
+	s.enableWebhooks()
+	_, body = s.do(http.MethodGet, "/account", nil, session)
+	s.Contains(body, `<a href="/account/webhooks">manage webhooks</a>`)
+}
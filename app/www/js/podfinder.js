@@ -1,3 +1,39 @@
 $(document).ready(function() {
     $(".regions.dropdown").dropdown();
+
+    $(".load-reviews").click(function() {
+        var button = $(this);
+        button.addClass("loading");
+        $.getJSON(button.data("url") + "&page=" + button.data("page"), function(body) {
+            $.each(body.reviews, function(_, review) {
+                $(".reviews").append(reviewComment(review));
+            });
+            if (body.page < body.last) {
+                button.data("page", body.page + 1);
+            } else {
+                button.remove();
+            }
+        }).always(function() {
+            button.removeClass("loading");
+        });
+    });
 });
+
+function reviewComment(review) {
+    var rating = $("<div class='rating'>").text(review.author + " ");
+    for (var i = 0; i < review.rating; i++) {
+        rating.append("<i class='star icon'></i>");
+    }
+    var metadata = $("<div class='metadata'>")
+        .append(rating)
+        .append($("<div class='date'>").text(new Date(review.date).toLocaleDateString("en-GB", {day: "numeric", month: "short", year: "numeric"})));
+    if (review.voteCount > 0) {
+        metadata.append($("<div class='votes'>").text(review.voteSum + " of " + review.voteCount + " found this helpful"));
+    }
+    metadata.append($("<div class='sentiment'>").addClass(review.sentiment.label).text(review.sentiment.label));
+    var content = $("<div class='content'>")
+        .append($("<span class='author'>").text(review.title))
+        .append(metadata)
+        .append($("<div class='text'>").text(review.content));
+    return $("<div class='ui comments'>").append($("<div class='comment'>").append(content));
+}